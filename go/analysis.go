@@ -0,0 +1,722 @@
+package guard
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── Conflict detection ─────────────────────────────────────────────────
+
+// Conflict is a pair of policies that can match the same invocation but
+// disagree on effect. First takes precedence over Second.
+type Conflict struct {
+	First        string      `json:"first"`
+	Second       string      `json:"second"`
+	FirstEffect  Effect      `json:"first_effect"`
+	SecondEffect Effect      `json:"second_effect"`
+	Sample       EvalContext `json:"sample"` // an invocation both policies match
+}
+
+// DetectConflicts reports every pair of enabled policies whose conditions
+// can match the same invocation but whose effects differ, in precedence
+// order.
+//
+// Overlap detection is approximate. A sample context is built from the
+// two conditions and a pair is reported only if both policies really
+// match it, so every conflict is genuine, but overlaps that need a cleverer
+// sample (two unrelated globs, say) can be missed. Identical conditions
+// and literals matched by the other side's globs are always caught.
+func DetectConflicts(ps *PolicySet) []Conflict {
+	policies := analysisOrder(ps)
+	conds := make([]compiledCondition, len(policies))
+	for i := range policies {
+		conds[i] = compilePolicy(&policies[i])
+	}
+	var out []Conflict
+	for i := range policies {
+		for j := i + 1; j < len(policies); j++ {
+			a, b := &policies[i], &policies[j]
+			if a.Effect == b.Effect {
+				continue
+			}
+			sample, ok := overlapSampleAnyOf(a, b, &conds[i], &conds[j])
+			if !ok {
+				continue
+			}
+			out = append(out, Conflict{
+				First:        a.ID,
+				Second:       b.ID,
+				FirstEffect:  a.Effect,
+				SecondEffect: b.Effect,
+				Sample:       sample,
+			})
+		}
+	}
+	return out
+}
+
+// analysisOrder returns the enabled policies of ps in the order the engine
+// would consider them.
+func analysisOrder(ps *PolicySet) []Policy {
+	var policies []Policy
+	for _, p := range ps.Policies {
+		if p.IsEnabled() {
+			policies = append(policies, p)
+		}
+	}
+	sortPolicies(policies)
+	return policies
+}
+
+// overlapSampleAnyOf is overlapSample for policies with AnyOf blocks: it
+// tries each pair of blocks in turn.
+func overlapSampleAnyOf(a, b *Policy, ca, cb *compiledCondition) (EvalContext, bool) {
+	for _, va := range anyOfVariants(a, true) {
+		for _, vb := range anyOfVariants(b, true) {
+			if ec, ok := overlapSample(&va, &vb, ca, cb); ok {
+				return ec, true
+			}
+		}
+	}
+	return EvalContext{}, false
+}
+
+// anyOfVariants splits p into one policy per AnyOf block, each with the
+// block ANDed into its condition, so that p matches exactly when some
+// variant does. A policy without AnyOf is its own only variant. Blocks
+// that cannot be merged into one condition (two sets of globs on the same
+// field) yield p's plain condition, which matches more, when keepUnmerged
+// is set and are dropped otherwise.
+func anyOfVariants(p *Policy, keepUnmerged bool) []Policy {
+	if len(p.AnyOf) == 0 {
+		return []Policy{*p}
+	}
+	out := make([]Policy, 0, len(p.AnyOf))
+	for _, block := range p.AnyOf {
+		v := *p
+		v.AnyOf = nil
+		cond, err := andConditions(p.Condition, block)
+		if err == nil {
+			v.Condition = cond
+		} else if !keepUnmerged {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// overlapSample tries to build a context matched by both a and b.
+func overlapSample(a, b *Policy, ca, cb *compiledCondition) (EvalContext, bool) {
+	var ec EvalContext
+	ac, bc := &a.Condition, &b.Condition
+	fields := []struct {
+		a, b []string
+		dst  *string
+	}{
+		{ac.Modes, bc.Modes, &ec.Mode},
+		{ac.Models, bc.Models, &ec.Model},
+		{ac.Channels, bc.Channels, &ec.Channel},
+		{ac.Tools, bc.Tools, &ec.Tool},
+		{ac.McpServers, bc.McpServers, &ec.McpServer},
+		{ac.McpMethods, bc.McpMethods, &ec.McpMethod},
+		{ac.Risk, bc.Risk, &ec.Risk},
+		{ac.Users, bc.Users, &ec.User},
+		{ac.Sessions, bc.Sessions, &ec.Session},
+		{ac.Agents, bc.Agents, &ec.Agent},
+		{ac.Tenants, bc.Tenants, &ec.Tenant},
+	}
+	for _, f := range fields {
+		v, ok := sampleValue(f.a, f.b)
+		if !ok {
+			return EvalContext{}, false
+		}
+		*f.dst = v
+	}
+
+	var ok bool
+	if ec.Args, ok = sampleMap(ac.Args, bc.Args); !ok {
+		return EvalContext{}, false
+	}
+	if ec.Tags, ok = sampleMap(ac.Tags, bc.Tags); !ok {
+		return EvalContext{}, false
+	}
+
+	if ec.SourceIP, ok = sampleIP(ac.SourceCIDRs, bc.SourceCIDRs); !ok {
+		return EvalContext{}, false
+	}
+
+	if ac.ModelVersion != "" || bc.ModelVersion != "" {
+		ec.Model = sampleModel(ec.Model, ac.ModelVersion, bc.ModelVersion)
+	}
+
+	for _, c := range []*float64{ac.CostGTE, bc.CostGTE} {
+		if c != nil && *c > ec.EstimatedCostUSD {
+			ec.EstimatedCostUSD = *c
+		}
+	}
+	for _, n := range []*int{ac.TokensGTE, bc.TokensGTE} {
+		if n != nil && *n > ec.TokenCount {
+			ec.TokenCount = *n
+		}
+	}
+	for _, r := range []*bool{ac.ReadOnly, bc.ReadOnly} {
+		if r == nil {
+			continue
+		}
+		if ec.ReadOnly != nil && *ec.ReadOnly != *r {
+			return EvalContext{}, false
+		}
+		ec.ReadOnly = r
+	}
+	var authed *bool
+	for _, a := range []*bool{ac.Authenticated, bc.Authenticated} {
+		if a == nil {
+			continue
+		}
+		if authed != nil && *authed != *a {
+			return EvalContext{}, false
+		}
+		authed = a
+	}
+	if authed != nil {
+		ec.Authenticated = *authed
+	}
+	for _, n := range []*int{ac.SessionAgeGTE, bc.SessionAgeGTE} {
+		if n != nil && *n > ec.SessionAgeSeconds {
+			ec.SessionAgeSeconds = *n
+		}
+	}
+	for _, n := range []*int{ac.RiskScoreGTE, bc.RiskScoreGTE} {
+		if n != nil && *n > ec.RiskScore {
+			ec.RiskScore = *n
+		}
+	}
+	if ec.RiskScore == 0 {
+		// Satisfy upper bounds below zero; lower bounds are already met.
+		for _, n := range []*int{ac.RiskScoreLTE, bc.RiskScoreLTE} {
+			if n != nil && *n < ec.RiskScore {
+				ec.RiskScore = *n
+			}
+		}
+	}
+
+	now, ok := sampleTime(a, b)
+	if !ok {
+		return EvalContext{}, false
+	}
+	if ac.Days != nil || bc.Days != nil {
+		if now, ok = sampleDay(a, b, now); !ok {
+			return EvalContext{}, false
+		}
+	}
+	ec.Now = now
+
+	if !ca.matches(ec) || !cb.matches(ec) {
+		return EvalContext{}, false
+	}
+	return ec, true
+}
+
+// sampleValue picks a value matched by both pattern lists, where nil means
+// unconstrained.
+func sampleValue(a, b []string) (string, bool) {
+	switch {
+	case a == nil && b == nil:
+		return "", true
+	case a == nil:
+		a, b = b, a
+	}
+	for _, x := range a {
+		if b == nil {
+			if v := exemplar(x); GlobMatch(x, v) {
+				return v, true
+			}
+			continue
+		}
+		for _, y := range b {
+			for _, v := range []string{exemplar(x), exemplar(y)} {
+				if GlobMatch(x, v) && GlobMatch(y, v) {
+					return v, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// sampleMap picks a value for every key of two keyed pattern fields such
+// as args.
+func sampleMap(a, b map[string][]string) (map[string]string, bool) {
+	if len(a) == 0 && len(b) == 0 {
+		return nil, true
+	}
+	out := make(map[string]string, len(a)+len(b))
+	for _, m := range []map[string][]string{a, b} {
+		for k := range m {
+			if _, done := out[k]; done {
+				continue
+			}
+			v, ok := sampleValue(a[k], b[k])
+			if !ok {
+				return nil, false
+			}
+			out[k] = v
+		}
+	}
+	return out, true
+}
+
+// exemplar returns a string that a glob pattern is likely to match: stars
+// are dropped, ? becomes "x" and a character class becomes its first
+// member. Callers must still check the result with GlobMatch.
+func exemplar(pattern string) string {
+	if _, lit, ok := cutAnchor(pattern); ok {
+		return lit
+	}
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+		case '?':
+			b.WriteByte('x')
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteByte(pattern[i])
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end <= 0 {
+				return pattern
+			}
+			class := pattern[i+1 : i+1+end]
+			if class[0] == '^' || class[0] == '\\' {
+				b.WriteByte('x')
+			} else {
+				b.WriteByte(class[0])
+			}
+			i += end + 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if b.Len() == 0 {
+		// Fields such as mcp_servers never match an empty value.
+		return "x"
+	}
+	return b.String()
+}
+
+// sampleIP picks an address inside both CIDR lists.
+func sampleIP(a, b []string) (string, bool) {
+	cidrs, err := andCIDRs(a, b)
+	if err != nil {
+		return "", false
+	}
+	if cidrs == nil {
+		return "", true
+	}
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil || len(prefixes) == 0 {
+		return "", false
+	}
+	return prefixes[0].Addr().String(), true
+}
+
+// sampleModel returns a model name carrying a version that satisfies both
+// constraints, appending one to model if it has none. The bounds of the
+// constraints, nudged up and down, are the candidates.
+func sampleModel(model string, constraints ...string) string {
+	if _, ok := modelVersion(model); ok {
+		return model
+	}
+	var candidates []string
+	for _, c := range constraints {
+		comparators, err := parseVersionConstraint(c)
+		if err != nil {
+			continue
+		}
+		for _, cmp := range comparators {
+			candidates = append(candidates, formatVersion(cmp.v), formatVersion(cmp.v)+".1")
+			if n := len(cmp.v); n > 0 && cmp.v[n-1] > 0 {
+				lower := append(version(nil), cmp.v...)
+				lower[n-1]--
+				candidates = append(candidates, formatVersion(lower))
+			}
+		}
+	}
+	candidates = append(candidates, "0")
+	for _, cand := range candidates {
+		ok := true
+		for _, c := range constraints {
+			if c != "" && !modelVersionMatches(c, model+cand) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return model + cand
+		}
+	}
+	return model
+}
+
+func formatVersion(v version) string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// sampleTime picks an instant at which both policies are active. The zero
+// time is returned when neither has an activation window.
+func sampleTime(a, b *Policy) (time.Time, bool) {
+	var candidates []time.Time
+	for _, p := range []*Policy{a, b} {
+		for _, t := range []*time.Time{p.ActiveFrom, p.ExpiresAt} {
+			if t != nil {
+				candidates = append(candidates, *t)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return time.Time{}, true
+	}
+	for _, t := range candidates {
+		if a.IsActiveAt(t) && b.IsActiveAt(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// sampleDay picks a time within a week of near, falling on a day both
+// policies allow, at which both are active.
+func sampleDay(a, b *Policy, near time.Time) (time.Time, bool) {
+	da, _ := parseDays(a.Condition.Days)
+	db, _ := parseDays(b.Condition.Days)
+	if a.Condition.Days == nil {
+		da = ^weekdaySet(0)
+	}
+	if b.Condition.Days == nil {
+		db = ^weekdaySet(0)
+	}
+	for i := -6; i <= 6; i++ {
+		t := near.AddDate(0, 0, i)
+		if da.has(t.Weekday()) && db.has(t.Weekday()) && a.IsActiveAt(t) && b.IsActiveAt(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ── Shadowed policies ──────────────────────────────────────────────────
+
+// DetectShadowed returns, in precedence order, the IDs of enabled policies
+// that can never win because an enabled policy of higher precedence
+// matches everything they match, e.g. a catch-all placed above a specific
+// deny.
+//
+// Superset detection is approximate: unset fields and "*" cover
+// everything, and otherwise each of the narrower policy's patterns must
+// appear in, or be matched by a glob in, the broader list. A policy with
+// an activation window only shadows policies with the same window. The
+// result assumes the default StrategyPriority and CombineFirstApplicable.
+func DetectShadowed(ps *PolicySet) []string {
+	policies := analysisOrder(ps)
+	var out []string
+	for j := range policies {
+		for i := 0; i < j; i++ {
+			if shadowsAnyOf(&policies[i], &policies[j]) {
+				out = append(out, policies[j].ID)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// shadowsAnyOf is shadows for policies with AnyOf blocks: every block of
+// narrow must be covered by some block of broad.
+func shadowsAnyOf(broad, narrow *Policy) bool {
+	if broad.Invert || narrow.Invert {
+		return false // complements are not modelled; never report them
+	}
+	if broad.DryRun {
+		return false // never enforced, so it hides nothing
+	}
+	for _, name := range broad.RequirePresent {
+		if !slices.Contains(narrow.RequirePresent, name) {
+			return false // narrow may match where the field is missing
+		}
+	}
+	if isResultPolicy(broad) != isResultPolicy(narrow) {
+		return false // evaluated in different phases
+	}
+	broads := anyOfVariants(broad, false)
+	for _, n := range anyOfVariants(narrow, true) {
+		covered := false
+		for i := range broads {
+			if shadows(&broads[i], &n) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// shadows reports whether broad matches every context narrow matches.
+func shadows(broad, narrow *Policy) bool {
+	timed := broad.ActiveFrom != nil || broad.ExpiresAt != nil
+	if timed && (!sameTime(broad.ActiveFrom, narrow.ActiveFrom) || !sameTime(broad.ExpiresAt, narrow.ExpiresAt)) {
+		return false
+	}
+	bc, nc := &broad.Condition, &narrow.Condition
+	// mcp_servers never matches a context without a server, even with "*",
+	// nor mcp_methods one without a method, sessions one without a
+	// session, agents one without an agent or tenants one without a tenant.
+	for _, l := range [][2][]string{
+		{bc.McpServers, nc.McpServers},
+		{bc.McpMethods, nc.McpMethods},
+		{bc.Sessions, nc.Sessions},
+		{bc.Agents, nc.Agents},
+		{bc.Tenants, nc.Tenants},
+	} {
+		if l[0] != nil && l[1] == nil {
+			return false
+		}
+	}
+	lists := [][2][]string{
+		{bc.Modes, nc.Modes},
+		{bc.Models, nc.Models},
+		{bc.Channels, nc.Channels},
+		{bc.Tools, nc.Tools},
+		{bc.McpServers, nc.McpServers},
+		{bc.McpMethods, nc.McpMethods},
+		{bc.Risk, nc.Risk},
+		{bc.Sessions, nc.Sessions},
+		{bc.Agents, nc.Agents},
+		{bc.Tenants, nc.Tenants},
+		{bc.OutputCategories, nc.OutputCategories},
+	}
+	for _, l := range lists {
+		if !coversPatterns(l[0], l[1]) {
+			return false
+		}
+	}
+	if !coversUsers(bc.Users, nc.Users) {
+		return false
+	}
+	if !coversPatternMap(bc.Args, nc.Args) || !coversPatternMap(bc.Tags, nc.Tags) {
+		return false
+	}
+	if !coversCIDRs(bc.SourceCIDRs, nc.SourceCIDRs) {
+		return false
+	}
+	if bc.CostGTE != nil && (nc.CostGTE == nil || *bc.CostGTE > *nc.CostGTE) {
+		return false
+	}
+	if bc.TokensGTE != nil && (nc.TokensGTE == nil || *bc.TokensGTE > *nc.TokensGTE) {
+		return false
+	}
+	if bc.ReadOnly != nil && (nc.ReadOnly == nil || *bc.ReadOnly != *nc.ReadOnly) {
+		return false
+	}
+	if bc.Authenticated != nil && (nc.Authenticated == nil || *bc.Authenticated != *nc.Authenticated) {
+		return false
+	}
+	if bc.SessionAgeGTE != nil && (nc.SessionAgeGTE == nil || *bc.SessionAgeGTE > *nc.SessionAgeGTE) {
+		return false
+	}
+	if bc.RiskScoreGTE != nil && (nc.RiskScoreGTE == nil || *bc.RiskScoreGTE > *nc.RiskScoreGTE) {
+		return false
+	}
+	if bc.RiskScoreLTE != nil && (nc.RiskScoreLTE == nil || *bc.RiskScoreLTE < *nc.RiskScoreLTE) {
+		return false
+	}
+	if bc.Days != nil {
+		bd, _ := parseDays(bc.Days)
+		nd, _ := parseDays(nc.Days)
+		if nc.Days == nil || nd&^bd != 0 {
+			return false
+		}
+	}
+	if bc.OutputBytesGTE != nil && (nc.OutputBytesGTE == nil || *bc.OutputBytesGTE > *nc.OutputBytesGTE) {
+		return false
+	}
+	return bc.ModelVersion == "" || bc.ModelVersion == nc.ModelVersion
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// coversPatterns reports whether every value matched by narrow is matched
+// by broad. nil means unconstrained. An empty narrow list matches nothing,
+// so anything covers it.
+func coversPatterns(broad, narrow []string) bool {
+	if broad == nil {
+		return true
+	}
+	for _, p := range broad {
+		if p == "*" || p == "**" {
+			return true
+		}
+	}
+	if narrow == nil {
+		return false
+	}
+	for _, n := range narrow {
+		covered := false
+		for _, b := range broad {
+			if coversPattern(b, n) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// coversUsers is coversPatterns for the users field, where a user ID
+// pattern never covers a group pattern or vice versa.
+func coversUsers(broad, narrow []string) bool {
+	if broad == nil {
+		return true
+	}
+	for _, b := range broad {
+		if b == "*" || b == "**" {
+			return true
+		}
+	}
+	if narrow == nil {
+		return false
+	}
+	for _, n := range narrow {
+		covered := false
+		for _, b := range broad {
+			if strings.HasPrefix(b, groupPrefix) == strings.HasPrefix(n, groupPrefix) && coversPattern(b, n) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// coversPattern reports whether the single pattern broad matches every
+// value narrow does. Besides equality and literals, only a trailing star
+// and the prefix:/suffix: anchors are understood, e.g. "mcp:*" covers
+// "mcp:github-*" and "prefix:mcp:" covers "mcp:github/**".
+func coversPattern(broad, narrow string) bool {
+	if broad == narrow {
+		return true
+	}
+	if kind, lit, ok := cutAnchor(narrow); ok {
+		return coversAnchor(broad, kind, lit)
+	}
+	if isLiteral(narrow) {
+		return GlobMatch(broad, narrow)
+	}
+	if kind, lit, ok := cutAnchor(broad); ok {
+		if kind == matchHasPrefix {
+			// Every value narrow matches starts with its literal head.
+			head := narrow[:strings.IndexAny(narrow, globMeta)]
+			return strings.HasPrefix(head, lit)
+		}
+		tail := strings.TrimLeft(narrow, "*")
+		return tail != narrow && !strings.ContainsAny(tail, globMeta) && strings.HasSuffix(tail, lit)
+	}
+	if lit, ok := strings.CutSuffix(broad, "**"); ok && !strings.ContainsAny(lit, globMeta) {
+		return strings.HasPrefix(narrow, lit)
+	}
+	if lit, ok := strings.CutSuffix(broad, "*"); ok && !strings.ContainsAny(lit, globMeta) {
+		rest, ok := strings.CutPrefix(narrow, lit)
+		// A single * stays within a segment, so the rest must too.
+		return ok && !strings.Contains(rest, "/") && !strings.Contains(rest, "**")
+	}
+	return false
+}
+
+// coversAnchor reports whether broad matches every value the anchored
+// pattern of the given kind and literal matches.
+func coversAnchor(broad string, kind matchKind, lit string) bool {
+	if bk, blit, ok := cutAnchor(broad); ok {
+		if bk != kind {
+			return false
+		}
+		if kind == matchHasPrefix {
+			return strings.HasPrefix(lit, blit)
+		}
+		return strings.HasSuffix(lit, blit)
+	}
+	// An anchored match may cross "/", so only ** can cover it.
+	if blit, ok := strings.CutSuffix(broad, "**"); ok && kind == matchHasPrefix && !strings.ContainsAny(blit, globMeta) {
+		return strings.HasPrefix(lit, blit)
+	}
+	if blit, ok := strings.CutPrefix(broad, "**"); ok && kind == matchHasSuffix && !strings.ContainsAny(blit, globMeta) {
+		return strings.HasSuffix(lit, blit)
+	}
+	return false
+}
+
+// coversPatternMap is coversPatterns for keyed fields such as args: every
+// key broad requires must be required by narrow with covered patterns.
+func coversPatternMap(broad, narrow map[string][]string) bool {
+	for k, patterns := range broad {
+		narrowPatterns, ok := narrow[k]
+		if !ok || !coversPatterns(patterns, narrowPatterns) {
+			return false
+		}
+	}
+	return true
+}
+
+// coversCIDRs reports whether every block in narrow lies within a block
+// in broad.
+func coversCIDRs(broad, narrow []string) bool {
+	if broad == nil {
+		return true
+	}
+	if narrow == nil {
+		return false
+	}
+	pb, err := parsePrefixes(broad)
+	if err != nil {
+		return false
+	}
+	pn, err := parsePrefixes(narrow)
+	if err != nil {
+		return false
+	}
+	for _, n := range pn {
+		covered := false
+		for _, b := range pb {
+			if b.Bits() <= n.Bits() && b.Contains(n.Addr()) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
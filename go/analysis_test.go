@@ -0,0 +1,247 @@
+package guard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ── Conflict detection ─────────────────────────────────────────────────
+
+func TestDetectConflictsIdenticalConditions(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "allow-bash", Effect: EffectAllow, Priority: 20, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"interactive"}}},
+		{ID: "deny-bash", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"interactive"}}},
+	}, EffectAsk)
+	conflicts := DetectConflicts(ps)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", conflicts)
+	}
+	c := conflicts[0]
+	if c.First != "deny-bash" || c.Second != "allow-bash" {
+		t.Errorf("expected deny-bash before allow-bash, got %s, %s", c.First, c.Second)
+	}
+	if c.FirstEffect != EffectDeny || c.SecondEffect != EffectAllow {
+		t.Errorf("unexpected effects %s, %s", c.FirstEffect, c.SecondEffect)
+	}
+	if c.Sample.Tool != "bash" || c.Sample.Mode != "interactive" {
+		t.Errorf("unexpected sample %+v", c.Sample)
+	}
+}
+
+func TestDetectConflictsGlobAndLiteral(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "mcp", Effect: EffectHITL, Priority: 10, Condition: Condition{Tools: []string{"mcp:github-*"}}},
+		{ID: "issues", Effect: EffectAllow, Priority: 20, Condition: Condition{
+			Tools: []string{"mcp:github-issues"},
+			Args:  map[string][]string{"repo": {"org/*"}},
+		}},
+	}, EffectAsk)
+	conflicts := DetectConflicts(ps)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", conflicts)
+	}
+	sample := conflicts[0].Sample
+	if sample.Tool != "mcp:github-issues" || !GlobMatch("org/*", sample.Args["repo"]) {
+		t.Errorf("unexpected sample %+v", sample)
+	}
+	for _, id := range []string{"mcp", "issues"} {
+		if v := NewPolicyEngine(ps).EvaluateAll(sample); !resultMatched(v, id) {
+			t.Errorf("sample does not match %s", id)
+		}
+	}
+}
+
+func TestDetectConflictsIgnoresDisjointAndAgreeing(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "allow-grep", Effect: EffectAllow, Priority: 20, Condition: Condition{Tools: []string{"grep"}}},
+		{ID: "deny-bg", Effect: EffectDeny, Priority: 30, Condition: Condition{Modes: []string{"background"}}},
+		{ID: "allow-bash-off", Effect: EffectAllow, Priority: 40, Enabled: boolPtr(false), Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "allow-net", Effect: EffectAllow, Priority: 50, Condition: Condition{Tools: []string{"bash"}, SourceCIDRs: []string{"10.0.0.0/8"}}},
+		{ID: "ask-net", Effect: EffectAsk, Priority: 60, Condition: Condition{Tools: []string{"bash"}, SourceCIDRs: []string{"192.168.0.0/16"}}},
+	}, EffectAsk)
+	var got []string
+	for _, c := range DetectConflicts(ps) {
+		got = append(got, c.First+"/"+c.Second)
+	}
+	// allow-net and ask-net never overlap; the disabled policy is ignored.
+	want := []string{"deny-bash/allow-net", "deny-bash/ask-net", "allow-grep/deny-bg", "deny-bg/allow-net", "deny-bg/ask-net"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDetectConflictsTimeWindows(t *testing.T) {
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	ps := makePolicySet([]Policy{
+		{ID: "jan", Effect: EffectDeny, Priority: 10, ExpiresAt: &jan, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "feb", Effect: EffectAllow, Priority: 20, ActiveFrom: &feb, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk)
+	if conflicts := DetectConflicts(ps); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts for disjoint windows, got %+v", conflicts)
+	}
+}
+
+func TestDetectConflictsModelVersion(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "old", Effect: EffectDeny, Priority: 10, Condition: Condition{Models: []string{"gpt-*"}, ModelVersion: "<5"}},
+		{ID: "gpt", Effect: EffectAllow, Priority: 20, Condition: Condition{Models: []string{"gpt-*"}}},
+		{ID: "new", Effect: EffectAsk, Priority: 30, Condition: Condition{ModelVersion: ">=5"}},
+	}, EffectAsk)
+	got := map[string]string{}
+	for _, c := range DetectConflicts(ps) {
+		got[c.First+"/"+c.Second] = c.Sample.Model
+	}
+	if m, ok := got["old/gpt"]; !ok || !modelVersionMatches("<5", m) {
+		t.Errorf("expected old/gpt conflict with a <5 model, got %v", got)
+	}
+	if _, ok := got["old/new"]; ok {
+		t.Errorf("old and new cannot overlap, got %v", got)
+	}
+}
+
+func TestDetectConflictsAnyOf(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-either", Effect: EffectDeny, Priority: 10, AnyOf: []Condition{
+			{Tools: []string{"rm"}}, {Tools: []string{"bash"}, Modes: []string{"background"}},
+		}},
+		{ID: "allow-bash", Effect: EffectAllow, Priority: 20, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "allow-view", Effect: EffectAllow, Priority: 30, Condition: Condition{Tools: []string{"view"}}},
+	}, EffectAsk)
+	conflicts := DetectConflicts(ps)
+	if len(conflicts) != 1 || conflicts[0].Second != "allow-bash" {
+		t.Fatalf("expected one conflict with allow-bash, got %+v", conflicts)
+	}
+	if s := conflicts[0].Sample; s.Tool != "bash" || s.Mode != "background" {
+		t.Errorf("sample = %+v", s)
+	}
+}
+
+func resultMatched(results []MatchResult, id string) bool {
+	for _, r := range results {
+		if r.PolicyID == id {
+			return r.Matched
+		}
+	}
+	return false
+}
+
+// ── Shadowed policies ──────────────────────────────────────────────────
+
+func TestDetectShadowedCatchAll(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "catch-all", Effect: EffectAsk, Priority: 10, Condition: Condition{Tools: []string{"*"}}},
+		{ID: "deny-rm", Effect: EffectDeny, Priority: 20, Condition: Condition{
+			Tools: []string{"bash"},
+			Args:  map[string][]string{"command": {"rm *"}},
+		}},
+	}, EffectAllow)
+	if got := DetectShadowed(ps); !reflect.DeepEqual(got, []string{"deny-rm"}) {
+		t.Errorf("expected [deny-rm], got %v", got)
+	}
+}
+
+func TestDetectShadowedLists(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "shell", Effect: EffectAsk, Priority: 10, Condition: Condition{Tools: []string{"bash", "sh"}}},
+		{ID: "mcp", Effect: EffectHITL, Priority: 20, Condition: Condition{Tools: []string{"mcp:*"}}},
+		{ID: "bash-bg", Effect: EffectDeny, Priority: 30, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"background"}}},
+		{ID: "bash-zsh", Effect: EffectDeny, Priority: 40, Condition: Condition{Tools: []string{"bash", "zsh"}}},
+		{ID: "mcp-github", Effect: EffectAllow, Priority: 50, Condition: Condition{Tools: []string{"mcp:github-*"}}},
+		{ID: "mcp-nested", Effect: EffectAllow, Priority: 60, Condition: Condition{Tools: []string{"mcp:github/*"}}},
+		{ID: "anything", Effect: EffectAllow, Priority: 70},
+	}, EffectAllow)
+	want := []string{"bash-bg", "mcp-github"}
+	if got := DetectShadowed(ps); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDetectShadowedRespectsConstraints(t *testing.T) {
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ps := makePolicySet([]Policy{
+		{ID: "disabled", Effect: EffectAsk, Priority: 1, Enabled: boolPtr(false)},
+		{ID: "temporary", Effect: EffectAsk, Priority: 2, ExpiresAt: &jan},
+		{ID: "any-server", Effect: EffectAsk, Priority: 3, Condition: Condition{McpServers: []string{"*"}}},
+		{ID: "office", Effect: EffectAllow, Priority: 4, Condition: Condition{SourceCIDRs: []string{"10.0.0.0/8"}}},
+		{ID: "lab", Effect: EffectDeny, Priority: 5, Condition: Condition{Tools: []string{"bash"}, SourceCIDRs: []string{"10.1.0.0/16"}}},
+		{ID: "bash", Effect: EffectDeny, Priority: 6, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	if got := DetectShadowed(ps); !reflect.DeepEqual(got, []string{"lab"}) {
+		t.Errorf("expected [lab], got %v", got)
+	}
+}
+
+func TestDetectShadowedAnyOf(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "shells", Effect: EffectDeny, Priority: 10, AnyOf: []Condition{
+			{Tools: []string{"bash"}}, {Tools: []string{"sh"}},
+		}},
+		{ID: "bash-bg", Effect: EffectAsk, Priority: 20, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"background"}}},
+		{ID: "sh-or-zsh", Effect: EffectAsk, Priority: 30, AnyOf: []Condition{
+			{Tools: []string{"sh"}}, {Tools: []string{"zsh"}},
+		}},
+		{ID: "bash-or-sh", Effect: EffectAllow, Priority: 40, Condition: Condition{Modes: []string{"auto"}}, AnyOf: []Condition{
+			{Tools: []string{"sh"}}, {Tools: []string{"bash"}},
+		}},
+	}, EffectAsk)
+	want := []string{"bash-bg", "bash-or-sh"}
+	if got := DetectShadowed(ps); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAnalysisSkipsInverted(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "not-view", Effect: EffectAsk, Priority: 10, Invert: true, Condition: Condition{Tools: []string{"view"}}},
+		{ID: "view", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"view"}}},
+		{ID: "not-bash", Effect: EffectAllow, Priority: 30, Invert: true, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk)
+	if got := DetectShadowed(ps); len(got) != 0 {
+		t.Errorf("expected no shadowed policies, got %v", got)
+	}
+	if got := DetectConflicts(ps); len(got) != 0 {
+		t.Errorf("expected no reported conflicts, got %+v", got)
+	}
+}
+
+func TestDetectShadowedAgents(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "any-agent", Effect: EffectAsk, Priority: 10, Condition: Condition{Agents: []string{"*"}}},
+		{ID: "direct", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "sub-agent", Effect: EffectDeny, Priority: 30, Condition: Condition{Agents: []string{"supervisor/*"}}},
+	}, EffectAllow)
+	// "*" never matches a context without an agent, so only sub-agent is shadowed.
+	if got := DetectShadowed(ps); !reflect.DeepEqual(got, []string{"sub-agent"}) {
+		t.Errorf("expected [sub-agent], got %v", got)
+	}
+}
+
+func TestDetectShadowedMcpMethods(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "reads", Effect: EffectAllow, Priority: 10, Condition: Condition{McpMethods: []string{"resources/*"}}},
+		{ID: "any-server", Effect: EffectAsk, Priority: 20, Condition: Condition{McpServers: []string{"*"}}},
+		{ID: "github-reads", Effect: EffectDeny, Priority: 30, Condition: Condition{
+			McpServers: []string{"github"}, McpMethods: []string{"resources/read"},
+		}},
+	}, EffectAllow)
+	if got := DetectShadowed(ps); !reflect.DeepEqual(got, []string{"github-reads"}) {
+		t.Errorf("expected [github-reads], got %v", got)
+	}
+}
+
+func TestDetectShadowedSessions(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "any-session", Effect: EffectAsk, Priority: 10, Condition: Condition{Sessions: []string{"*"}}},
+		{ID: "bash", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "prod-bash", Effect: EffectDeny, Priority: 30, Condition: Condition{
+			Tools: []string{"bash"}, Sessions: []string{"sess-prod-*"},
+		}},
+	}, EffectAllow)
+	// any-session does not cover bash, which also matches without a session.
+	if got := DetectShadowed(ps); !reflect.DeepEqual(got, []string{"prod-bash"}) {
+		t.Errorf("expected [prod-bash], got %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package guard
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ── Audit ──────────────────────────────────────────────────────────────
+
+// AuditEntry is the record of a single decision.
+type AuditEntry struct {
+	Time     time.Time
+	Context  EvalContext
+	Verdict  Verdict
+	PolicyID string // the matched policy, empty when the default applied
+	Fallback bool   // true if the verdict came from a context fallback mode
+}
+
+// MarshalJSON renders the entry with snake_case keys, e.g.
+//
+//	{"time":"…","context":{"tool":"bash"},"verdict":{"effect":"deny",…},
+//	 "policy_id":"p1","fallback":false}
+func (a AuditEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Time     time.Time   `json:"time"`
+		Context  EvalContext `json:"context"`
+		Verdict  Verdict     `json:"verdict"`
+		PolicyID string      `json:"policy_id,omitempty"`
+		Fallback bool        `json:"fallback"`
+	}{
+		Time:     a.Time,
+		Context:  a.Context,
+		Verdict:  a.Verdict,
+		PolicyID: a.PolicyID,
+		Fallback: a.Fallback,
+	})
+}
+
+// AuditSink receives an AuditEntry for every decision the engine makes.
+//
+// Record is called synchronously on the evaluating goroutine before
+// Evaluate returns, so a slow sink slows every evaluation. Implementations
+// that write to disk or the network should buffer entries and do the I/O
+// asynchronously. Record must be safe for concurrent use. The entry is not
+// shared with the caller and may be retained.
+type AuditSink interface {
+	Record(AuditEntry)
+}
+
+// auditBox lets a nil-able interface live behind an atomic.Pointer.
+type auditBox struct {
+	sink AuditSink
+}
+
+// SetAuditSink installs s to record every evaluation, replacing any
+// previous sink. Passing nil removes it.
+func (e *PolicyEngine) SetAuditSink(s AuditSink) {
+	if s == nil {
+		e.audit.Store(nil)
+		return
+	}
+	e.audit.Store(&auditBox{sink: s})
+}
+
+// newAuditEntry builds the entry for a completed evaluation. The context's
+// args are copied so sinks may retain the entry.
+func newAuditEntry(t time.Time, ec EvalContext, v Verdict) AuditEntry {
+	ec.Args = copyStringMap(ec.Args)
+	ec.Tags = copyStringMap(ec.Tags)
+	return AuditEntry{
+		Time:     t,
+		Context:  ec,
+		Verdict:  v,
+		PolicyID: v.PolicyID,
+		Fallback: v.Source == SourceFallbackMatched,
+	}
+}
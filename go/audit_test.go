@@ -0,0 +1,97 @@
+package guard
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (m *memorySink) Record(a AuditEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, a)
+}
+
+func TestAuditSinkRecordsEveryDecision(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"safe"}}},
+	}, EffectAsk)
+	ps.ContextFallbacks = map[string]string{"auto": "safe"}
+	engine := NewPolicyEngine(ps)
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+
+	before := time.Now()
+	engine.Evaluate(EvalContext{Mode: "safe", Tool: "bash"})
+	engine.Evaluate(EvalContext{Mode: "auto", Tool: "bash"})
+	engine.Evaluate(EvalContext{Mode: "auto", Tool: "view"})
+
+	if len(sink.entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(sink.entries))
+	}
+	direct, viaFallback, dflt := sink.entries[0], sink.entries[1], sink.entries[2]
+	if direct.PolicyID != "deny-bash" || direct.Fallback || direct.Verdict.Effect != EffectDeny {
+		t.Errorf("direct entry = %+v", direct)
+	}
+	if viaFallback.PolicyID != "deny-bash" || !viaFallback.Fallback || viaFallback.Context.Mode != "auto" {
+		t.Errorf("fallback entry = %+v", viaFallback)
+	}
+	if dflt.PolicyID != "" || dflt.Fallback || dflt.Verdict.Effect != EffectAsk {
+		t.Errorf("default entry = %+v", dflt)
+	}
+	if direct.Time.Before(before) {
+		t.Errorf("timestamp %v before evaluation start %v", direct.Time, before)
+	}
+}
+
+func TestAuditEntryCopiesArgs(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet(nil, EffectAsk))
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+
+	args := map[string]string{"path": "/tmp"}
+	engine.Evaluate(EvalContext{Tool: "write", Args: args})
+	args["path"] = "/etc"
+
+	if got := sink.entries[0].Context.Args["path"]; got != "/tmp" {
+		t.Errorf("audited args mutated by caller: got %q", got)
+	}
+}
+
+func TestAuditEntryJSON(t *testing.T) {
+	entry := AuditEntry{
+		Time:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Context:  EvalContext{Tool: "bash", Session: "s1"},
+		Verdict:  Verdict{Effect: EffectDeny, Channel: ChannelChat, PolicyID: "p1", Reason: "no shell"},
+		PolicyID: "p1",
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"2025-01-02T03:04:05Z","context":{"tool":"bash","session":"s1"},` +
+		`"verdict":{"effect":"deny","channel":"chat","policy_id":"p1","reason":"no shell"},` +
+		`"policy_id":"p1","fallback":false}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestSetAuditSinkNilRemoves(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet(nil, EffectAsk))
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	engine.SetAuditSink(nil)
+	engine.Evaluate(EvalContext{Tool: "bash"})
+
+	if len(sink.entries) != 1 {
+		t.Errorf("expected 1 entry, got %d", len(sink.entries))
+	}
+}
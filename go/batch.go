@@ -0,0 +1,100 @@
+package guard
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// batchParallelMin is the batch size below which EvaluateBatch stays on the
+// calling goroutine; smaller batches don't amortise the goroutine overhead.
+const batchParallelMin = 256
+
+// EvaluateBatch evaluates every context against the same policy snapshot
+// and returns the verdicts in input order. Large batches are split across
+// GOMAXPROCS goroutines; the output is identical to calling Evaluate for
+// each context in turn, except that rate-limit counters may be incremented
+// in a different order.
+func (e *PolicyEngine) EvaluateBatch(ctxs []EvalContext) []Verdict {
+	st := e.state.Load()
+	out := make([]Verdict, len(ctxs))
+	workers := runtime.GOMAXPROCS(0)
+	if len(ctxs) < batchParallelMin || workers == 1 {
+		e.evaluateRange(st, ctxs, out)
+		return out
+	}
+
+	chunk := (len(ctxs) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(ctxs); start += chunk {
+		end := start + chunk
+		if end > len(ctxs) {
+			end = len(ctxs)
+		}
+		wg.Add(1)
+		go func(in []EvalContext, out []Verdict) {
+			defer wg.Done()
+			e.evaluateRange(st, in, out)
+		}(ctxs[start:end], out[start:end])
+	}
+	wg.Wait()
+	return out
+}
+
+// evaluateRange fills out[i] with the verdict for in[i].
+func (e *PolicyEngine) evaluateRange(st *engineState, in []EvalContext, out []Verdict) {
+	for i := range in {
+		// Background is never cancelled, so evaluate cannot fail.
+		out[i], _ = e.evaluateObserved(context.Background(), st, in[i], nil)
+	}
+}
+
+// EvaluateStream evaluates every context received on in across workers
+// goroutines and sends one verdict per context to out, in input order, so
+// the i-th verdict belongs to the i-th context. At most workers+1
+// contexts are in flight: a slow reader of out holds back reads from in.
+// It returns once in is closed and every verdict has been sent, closing
+// out.
+//
+// Each context is evaluated as by Evaluate, against whichever policy set
+// is loaded at the time, so a concurrent Load takes effect mid-stream.
+// A workers value below 1 means 1.
+func (e *PolicyEngine) EvaluateStream(in <-chan EvalContext, out chan<- Verdict, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	type job struct {
+		ec  EvalContext
+		res chan Verdict
+	}
+	jobs := make(chan job)
+	// pending queues each job's result channel in input order; its
+	// capacity bounds the contexts in flight.
+	pending := make(chan chan Verdict, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.res <- e.Evaluate(j.ec)
+			}
+		}()
+	}
+	go func() {
+		for ec := range in {
+			res := make(chan Verdict, 1)
+			pending <- res
+			jobs <- job{ec: ec, res: res}
+		}
+		close(jobs)
+		close(pending)
+	}()
+
+	for res := range pending {
+		out <- <-res
+	}
+	wg.Wait()
+	close(out)
+}
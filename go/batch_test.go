@@ -0,0 +1,123 @@
+package guard
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func batchPolicySet() *PolicySet {
+	return &PolicySet{
+		Metadata: Metadata{Name: "batch"},
+		Defaults: Defaults{Effect: EffectAsk, Channel: ChannelChat},
+		Policies: []Policy{
+			{ID: "allow-read", Effect: EffectAllow, Priority: 10, Channel: ChannelChat, Condition: Condition{Tools: []string{"view", "grep"}}},
+			{ID: "deny-bg-bash", Effect: EffectDeny, Priority: 20, Channel: ChannelChat, Condition: Condition{Modes: []string{"background"}, Tools: []string{"bash"}}},
+			{ID: "hitl-high", Effect: EffectHITL, Priority: 30, Channel: ChannelPhone, Condition: Condition{Risk: []string{"high"}}},
+		},
+		ContextFallbacks: map[string]string{"scheduler": "background"},
+	}
+}
+
+func batchContexts(n int) []EvalContext {
+	tools := []string{"view", "grep", "bash", "edit", "deploy"}
+	modes := []string{"interactive", "background", "scheduler"}
+	risks := []string{"low", "medium", "high"}
+	ctxs := make([]EvalContext, n)
+	for i := range ctxs {
+		ctxs[i] = EvalContext{
+			Tool:    tools[i%len(tools)],
+			Mode:    modes[i%len(modes)],
+			Risk:    risks[i%len(risks)],
+			Session: fmt.Sprintf("sess-%d", i),
+		}
+	}
+	return ctxs
+}
+
+func TestEvaluateBatchMatchesEvaluate(t *testing.T) {
+	engine := NewPolicyEngine(batchPolicySet())
+	for _, n := range []int{0, 1, 17, batchParallelMin, 5000} {
+		ctxs := batchContexts(n)
+		got := engine.EvaluateBatch(ctxs)
+		if len(got) != n {
+			t.Fatalf("n=%d: expected %d verdicts, got %d", n, n, len(got))
+		}
+		for i, ctx := range ctxs {
+			if want := engine.Evaluate(ctx); !reflect.DeepEqual(got[i], want) {
+				t.Fatalf("n=%d: verdict %d: expected %+v, got %+v", n, i, want, got[i])
+			}
+		}
+	}
+}
+
+func TestEvaluateStream(t *testing.T) {
+	engine := NewPolicyEngine(batchPolicySet())
+	ctxs := batchContexts(2000)
+	want := engine.EvaluateBatch(ctxs)
+
+	// Reloading an equivalent set throughout must not lose or reorder
+	// verdicts.
+	stop := make(chan struct{})
+	reloaded := make(chan struct{})
+	go func() {
+		defer close(reloaded)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				engine.Load(batchPolicySet())
+			}
+		}
+	}()
+
+	for _, workers := range []int{0, 1, 8} {
+		in := make(chan EvalContext)
+		out := make(chan Verdict)
+		go engine.EvaluateStream(in, out, workers)
+		go func() {
+			for _, ec := range ctxs {
+				in <- ec
+			}
+			close(in)
+		}()
+		var got []Verdict
+		for v := range out {
+			got = append(got, v)
+		}
+		if len(got) != len(ctxs) {
+			t.Fatalf("workers=%d: got %d verdicts for %d contexts", workers, len(got), len(ctxs))
+		}
+		for i := range got {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Fatalf("workers=%d: verdict %d = %+v, want %+v", workers, i, got[i], want[i])
+			}
+		}
+	}
+	close(stop)
+	<-reloaded
+}
+
+func BenchmarkEvaluateLoop(b *testing.B) {
+	engine := NewPolicyEngine(batchPolicySet())
+	ctxs := batchContexts(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out := make([]Verdict, len(ctxs))
+		for j, ctx := range ctxs {
+			out[j] = engine.Evaluate(ctx)
+		}
+	}
+}
+
+func BenchmarkEvaluateBatch(b *testing.B) {
+	engine := NewPolicyEngine(batchPolicySet())
+	ctxs := batchContexts(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.EvaluateBatch(ctxs)
+	}
+}
@@ -0,0 +1,487 @@
+package guard
+
+import (
+	"fmt"
+	"time"
+)
+
+// ── Builder ────────────────────────────────────────────────────────────
+
+// Builder constructs a PolicySet programmatically:
+//
+//	ps, err := guard.NewBuilder("bootstrap").
+//		Default(guard.EffectDeny).
+//		Policy("p1").Tools("bash").Effect(guard.EffectAsk).Priority(10).Done().
+//		Build()
+//
+// Mistakes are recorded as they happen and the first one is returned by
+// Build; later calls are still accepted so the chain never breaks.
+type Builder struct {
+	ps  PolicySet
+	ids map[string]bool
+	err error
+}
+
+// NewBuilder starts a PolicySet with the given metadata name.
+func NewBuilder(name string) *Builder {
+	return &Builder{
+		ps:  PolicySet{Metadata: Metadata{Name: name}},
+		ids: make(map[string]bool),
+	}
+}
+
+func (b *Builder) fail(format string, args ...any) {
+	if b.err == nil {
+		b.err = fmt.Errorf("guard: builder: "+format, args...)
+	}
+}
+
+// Description sets the policy set description.
+func (b *Builder) Description(d string) *Builder {
+	b.ps.Metadata.Description = d
+	return b
+}
+
+// Version sets the policy set version.
+func (b *Builder) Version(v string) *Builder {
+	b.ps.Metadata.Version = v
+	return b
+}
+
+// Label adds a metadata label.
+func (b *Builder) Label(key, value string) *Builder {
+	if b.ps.Metadata.Labels == nil {
+		b.ps.Metadata.Labels = make(map[string]string)
+	}
+	b.ps.Metadata.Labels[key] = value
+	return b
+}
+
+// Default sets the effect applied when no policy matches.
+func (b *Builder) Default(e Effect) *Builder {
+	b.ps.Defaults.Effect = e
+	return b
+}
+
+// DefaultChannelForEffect sets the channel of verdicts with effect when
+// the winning policy sets none, overriding the default channel.
+func (b *Builder) DefaultChannelForEffect(effect Effect, c Channel) *Builder {
+	if b.ps.Defaults.ChannelByEffect == nil {
+		b.ps.Defaults.ChannelByEffect = make(map[Effect]Channel)
+	}
+	b.ps.Defaults.ChannelByEffect[effect] = c
+	return b
+}
+
+// DefaultForMode sets the effect used when no policy matches a context in
+// mode, overriding Default.
+func (b *Builder) DefaultForMode(mode string, e Effect) *Builder {
+	if b.ps.Defaults.PerMode == nil {
+		b.ps.Defaults.PerMode = make(map[string]Effect)
+	}
+	b.ps.Defaults.PerMode[mode] = e
+	return b
+}
+
+// Alias makes the engine treat tool name as tool.
+func (b *Builder) Alias(name, tool string) *Builder {
+	if b.ps.Aliases == nil {
+		b.ps.Aliases = make(map[string]string)
+	}
+	b.ps.Aliases[name] = tool
+	return b
+}
+
+// DefaultChannel sets the channel used when no policy matches.
+func (b *Builder) DefaultChannel(c Channel) *Builder {
+	b.ps.Defaults.Channel = c
+	return b
+}
+
+// ContextFallback makes mode fall back to fallback when nothing matches.
+func (b *Builder) ContextFallback(mode, fallback string) *Builder {
+	if b.ps.ContextFallbacks == nil {
+		b.ps.ContextFallbacks = make(map[string]string)
+	}
+	b.ps.ContextFallbacks[mode] = fallback
+	return b
+}
+
+// Group defines a shared condition that policies can reference with
+// PolicyBuilder.Group.
+func (b *Builder) Group(name string, cond Condition) *Builder {
+	if b.ps.Groups == nil {
+		b.ps.Groups = make(map[string]Condition)
+	}
+	b.ps.Groups[name] = cond
+	return b
+}
+
+// Policy starts a new policy. Finish it with Done to return to b.
+func (b *Builder) Policy(id string) *PolicyBuilder {
+	switch {
+	case id == "":
+		b.fail("policy %d has no ID", len(b.ps.Policies)+1)
+	case b.ids[id]:
+		b.fail("duplicate policy ID %q", id)
+	}
+	b.ids[id] = true
+	return &PolicyBuilder{b: b, p: Policy{ID: id}}
+}
+
+// Build returns the PolicySet with the same defaults, group resolution
+// and validation the YAML loader applies, or the first error recorded
+// while building.
+func (b *Builder) Build() (*PolicySet, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	ps := b.ps
+	ps.Policies = append([]Policy(nil), b.ps.Policies...)
+	applyLoaderDefaults(&ps)
+	if err := finishPolicySet(&ps, loadOptions{}); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+// PolicyBuilder configures a single policy. See Builder.
+type PolicyBuilder struct {
+	b *Builder
+	p Policy
+}
+
+// Done finishes the policy and returns to the enclosing Builder.
+func (pb *PolicyBuilder) Done() *Builder {
+	if pb.p.Effect == "" {
+		pb.b.fail("policy %q has no effect", pb.p.ID)
+	}
+	pb.b.ps.Policies = append(pb.b.ps.Policies, pb.p)
+	return pb.b
+}
+
+// Effect sets the policy's effect.
+func (pb *PolicyBuilder) Effect(e Effect) *PolicyBuilder {
+	pb.p.Effect = e
+	return pb
+}
+
+// Name sets the policy's display name.
+func (pb *PolicyBuilder) Name(n string) *PolicyBuilder {
+	pb.p.Name = n
+	return pb
+}
+
+// Description sets the policy's description.
+func (pb *PolicyBuilder) Description(d string) *PolicyBuilder {
+	pb.p.Description = d
+	return pb
+}
+
+// Priority sets the policy's priority. Lower numbers win.
+func (pb *PolicyBuilder) Priority(n int) *PolicyBuilder {
+	pb.p.Priority = n
+	return pb
+}
+
+// AnyOf adds alternative condition blocks; the policy then also requires
+// at least one of them to match. See Policy.AnyOf.
+func (pb *PolicyBuilder) AnyOf(blocks ...Condition) *PolicyBuilder {
+	pb.p.AnyOf = append(pb.p.AnyOf, blocks...)
+	return pb
+}
+
+// Weight sets the policy's contribution under WithWeightedScoring.
+func (pb *PolicyBuilder) Weight(w int) *PolicyBuilder {
+	pb.p.Weight = w
+	return pb
+}
+
+// Invert makes the policy match every invocation its condition does not.
+func (pb *PolicyBuilder) Invert() *PolicyBuilder {
+	pb.p.Invert = true
+	return pb
+}
+
+// DryRun puts the policy in observe-only mode; see Policy.DryRun.
+func (pb *PolicyBuilder) DryRun() *PolicyBuilder {
+	pb.p.DryRun = true
+	return pb
+}
+
+// Deprecated marks the policy for removal, with a message saying what to
+// migrate to; see Policy.Deprecated.
+func (pb *PolicyBuilder) Deprecated(message string) *PolicyBuilder {
+	pb.p.Deprecated = true
+	pb.p.DeprecationMessage = message
+	return pb
+}
+
+// RequirePresent makes the policy fail closed when the context has no
+// value for any of fields; see Policy.RequirePresent.
+func (pb *PolicyBuilder) RequirePresent(fields ...string) *PolicyBuilder {
+	pb.p.RequirePresent = append(pb.p.RequirePresent, fields...)
+	return pb
+}
+
+// Disabled marks the policy as disabled.
+func (pb *PolicyBuilder) Disabled() *PolicyBuilder {
+	enabled := false
+	pb.p.Enabled = &enabled
+	return pb
+}
+
+// Channel sets the channel used when the policy wins.
+func (pb *PolicyBuilder) Channel(c Channel) *PolicyBuilder {
+	pb.p.Channel = c
+	return pb
+}
+
+// ChannelChain sets the policy's approval channels in order of preference.
+func (pb *PolicyBuilder) ChannelChain(chain ...Channel) *PolicyBuilder {
+	pb.p.Channels = append(pb.p.Channels, chain...)
+	return pb
+}
+
+// Message sets the reason reported when the policy wins.
+func (pb *PolicyBuilder) Message(m string) *PolicyBuilder {
+	pb.p.Message = m
+	return pb
+}
+
+// Code sets the machine-readable code reported when the policy wins.
+func (pb *PolicyBuilder) Code(c string) *PolicyBuilder {
+	pb.p.Code = c
+	return pb
+}
+
+// RequireReason makes the verdict ask the user for a justification.
+func (pb *PolicyBuilder) RequireReason() *PolicyBuilder {
+	pb.p.RequireReason = true
+	return pb
+}
+
+// Obligation adds a key/value obligation attached to the verdict.
+func (pb *PolicyBuilder) Obligation(key, value string) *PolicyBuilder {
+	if pb.p.Obligations == nil {
+		pb.p.Obligations = make(map[string]string)
+	}
+	pb.p.Obligations[key] = value
+	return pb
+}
+
+// Annotation adds a key/value annotation surfaced in the verdict.
+func (pb *PolicyBuilder) Annotation(key, value string) *PolicyBuilder {
+	if pb.p.Annotations == nil {
+		pb.p.Annotations = make(map[string]string)
+	}
+	pb.p.Annotations[key] = value
+	return pb
+}
+
+// RateLimit makes the policy a rate-limit policy allowing limit calls per
+// session and tool, then applying exceeded.
+func (pb *PolicyBuilder) RateLimit(limit int, exceeded Effect) *PolicyBuilder {
+	pb.p.Effect = EffectRateLimit
+	pb.p.RateLimit = &RateLimit{Max: limit, Exceeded: exceeded}
+	return pb
+}
+
+// Label adds a policy label, used by WithLabelSelector.
+func (pb *PolicyBuilder) Label(key, value string) *PolicyBuilder {
+	if pb.p.Labels == nil {
+		pb.p.Labels = make(map[string]string)
+	}
+	pb.p.Labels[key] = value
+	return pb
+}
+
+// Group ANDs the named group's condition with the policy's own.
+func (pb *PolicyBuilder) Group(name string) *PolicyBuilder {
+	pb.p.Group = name
+	return pb
+}
+
+// Modes restricts the policy to the given modes.
+func (pb *PolicyBuilder) Modes(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Modes = append(pb.p.Condition.Modes, patterns...)
+	return pb
+}
+
+// Models restricts the policy to the given models.
+func (pb *PolicyBuilder) Models(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Models = append(pb.p.Condition.Models, patterns...)
+	return pb
+}
+
+// Channels restricts the policy to the given request channels.
+func (pb *PolicyBuilder) Channels(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Channels = append(pb.p.Condition.Channels, patterns...)
+	return pb
+}
+
+// Tools restricts the policy to the given tools.
+func (pb *PolicyBuilder) Tools(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Tools = append(pb.p.Condition.Tools, patterns...)
+	return pb
+}
+
+// McpMethods restricts the policy to the given MCP methods, e.g.
+// "resources/*".
+func (pb *PolicyBuilder) McpMethods(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.McpMethods = append(pb.p.Condition.McpMethods, patterns...)
+	return pb
+}
+
+// Agents restricts the policy to the given acting agents.
+func (pb *PolicyBuilder) Agents(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Agents = append(pb.p.Condition.Agents, patterns...)
+	return pb
+}
+
+// ReadOnly restricts the policy to tools whose read-only flag equals
+// readOnly.
+func (pb *PolicyBuilder) ReadOnly(readOnly bool) *PolicyBuilder {
+	pb.p.Condition.ReadOnly = &readOnly
+	return pb
+}
+
+// Authenticated restricts the policy to callers whose authenticated flag
+// equals authenticated.
+func (pb *PolicyBuilder) Authenticated(authenticated bool) *PolicyBuilder {
+	pb.p.Condition.Authenticated = &authenticated
+	return pb
+}
+
+// Extends bases the policy on the policy with the given ID; see
+// Policy.Extends.
+func (pb *PolicyBuilder) Extends(id string) *PolicyBuilder {
+	pb.p.Extends = id
+	return pb
+}
+
+// Tenants restricts the policy to the given tenants.
+func (pb *PolicyBuilder) Tenants(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Tenants = append(pb.p.Condition.Tenants, patterns...)
+	return pb
+}
+
+// McpServers restricts the policy to the given MCP servers.
+func (pb *PolicyBuilder) McpServers(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.McpServers = append(pb.p.Condition.McpServers, patterns...)
+	return pb
+}
+
+// Risk restricts the policy to the given risk levels.
+func (pb *PolicyBuilder) Risk(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Risk = append(pb.p.Condition.Risk, patterns...)
+	return pb
+}
+
+// Users restricts the policy to the given users.
+func (pb *PolicyBuilder) Users(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Users = append(pb.p.Condition.Users, patterns...)
+	return pb
+}
+
+// Sessions restricts the policy to the given sessions.
+func (pb *PolicyBuilder) Sessions(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.Sessions = append(pb.p.Condition.Sessions, patterns...)
+	return pb
+}
+
+// Arg restricts the policy to invocations whose argument key matches one
+// of patterns.
+func (pb *PolicyBuilder) Arg(key string, patterns ...string) *PolicyBuilder {
+	if pb.p.Condition.Args == nil {
+		pb.p.Condition.Args = make(map[string][]string)
+	}
+	pb.p.Condition.Args[key] = append(pb.p.Condition.Args[key], patterns...)
+	return pb
+}
+
+// Tag restricts the policy to sessions whose tag key matches one of
+// patterns.
+func (pb *PolicyBuilder) Tag(key string, patterns ...string) *PolicyBuilder {
+	if pb.p.Condition.Tags == nil {
+		pb.p.Condition.Tags = make(map[string][]string)
+	}
+	pb.p.Condition.Tags[key] = append(pb.p.Condition.Tags[key], patterns...)
+	return pb
+}
+
+// CostAtLeast restricts the policy to invocations estimated to cost at
+// least usd dollars.
+func (pb *PolicyBuilder) CostAtLeast(usd float64) *PolicyBuilder {
+	pb.p.Condition.CostGTE = &usd
+	return pb
+}
+
+// TokensAtLeast restricts the policy to invocations using at least n
+// tokens.
+func (pb *PolicyBuilder) TokensAtLeast(n int) *PolicyBuilder {
+	pb.p.Condition.TokensGTE = &n
+	return pb
+}
+
+// Days restricts the policy to invocations on the given days of the week,
+// e.g. Days("sat", "sun").
+func (pb *PolicyBuilder) Days(days ...string) *PolicyBuilder {
+	pb.p.Condition.Days = append(pb.p.Condition.Days, days...)
+	return pb
+}
+
+// SessionAgeAtLeast restricts the policy to sessions that have been
+// running for at least d, rounded down to the second.
+func (pb *PolicyBuilder) SessionAgeAtLeast(d time.Duration) *PolicyBuilder {
+	n := int(d / time.Second)
+	pb.p.Condition.SessionAgeGTE = &n
+	return pb
+}
+
+// RiskScoreBetween restricts the policy to invocations whose RiskScore
+// lies within [low, high].
+func (pb *PolicyBuilder) RiskScoreBetween(low, high int) *PolicyBuilder {
+	pb.p.Condition.RiskScoreGTE = &low
+	pb.p.Condition.RiskScoreLTE = &high
+	return pb
+}
+
+// OutputAtLeast makes this a result policy matching outputs of at least
+// n bytes; see EvaluateResult.
+func (pb *PolicyBuilder) OutputAtLeast(n int) *PolicyBuilder {
+	pb.p.Condition.OutputBytesGTE = &n
+	return pb
+}
+
+// OutputCategories makes this a result policy matching outputs in which
+// a category matching one of patterns was detected; see EvaluateResult.
+func (pb *PolicyBuilder) OutputCategories(patterns ...string) *PolicyBuilder {
+	pb.p.Condition.OutputCategories = append(pb.p.Condition.OutputCategories, patterns...)
+	return pb
+}
+
+// SourceCIDRs restricts the policy to callers within the given networks.
+func (pb *PolicyBuilder) SourceCIDRs(cidrs ...string) *PolicyBuilder {
+	pb.p.Condition.SourceCIDRs = append(pb.p.Condition.SourceCIDRs, cidrs...)
+	return pb
+}
+
+// ModelVersion restricts the policy to models whose version satisfies
+// constraint, e.g. ">=5.0 <6".
+func (pb *PolicyBuilder) ModelVersion(constraint string) *PolicyBuilder {
+	pb.p.Condition.ModelVersion = constraint
+	return pb
+}
+
+// ActiveFrom makes the policy match only at or after t.
+func (pb *PolicyBuilder) ActiveFrom(t time.Time) *PolicyBuilder {
+	pb.p.ActiveFrom = &t
+	return pb
+}
+
+// ExpiresAt makes the policy stop matching after t.
+func (pb *PolicyBuilder) ExpiresAt(t time.Time) *PolicyBuilder {
+	pb.p.ExpiresAt = &t
+	return pb
+}
@@ -0,0 +1,120 @@
+package guard
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilderMatchesLoader(t *testing.T) {
+	built, err := NewBuilder("bootstrap").
+		Default(EffectDeny).
+		DefaultForMode("interactive", EffectAsk).
+		ContextFallback("auto", "safe").
+		Policy("p1").Tools("bash").Effect(EffectAsk).Priority(10).Done().
+		Policy("p2").Name("Reads").Tools("read_*").Arg("path", "/tmp/*").Effect(EffectAllow).Message("ok").Done().
+		Policy("p3").Models("gpt-*").ModelVersion(">=5").RateLimit(3, EffectDeny).Disabled().Done().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: bootstrap
+defaults:
+  effect: deny
+  per_mode:
+    interactive: ask
+context_fallbacks:
+  auto: safe
+policies:
+  - id: p1
+    effect: ask
+    priority: 10
+    condition:
+      tools: [bash]
+  - id: p2
+    name: Reads
+    effect: allow
+    message: ok
+    condition:
+      tools: ["read_*"]
+      args:
+        path: ["/tmp/*"]
+  - id: p3
+    effect: rate-limit
+    enabled: false
+    rate_limit:
+      max: 3
+      exceeded: deny
+    condition:
+      models: ["gpt-*"]
+      model_version: ">=5"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(built, loaded) {
+		t.Errorf("builder and loader disagree:\nbuilt:  %+v\nloaded: %+v", built, loaded)
+	}
+}
+
+func TestBuilderRejectsMissingID(t *testing.T) {
+	_, err := NewBuilder("x").
+		Policy("").Tools("bash").Effect(EffectDeny).Done().
+		Build()
+	if err == nil || !strings.Contains(err.Error(), "no ID") {
+		t.Fatalf("expected missing ID error, got %v", err)
+	}
+}
+
+func TestBuilderRejectsDuplicateID(t *testing.T) {
+	_, err := NewBuilder("x").
+		Policy("p1").Effect(EffectDeny).Done().
+		Policy("p1").Effect(EffectAllow).Done().
+		Build()
+	if err == nil || !strings.Contains(err.Error(), `duplicate policy ID "p1"`) {
+		t.Fatalf("expected duplicate ID error, got %v", err)
+	}
+}
+
+func TestBuilderRejectsMissingEffect(t *testing.T) {
+	_, err := NewBuilder("x").Policy("p1").Tools("bash").Done().Build()
+	if err == nil || !strings.Contains(err.Error(), "no effect") {
+		t.Fatalf("expected missing effect error, got %v", err)
+	}
+}
+
+func TestBuilderRunsLoaderValidation(t *testing.T) {
+	_, err := NewBuilder("x").Policy("p1").SourceCIDRs("not-a-cidr").Effect(EffectDeny).Done().Build()
+	if err == nil || !strings.Contains(err.Error(), "invalid source CIDR") {
+		t.Fatalf("expected CIDR error, got %v", err)
+	}
+}
+
+func TestBuilderReportsFirstError(t *testing.T) {
+	_, err := NewBuilder("x").
+		Policy("").Effect(EffectDeny).Done().
+		Policy("p2").Done().
+		Build()
+	if err == nil || !strings.Contains(err.Error(), "no ID") {
+		t.Fatalf("expected first (missing ID) error, got %v", err)
+	}
+}
+
+func TestBuilderEvaluates(t *testing.T) {
+	ps, err := NewBuilder("x").Default(EffectDeny).
+		Policy("allow-view").Tools("view").Effect(EffectAllow).Done().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Tool: "view"}); v.Effect != EffectAllow {
+		t.Errorf("view: expected allow, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Effect != EffectDeny {
+		t.Errorf("bash: expected deny, got %s", v.Effect)
+	}
+}
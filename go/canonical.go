@@ -0,0 +1,263 @@
+package guard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ── Canonical form ─────────────────────────────────────────────────────
+
+// Canonicalize rewrites ps into a normal form, so that sets that evaluate
+// identically also serialize identically:
+//
+//   - the defaults the loader assumes are filled in (apiVersion, kind,
+//     default effect and channel, priority 100, each policy's channel);
+//   - policies are sorted by priority, then ID;
+//   - every condition pattern list is sorted with duplicates removed,
+//     and any_of blocks are sorted;
+//   - enabled: true, which is implied, is dropped.
+//
+// Evaluation is unchanged for any set produced by the loader or Builder,
+// which apply the same defaults. A nil list stays nil and an empty list
+// stays empty, since the two differ in meaning.
+func (ps *PolicySet) Canonicalize() {
+	applyLoaderDefaults(ps)
+	inheritChannels(ps)
+	for i := range ps.Policies {
+		p := &ps.Policies[i]
+		if p.Enabled != nil && *p.Enabled {
+			p.Enabled = nil
+		}
+		canonicalizeCondition(&p.Condition)
+		for j := range p.AnyOf {
+			canonicalizeCondition(&p.AnyOf[j])
+		}
+		sortConditions(p.AnyOf)
+		p.RequirePresent = sortedSet(p.RequirePresent)
+	}
+	sortPolicies(ps.Policies)
+	for name, g := range ps.Groups {
+		canonicalizeCondition(&g)
+		ps.Groups[name] = g
+	}
+}
+
+// Hash returns a hex SHA-256 digest of ps's canonical form, for change
+// detection: sets that differ only in policy order, list order or
+// spelled-out defaults hash the same. ps itself is not modified. Unlike
+// the YAML form, the hash tells an empty list from an absent one, so
+// tools: [], which matches nothing, and no tools condition, which matches
+// everything, hash differently.
+func (ps *PolicySet) Hash() string {
+	c := copyPolicySet(ps)
+	c.Canonicalize()
+	var b bytes.Buffer
+	writeHashJSON(&b, reflect.ValueOf(c).Elem())
+	sum := sha256.Sum256(b.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// writeHashJSON writes v to b as JSON keyed by the YAML field names. Unlike
+// encoding/json with the omitempty tags, it writes a nil slice as null and
+// an empty one as [], keeping apart lists that differ in meaning. Map
+// keys are sorted; an empty map is written like a nil one.
+func writeHashJSON(b *bytes.Buffer, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			b.WriteString("null")
+			return
+		}
+		writeHashJSON(b, v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			b.WriteString("null")
+			return
+		}
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeHashJSON(b, v.Index(i))
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		if v.Len() == 0 {
+			b.WriteString("null")
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeHashScalar(b, k.String())
+			b.WriteByte(':')
+			writeHashJSON(b, v.MapIndex(k))
+		}
+		b.WriteByte('}')
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			writeHashScalar(b, t.UTC().Format(time.RFC3339Nano))
+			return
+		}
+		b.WriteByte('{')
+		t := v.Type()
+		first := true
+		for i := 0; i < t.NumField(); i++ {
+			name := yamlFieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			writeHashScalar(b, name)
+			b.WriteByte(':')
+			writeHashJSON(b, v.Field(i))
+		}
+		b.WriteByte('}')
+	default:
+		writeHashScalar(b, v.Interface())
+	}
+}
+
+// writeHashScalar writes a string, number or bool as JSON.
+func writeHashScalar(b *bytes.Buffer, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// Policy sets hold only strings, numbers and bools at the leaves.
+		panic(fmt.Errorf("guard: hashing policy set: %w", err))
+	}
+	b.Write(data)
+}
+
+// copyPolicy returns a deep copy of p that shares no slices, maps or
+// pointers with it.
+func copyPolicy(p *Policy) Policy {
+	var c Policy
+	deepCopy(reflect.ValueOf(&c).Elem(), reflect.ValueOf(p).Elem())
+	return c
+}
+
+// copyPolicySet returns a deep copy of ps that shares no slices, maps or
+// pointers with it.
+func copyPolicySet(ps *PolicySet) *PolicySet {
+	var c PolicySet
+	deepCopy(reflect.ValueOf(&c).Elem(), reflect.ValueOf(ps).Elem())
+	return &c
+}
+
+// deepCopy sets dst to a copy of src, duplicating every slice, map and
+// pointer it reaches. A nil slice stays nil and an empty one stays empty.
+func deepCopy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(src.Type()))
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		deepCopy(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(src.Type()))
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(src.Type()))
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			v := reflect.New(src.Type().Elem()).Elem()
+			deepCopy(v, iter.Value())
+			m.SetMapIndex(iter.Key(), v)
+		}
+		dst.Set(m)
+	case reflect.Struct:
+		dst.Set(src) // copies unexported fields, such as time.Time's, as is
+		t := src.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				deepCopy(dst.Field(i), src.Field(i))
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+func canonicalizeCondition(c *Condition) {
+	for _, list := range []*[]string{
+		&c.Modes, &c.Models, &c.Channels, &c.Tools, &c.McpServers,
+		&c.McpMethods, &c.Agents, &c.Tenants, &c.Risk, &c.Users, &c.Sessions,
+		&c.SourceCIDRs, &c.OutputCategories,
+	} {
+		*list = sortedSet(*list)
+	}
+	if days, err := parseDays(c.Days); err == nil && c.Days != nil {
+		c.Days = days.names() // e.g. [Saturday, sun] becomes [sun, sat]
+	}
+	for k, v := range c.Args {
+		c.Args[k] = sortedSet(v)
+	}
+	for k, v := range c.Tags {
+		c.Tags[k] = sortedSet(v)
+	}
+}
+
+// sortedSet sorts list in place and drops duplicates, keeping nil nil.
+func sortedSet(list []string) []string {
+	if len(list) < 2 {
+		return list
+	}
+	sort.Strings(list)
+	out := list[:1]
+	for _, s := range list[1:] {
+		if s != out[len(out)-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// sortConditions orders already canonical conditions by their hash
+// encoding, which tells an empty list from an absent one.
+func sortConditions(conds []Condition) {
+	if len(conds) < 2 {
+		return
+	}
+	type keyed struct {
+		key  string
+		cond Condition
+	}
+	sorted := make([]keyed, len(conds))
+	for i := range conds {
+		var b bytes.Buffer
+		writeHashJSON(&b, reflect.ValueOf(&conds[i]).Elem())
+		sorted[i] = keyed{b.String(), conds[i]}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].key < sorted[j].key })
+	for i := range sorted {
+		conds[i] = sorted[i].cond
+	}
+}
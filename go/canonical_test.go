@@ -0,0 +1,186 @@
+package guard
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCanonicalizeLogicallyEqualSets(t *testing.T) {
+	a, err := LoadPolicySetFromBytes([]byte(`
+apiVersion: agent-policy/v1
+kind: PolicySet
+defaults:
+  effect: ask
+  channel: chat
+policies:
+  - id: deny-shell
+    effect: deny
+    priority: 10
+    enabled: true
+    condition:
+      tools: [sh, bash, bash]
+      modes: [background]
+  - id: ask-net
+    effect: ask
+    condition:
+      tools: [curl]
+      args:
+        url: ["https://b/*", "https://a/*"]
+    any_of:
+      - users: [bob]
+      - users: [alice]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadPolicySetFromBytes([]byte(`
+policies:
+  - id: ask-net
+    effect: ask
+    priority: 100
+    channel: chat
+    condition:
+      tools: [curl]
+      args:
+        url: ["https://a/*", "https://b/*"]
+    any_of:
+      - users: [alice]
+      - users: [bob]
+  - id: deny-shell
+    effect: deny
+    priority: 10
+    condition:
+      modes: [background]
+      tools: [bash, sh]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Hash() != b.Hash() {
+		t.Error("expected equal hashes before canonicalizing")
+	}
+
+	a.Canonicalize()
+	b.Canonicalize()
+	ya, _ := yaml.Marshal(a)
+	yb, _ := yaml.Marshal(b)
+	if string(ya) != string(yb) {
+		t.Errorf("canonical forms differ:\n%s\n---\n%s", ya, yb)
+	}
+	if a.Policies[0].ID != "deny-shell" {
+		t.Errorf("expected policies sorted by priority, got %s first", a.Policies[0].ID)
+	}
+
+	c, _ := LoadPolicySetFromBytes(yb)
+	c.Policies[0].Condition.Tools = []string{"bash"}
+	if c.Hash() == b.Hash() {
+		t.Error("expected a different hash after changing a condition")
+	}
+}
+
+func TestCanonicalizeSortsAnyOf(t *testing.T) {
+	block := func(user string) Condition { return Condition{Users: []string{user}} }
+	load := func(order ...string) []byte {
+		var blocks []Condition
+		for _, u := range order {
+			blocks = append(blocks, block(u))
+		}
+		ps := makePolicySet([]Policy{{ID: "p", Effect: EffectAsk, AnyOf: blocks}}, EffectAllow)
+		ps.Canonicalize()
+		data, err := yaml.Marshal(ps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	want := load("a", "b", "c", "d", "e")
+	for _, order := range [][]string{
+		{"c", "e", "a", "d", "b"},
+		{"e", "d", "c", "b", "a"},
+		{"b", "a", "e", "c", "d"},
+	} {
+		if got := load(order...); string(got) != string(want) {
+			t.Errorf("%v: canonical forms differ:\n%s\n---\n%s", order, got, want)
+		}
+	}
+}
+
+func TestCanonicalizeKeepsSemantics(t *testing.T) {
+	ps := hitPolicySet()
+	ctxs := []EvalContext{{Tool: "bash"}, {Tool: "sh", Mode: "safe"}, {Tool: "view"}, {Tool: "rm"}}
+	before := NewPolicyEngine(ps)
+	want := before.EvaluateBatch(ctxs)
+
+	ps.Canonicalize()
+	got := NewPolicyEngine(ps).EvaluateBatch(ctxs)
+	for i := range ctxs {
+		if got[i].Effect != want[i].Effect || got[i].PolicyID != want[i].PolicyID {
+			t.Errorf("%+v: got %+v, want %+v", ctxs[i], got[i], want[i])
+		}
+	}
+}
+
+func TestHashTellsEmptyListFromAbsent(t *testing.T) {
+	set := func(tools []string) *PolicySet {
+		return makePolicySet([]Policy{
+			{ID: "not-tools", Effect: EffectAllow, Invert: true, Condition: Condition{Tools: tools}},
+		}, EffectAsk)
+	}
+	empty, absent := NewPolicyEngine(set([]string{})), NewPolicyEngine(set(nil))
+	ctx := EvalContext{Tool: "bash"}
+	if a, b := empty.Evaluate(ctx).Effect, absent.Evaluate(ctx).Effect; a == b {
+		t.Fatalf("expected the variants to evaluate differently, both gave %s", a)
+	}
+	if empty.PolicyHash() == absent.PolicyHash() {
+		t.Error("tools: [] and no tools condition hash the same")
+	}
+	if set([]string{}).Hash() != set([]string{}).Hash() {
+		t.Error("expected identical sets to hash the same")
+	}
+}
+
+func TestHashDoesNotMutate(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "b", Effect: EffectDeny, Condition: Condition{Tools: []string{"sh", "bash"}}},
+		{ID: "a", Effect: EffectAllow},
+	}, EffectAsk)
+	ps.Hash()
+	if ps.Policies[0].ID != "b" || ps.Policies[0].Condition.Tools[0] != "sh" || ps.Policies[0].Priority != 0 {
+		t.Errorf("Hash modified the set: %+v", ps.Policies)
+	}
+}
+
+func TestPolicyHashStableAcrossReloads(t *testing.T) {
+	engine := NewPolicyEngine(nil)
+	if engine.PolicyHash() != "" {
+		t.Errorf("expected no hash before Load, got %q", engine.PolicyHash())
+	}
+	engine.Load(hitPolicySet())
+	first := engine.PolicyHash()
+	if first == "" {
+		t.Fatal("expected a hash after Load")
+	}
+
+	engine.Load(hitPolicySet())
+	if engine.PolicyHash() != first {
+		t.Error("reloading identical content changed the hash")
+	}
+	if NewPolicyEngine(hitPolicySet()).PolicyHash() != first {
+		t.Error("expected engines with identical content to share a hash")
+	}
+
+	changed := hitPolicySet()
+	changed.Policies[0].Effect = EffectDeny
+	engine.Load(changed)
+	if engine.PolicyHash() == first {
+		t.Error("expected a new hash after a real change")
+	}
+
+	aliased := hitPolicySet()
+	aliased.Aliases = map[string]string{"shell": "bash"}
+	engine.Load(aliased)
+	if engine.PolicyHash() == first {
+		t.Error("expected aliases to contribute to the hash")
+	}
+}
@@ -0,0 +1,28 @@
+package guard
+
+import "time"
+
+// ── Clock ──────────────────────────────────────────────────────────────
+
+// Clock supplies the current time. The engine reads it for activation
+// windows, evaluation timings and audit timestamps, so tests can pin time
+// by installing a fake with WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock reads the system clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// WithClock sets the clock the engine reads the current time from. The
+// default is the system clock. An EvalContext with Now set still takes
+// precedence for that evaluation.
+func WithClock(c Clock) Option {
+	return func(e *PolicyEngine) {
+		if c != nil {
+			e.clock = c
+		}
+	}
+}
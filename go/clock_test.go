@@ -0,0 +1,72 @@
+package guard
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestWithClockDrivesExpiry(t *testing.T) {
+	expires := time.Date(2025, 3, 7, 17, 0, 0, 0, time.UTC)
+	ps := makePolicySet([]Policy{
+		{ID: "freeze", Effect: EffectDeny, Priority: 10, ExpiresAt: &expires, Condition: Condition{Tools: []string{"deploy"}}},
+	}, EffectAllow)
+	clock := &fakeClock{now: expires.Add(-time.Minute)}
+	engine := NewPolicyEngineWithOptions(ps, WithClock(clock))
+
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.PolicyID != "freeze" {
+		t.Errorf("before expiry: expected freeze, got %q", v.PolicyID)
+	}
+	if r := engine.EvaluateAll(EvalContext{Tool: "deploy"})[0]; r.Expired {
+		t.Error("before expiry: EvaluateAll reports expired")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.PolicyID != "" {
+		t.Errorf("after expiry: expected default, got %q", v.PolicyID)
+	}
+	if v, _ := engine.EvaluateDetailed(EvalContext{Tool: "deploy"}); v.PolicyID != "" {
+		t.Errorf("after expiry: EvaluateDetailed expected default, got %q", v.PolicyID)
+	}
+
+	// An explicit Now still wins over the clock.
+	if v := engine.Evaluate(EvalContext{Tool: "deploy", Now: expires.Add(-time.Hour)}); v.PolicyID != "freeze" {
+		t.Errorf("explicit Now: expected freeze, got %q", v.PolicyID)
+	}
+}
+
+func TestWithClockStampsAudit(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	engine := NewPolicyEngineWithOptions(observerPolicySet(), WithClock(&fakeClock{now: at}))
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	if len(sink.entries) != 1 || !sink.entries[0].Time.Equal(at) {
+		t.Errorf("expected one entry stamped %s, got %+v", at, sink.entries)
+	}
+}
+
+func TestWithClockNilKeepsDefault(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(observerPolicySet(), WithClock(nil))
+	if _, ok := engine.clock.(realClock); !ok {
+		t.Errorf("expected the real clock, got %T", engine.clock)
+	}
+}
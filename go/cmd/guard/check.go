@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	guard "github.com/agent-policy/guard"
+)
+
+// ── validate and lint ──────────────────────────────────────────────────
+
+// severity grades a finding. Errors always fail the command; warnings
+// fail it only with --strict.
+type severity string
+
+const (
+	sevError   severity = "error"
+	sevWarning severity = "warning"
+)
+
+type finding struct {
+	file     string
+	severity severity
+	msg      string
+}
+
+func (f finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.file, f.severity, f.msg)
+}
+
+// checkFunc produces the findings for one successfully loaded file.
+type checkFunc func(ps *guard.PolicySet, strict bool) []finding
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	return runCheck("validate", args, stdout, stderr, validateFindings)
+}
+
+func runLint(args []string, stdout, stderr io.Writer) int {
+	return runCheck("lint", args, stdout, stderr, lintFindings)
+}
+
+// runCheck loads every file named in args and prints the findings of
+// check, one per line. Files that fail to load are reported as errors.
+func runCheck(name string, args []string, stdout, stderr io.Writer, check checkFunc) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: guard %s [--strict] file.yaml...\n", name)
+		fs.PrintDefaults()
+	}
+	strict := fs.Bool("strict", false, "reject unknown keys and effects, malformed or duplicate codes and sets with every policy disabled, and treat warnings as errors")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitAllow
+		}
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	var opts []guard.LoadOption
+	if *strict {
+		opts = append(opts, guard.WithStrictEffects(), guard.WithStrictCodes(), guard.WithStrictFields(), guard.WithRequireEnabled())
+	}
+	failed := false
+	for _, file := range fs.Args() {
+		var findings []finding
+		ps, err := guard.LoadPolicySet(file, opts...)
+		if err != nil {
+			findings = []finding{{severity: sevError, msg: err.Error()}}
+		} else {
+			findings = check(ps, *strict)
+		}
+		for _, f := range findings {
+			f.file = file
+			fmt.Fprintln(stdout, f)
+			if f.severity == sevError || *strict {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return exitError
+	}
+	return exitAllow
+}
+
+func validateFindings(ps *guard.PolicySet, strict bool) []finding {
+	var opts []guard.LoadOption
+	if strict {
+		opts = append(opts, guard.WithStrictEffects(), guard.WithStrictCodes(), guard.WithRequireEnabled())
+	}
+	var out []finding
+	for _, err := range guard.Validate(ps, opts...) {
+		out = append(out, finding{severity: sevError, msg: err.Error()})
+	}
+	return out
+}
+
+// lintFindings reports shadowed policies, which can never take effect, as
+// errors and conflicting policies, which priority may resolve on purpose,
+// as warnings.
+func lintFindings(ps *guard.PolicySet, _ bool) []finding {
+	var out []finding
+	for _, id := range guard.DetectShadowed(ps) {
+		out = append(out, finding{
+			severity: sevError,
+			msg:      fmt.Sprintf("policy %q is shadowed by a higher-priority policy and can never win", id),
+		})
+	}
+	for _, c := range guard.DetectConflicts(ps) {
+		sample, _ := json.Marshal(c.Sample)
+		out = append(out, finding{
+			severity: sevWarning,
+			msg: fmt.Sprintf("policies %q (%s) and %q (%s) both match %s",
+				c.First, c.FirstEffect, c.Second, c.SecondEffect, sample),
+		})
+	}
+	return out
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	guard "github.com/agent-policy/guard"
+)
+
+// ── eval ───────────────────────────────────────────────────────────────
+
+// keyValues is a repeatable key=value flag.
+type keyValues map[string]string
+
+func (kv keyValues) String() string {
+	parts := make([]string, 0, len(kv))
+	for k, v := range kv {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (kv keyValues) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	kv[k] = v
+	return nil
+}
+
+// evalOutput is the --json form of an evaluation: the verdict exactly as
+// the HTTP endpoint encodes it, plus the trace when --explain is set.
+type evalOutput struct {
+	guard.Verdict
+	Trace []traceEntry `json:"trace,omitempty"`
+}
+
+// MarshalJSON appends the trace to the verdict's own encoding, which the
+// embedded Verdict.MarshalJSON would otherwise produce on its own.
+func (o evalOutput) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(o.Verdict)
+	if err != nil || len(o.Trace) == 0 {
+		return data, err
+	}
+	trace, err := json.Marshal(o.Trace)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data[:len(data)-1], `,"trace":`...)
+	data = append(data, trace...)
+	return append(data, '}'), nil
+}
+
+type traceEntry struct {
+	PolicyID string       `json:"policy_id"`
+	Priority int          `json:"priority"`
+	Effect   guard.Effect `json:"effect"`
+	Status   string       `json:"status"`
+	Winner   bool         `json:"winner,omitempty"`
+}
+
+func runEval(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		policy  = fs.String("policy", "", "policy file to load (required)")
+		explain = fs.Bool("explain", false, "print every policy and whether it matched")
+		asJSON  = fs.Bool("json", false, "print the verdict as JSON")
+		ec      guard.EvalContext
+		argKV   = keyValues{}
+		tagKV   = keyValues{}
+	)
+	fs.StringVar(&ec.Tool, "tool", "", "tool name")
+	fs.StringVar(&ec.Mode, "mode", "", "execution mode")
+	fs.StringVar(&ec.Model, "model", "", "model name")
+	fs.StringVar(&ec.Channel, "channel", "", "communication channel")
+	fs.StringVar(&ec.McpServer, "mcp-server", "", "MCP server name")
+	fs.StringVar(&ec.McpMethod, "mcp-method", "", "MCP method, e.g. tools/call")
+	fs.StringVar(&ec.Agent, "agent", "", "acting agent")
+	fs.StringVar(&ec.Tenant, "tenant", "", "tenant")
+	fs.StringVar(&ec.Risk, "risk", "", "risk level")
+	fs.StringVar(&ec.User, "user", "", "user ID")
+	fs.StringVar(&ec.Session, "session", "", "session ID")
+	fs.StringVar(&ec.SourceIP, "source-ip", "", "caller IP address")
+	fs.Float64Var(&ec.EstimatedCostUSD, "cost", 0, "estimated cost in USD")
+	fs.IntVar(&ec.TokenCount, "tokens", 0, "token count")
+	fs.IntVar(&ec.RiskScore, "risk-score", 0, "numeric risk score")
+	fs.IntVar(&ec.SessionAgeSeconds, "session-age", 0, "session age in seconds")
+	fs.BoolFunc("read-only", "the tool only reads (--read-only=false for a mutating tool)", func(s string) error {
+		b, err := strconv.ParseBool(s)
+		ec.ReadOnly = &b
+		return err
+	})
+	fs.BoolVar(&ec.Authenticated, "authenticated", false, "the caller is authenticated")
+	fs.Func("tools", "comma-separated tools of a batch call, matched if any matches", func(s string) error {
+		ec.Tools = strings.Split(s, ",")
+		return nil
+	})
+	fs.Func("risks", "comma-separated risk tags, matched if any matches", func(s string) error {
+		ec.Risks = strings.Split(s, ",")
+		return nil
+	})
+	fs.Var(argKV, "arg", "tool argument as key=value (repeatable)")
+	fs.Var(tagKV, "tag", "session tag as key=value (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitAllow
+		}
+		return exitError
+	}
+	if *policy == "" {
+		fmt.Fprintln(stderr, "guard eval: --policy is required")
+		return exitError
+	}
+	if len(argKV) > 0 {
+		ec.Args = argKV
+	}
+	if len(tagKV) > 0 {
+		ec.Tags = tagKV
+	}
+
+	ps, err := guard.LoadPolicySet(*policy)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	warn := guard.WithDeprecationWarner(func(id, msg string) {
+		if msg == "" {
+			fmt.Fprintf(stderr, "guard eval: warning: policy %q is deprecated\n", id)
+			return
+		}
+		fmt.Fprintf(stderr, "guard eval: warning: policy %q is deprecated: %s\n", id, msg)
+	})
+	v, results := guard.NewPolicyEngineWithOptions(ps, warn).EvaluateDetailed(ec)
+
+	if *asJSON {
+		out := evalOutput{Verdict: v}
+		if *explain {
+			for _, r := range results {
+				out.Trace = append(out.Trace, traceEntry{
+					PolicyID: r.PolicyID, Priority: r.Priority, Effect: r.Effect,
+					Status: matchStatus(r), Winner: r.Winner,
+				})
+			}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	} else {
+		fmt.Fprintln(stdout, v.Effect)
+		if *explain {
+			printExplain(stdout, v, results)
+		}
+	}
+	return exitCode(v.Effect)
+}
+
+// printExplain writes the verdict details and a table of every policy,
+// with the winner marked by "*".
+func printExplain(w io.Writer, v guard.Verdict, results []guard.MatchResult) {
+	fmt.Fprintf(w, "source: %s\n", v.Source)
+	if v.PolicyID != "" {
+		fmt.Fprintf(w, "policy: %s\n", v.PolicyID)
+	}
+	if v.Channel != "" {
+		fmt.Fprintf(w, "channel: %s\n", v.Channel)
+	}
+	if v.Reason != "" {
+		fmt.Fprintf(w, "reason: %s\n", v.Reason)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nPOLICY\tPRIORITY\tEFFECT\tSTATUS")
+	for _, r := range results {
+		marker := " "
+		if r.Winner {
+			marker = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%d\t%s\t%s\n", marker, r.PolicyID, r.Priority, r.Effect, matchStatus(r))
+	}
+	tw.Flush()
+}
+
+func matchStatus(r guard.MatchResult) string {
+	switch {
+	case !r.Enabled:
+		return "disabled"
+	case r.Expired:
+		return "expired"
+	case r.Pending:
+		return "pending"
+	case r.Matched:
+		return "matched"
+	}
+	return "no match"
+}
+
+func exitCode(e guard.Effect) int {
+	switch e {
+	case guard.EffectAllow:
+		return exitAllow
+	case guard.EffectDeny:
+		return exitDeny
+	}
+	return exitOther
+}
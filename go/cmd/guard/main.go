@@ -0,0 +1,60 @@
+// Command guard evaluates and checks agent-policy files from the shell.
+//
+//	guard eval --policy balanced.yaml --tool bash --mode background --risk high
+//	guard validate --strict policies/*.yaml
+//	guard lint policies/*.yaml
+//
+// The exit status of eval reflects the verdict so scripts can branch on
+// it: 0 for allow, 2 for deny, 3 for any other effect, and 1 on error.
+// validate and lint exit with 1 when they report an error, or with
+// --strict any finding, so they can gate CI and pre-commit hooks.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes.
+const (
+	exitAllow = 0
+	exitError = 1
+	exitDeny  = 2
+	exitOther = 3
+)
+
+const usage = `usage: guard <command> [flags]
+
+Commands:
+  eval      evaluate a single context against a policy file
+  validate  check policy files for errors
+  lint      report shadowed and conflicting policies
+
+Run "guard <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	switch args[0] {
+	case "eval":
+		return runEval(args[1:], stdout, stderr)
+	case "validate":
+		return runValidate(args[1:], stdout, stderr)
+	case "lint":
+		return runLint(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitAllow
+	}
+	fmt.Fprintf(stderr, "guard: unknown command %q\n\n%s", args[0], usage)
+	return exitError
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	guard "github.com/agent-policy/guard"
+)
+
+var balanced = filepath.Join("..", "..", "..", "examples", "balanced.yaml")
+
+func runGuard(args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestEvalExitCodes(t *testing.T) {
+	cases := []struct {
+		args   []string
+		effect string
+		code   int
+	}{
+		{[]string{"--risk", "low"}, "allow", exitAllow},
+		{[]string{"--mode", "background", "--risk", "high"}, "deny", exitDeny},
+		{[]string{"--mode", "interactive", "--risk", "high"}, "hitl", exitOther},
+		{[]string{"--mode", "scheduler", "--risk", "high"}, "deny", exitDeny}, // via context fallback
+	}
+	for _, tc := range cases {
+		args := append([]string{"eval", "--policy", balanced, "--tool", "bash"}, tc.args...)
+		code, out, errOut := runGuard(args...)
+		if code != tc.code || strings.TrimSpace(out) != tc.effect {
+			t.Errorf("%v: got code %d output %q (stderr %q), want %d %q", tc.args, code, out, errOut, tc.code, tc.effect)
+		}
+	}
+}
+
+func TestEvalExplain(t *testing.T) {
+	code, out, _ := runGuard("eval", "--policy", balanced, "--tool", "bash", "--mode", "background", "--risk", "high", "--explain")
+	if code != exitDeny {
+		t.Fatalf("expected exit %d, got %d", exitDeny, code)
+	}
+	for _, want := range []string{"policy: deny-background-high", "* deny-background-high", "  allow-low-risk"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestEvalJSON(t *testing.T) {
+	code, out, _ := runGuard("eval", "--policy", balanced, "--tool", "make_voice_call", "--json", "--explain")
+	if code != exitOther {
+		t.Fatalf("expected exit %d, got %d", exitOther, code)
+	}
+	var got evalOutput
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if got.Effect != "pitl" || got.Channel != "phone" || got.PolicyID != "phone-verify-calls" || got.Source != "matched" {
+		t.Errorf("unexpected verdict %+v", got)
+	}
+	winners := 0
+	for _, e := range got.Trace {
+		if e.Winner {
+			winners++
+		}
+	}
+	if len(got.Trace) == 0 || winners != 1 {
+		t.Errorf("expected a trace with one winner, got %+v", got.Trace)
+	}
+}
+
+func TestEvalJSONMatchesHTTPVerdict(t *testing.T) {
+	path := writeFile(t, "dryrun.yaml", checkHeader+`
+policies:
+  - id: new-deny-bash
+    effect: deny
+    priority: 1
+    dry_run: true
+    condition:
+      tools: [bash]
+  - id: ask-bash
+    effect: ask
+    priority: 10
+    condition:
+      tools: [bash]
+`)
+	ps, err := guard.LoadPolicySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(guard.NewPolicyEngine(ps).Evaluate(guard.EvalContext{Tool: "bash"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want map[string]any
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if want["dry_run_policy_id"] != "new-deny-bash" {
+		t.Fatalf("expected a dry-run verdict, got %s", data)
+	}
+
+	_, out, _ := runGuard("eval", "--policy", path, "--tool", "bash", "--json")
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CLI verdict differs from HTTP verdict:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestKeyValuesFlag(t *testing.T) {
+	var kv keyValues = map[string]string{}
+	if err := kv.Set("path=/etc/passwd"); err != nil || kv["path"] != "/etc/passwd" {
+		t.Errorf("Set: %v %v", err, kv)
+	}
+	if err := kv.Set("novalue"); err == nil {
+		t.Error("expected error for missing =")
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"bogus"},
+		{"eval", "--tool", "bash"},
+		{"eval", "--policy", "does-not-exist.yaml"},
+		{"eval", "--policy", balanced, "--arg", "nokey"},
+	}
+	for _, args := range cases {
+		if code, _, _ := runGuard(args...); code != exitError {
+			t.Errorf("%v: expected exit %d, got %d", args, exitError, code)
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const checkHeader = `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: check
+`
+
+func TestValidate(t *testing.T) {
+	bad := writeFile(t, "bad.yaml", checkHeader+`
+context_fallbacks:
+  a: b
+  b: a
+policies:
+  - id: p1
+    effect: allow
+  - id: p1
+    effect: deny
+`)
+	custom := writeFile(t, "custom.yaml", checkHeader+`
+policies:
+  - id: p1
+    effect: quarantine
+`)
+
+	if code, out, _ := runGuard("validate", balanced); code != exitAllow || out != "" {
+		t.Errorf("balanced: expected clean exit, got %d %q", code, out)
+	}
+	code, out, _ := runGuard("validate", balanced, bad)
+	if code != exitError {
+		t.Errorf("bad: expected exit %d, got %d", exitError, code)
+	}
+	for _, want := range []string{bad + `: error: guard: policy "p1": duplicate id`, "cycle: a -> b -> a"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if code, _, _ := runGuard("validate", custom); code != exitAllow {
+		t.Errorf("custom effect without --strict: expected exit 0, got %d", code)
+	}
+	if code, out, _ := runGuard("validate", "--strict", custom); code != exitError || !strings.Contains(out, "unknown effect") {
+		t.Errorf("custom effect with --strict: got %d %q", code, out)
+	}
+	typo := writeFile(t, "typo.yaml", checkHeader+`
+policies:
+  - id: p1
+    effect: deny
+    condition:
+      tool: [bash]
+`)
+	if code, out, _ := runGuard("validate", "--strict", typo); code != exitError || !strings.Contains(out, "field tool not found") {
+		t.Errorf("unknown key with --strict: got %d %q", code, out)
+	}
+	if code, _, _ := runGuard("validate"); code != exitError {
+		t.Errorf("no files: expected exit %d, got %d", exitError, code)
+	}
+}
+
+func TestLint(t *testing.T) {
+	shadowed := writeFile(t, "shadowed.yaml", checkHeader+`
+policies:
+  - id: catch-all
+    priority: 10
+    effect: ask
+    condition:
+      tools: ["*"]
+  - id: deny-rm
+    priority: 20
+    effect: deny
+    condition:
+      tools: [rm]
+`)
+	code, out, _ := runGuard("lint", shadowed)
+	if code != exitError {
+		t.Errorf("expected exit %d, got %d", exitError, code)
+	}
+	for _, want := range []string{
+		`error: policy "deny-rm" is shadowed`,
+		`warning: policies "catch-all" (ask) and "deny-rm" (deny) both match {"tool":"rm"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	// Warnings alone only fail with --strict.
+	if code, out, _ := runGuard("lint", balanced); code != exitAllow || !strings.Contains(out, "warning") {
+		t.Errorf("balanced: expected warnings and exit 0, got %d %q", code, out)
+	}
+	if code, _, _ := runGuard("lint", "--strict", balanced); code != exitError {
+		t.Errorf("balanced --strict: expected exit %d, got %d", exitError, code)
+	}
+}
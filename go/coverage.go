@@ -0,0 +1,74 @@
+package guard
+
+// ── Coverage ───────────────────────────────────────────────────────────
+
+// Coverage reports how a corpus of contexts exercised a policy set. It
+// encodes to JSON for use as a CI artifact.
+type Coverage struct {
+	Total     int              `json:"total"`
+	Defaulted int              `json:"defaulted"` // verdicts no policy decided
+	Policies  []PolicyCoverage `json:"policies"`
+	// Unused lists, in precedence order, the policies that decided no
+	// verdict. Those that also have no matches never fired at all.
+	Unused []string `json:"unused,omitempty"`
+}
+
+// PolicyCoverage counts, for one policy, the verdicts it decided (as
+// HitCounts would) and the contexts whose own mode it matched, whether or
+// not it won them.
+type PolicyCoverage struct {
+	PolicyID string `json:"policy_id"`
+	Wins     int    `json:"wins"`
+	Matches  int    `json:"matches"`
+}
+
+// CoverageReport evaluates ctxs against a fresh engine loaded with ps and
+// reports which policies they exercised, in precedence order. It is the
+// offline counterpart of HitCounts: the result depends only on ps and the
+// corpus. Contexts without a Now are evaluated at the current time.
+// Result policies are never exercised, as only EvaluateResult considers
+// them.
+func CoverageReport(ps *PolicySet, ctxs []EvalContext) Coverage {
+	engine := NewPolicyEngine(ps)
+	policies := engine.Policies()
+	index := make(map[string]int, len(policies))
+	report := Coverage{Total: len(ctxs), Policies: make([]PolicyCoverage, len(policies))}
+	for i := range policies {
+		index[policies[i].ID] = i
+		report.Policies[i].PolicyID = policies[i].ID
+	}
+
+	for _, ec := range ctxs {
+		if v := engine.Evaluate(ec); v.PolicyID == "" {
+			report.Defaulted++
+		}
+		for _, m := range engine.EvaluateMatched(ec) {
+			report.Policies[index[m.PolicyID]].Matches++
+		}
+	}
+	for id, n := range engine.HitCounts() {
+		report.Policies[index[id]].Wins = int(n)
+	}
+	for _, p := range report.Policies {
+		if p.Wins == 0 {
+			report.Unused = append(report.Unused, p.PolicyID)
+		}
+	}
+	return report
+}
+
+// Distribution evaluates every context in universe against a fresh engine
+// loaded with ps and counts the verdicts by effect, e.g. to report that
+// 40% of an organisation's tools are denied. Together with
+// GenerateSampleContexts it gives a quick snapshot of a set's posture.
+// Like CoverageReport, the result depends only on ps and universe, and
+// contexts without a Now are evaluated at the current time. Effects no
+// verdict carried are absent; the map encodes to JSON with sorted keys.
+func Distribution(ps *PolicySet, universe []EvalContext) map[Effect]int {
+	engine := NewPolicyEngine(ps)
+	tally := make(map[Effect]int)
+	for _, ec := range universe {
+		tally[engine.Evaluate(ec).Effect]++
+	}
+	return tally
+}
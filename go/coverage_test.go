@@ -0,0 +1,86 @@
+package guard
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCoverageReport(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-rm", Priority: 1, Effect: EffectDeny, Condition: Condition{Tools: []string{"rm"}}},
+		{ID: "ask-shell", Priority: 5, Effect: EffectAsk, Condition: Condition{Tools: []string{"bash", "rm"}}},
+		{ID: "deny-safe", Priority: 5, Effect: EffectDeny, Condition: Condition{Modes: []string{"safe"}, Tools: []string{"bash"}}},
+		{ID: "never", Priority: 9, Effect: EffectDeny, Condition: Condition{Tools: []string{"format-disk"}}},
+	}, EffectAllow)
+	ps.ContextFallbacks = map[string]string{"auto": "safe"}
+	corpus := []EvalContext{
+		{Tool: "rm"},
+		{Tool: "rm"},
+		{Tool: "bash"},
+		{Mode: "safe", Tool: "bash"},
+		{Tool: "view"},
+		{Mode: "auto", Tool: "view"},
+	}
+
+	got := CoverageReport(ps, corpus)
+	want := Coverage{
+		Total:     6,
+		Defaulted: 2,
+		Policies: []PolicyCoverage{
+			{PolicyID: "deny-rm", Wins: 2, Matches: 2},
+			{PolicyID: "ask-shell", Wins: 2, Matches: 4},
+			{PolicyID: "deny-safe", Wins: 0, Matches: 1},
+			{PolicyID: "never", Wins: 0, Matches: 0},
+		},
+		Unused: []string{"deny-safe", "never"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Coverage
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, want) {
+		t.Errorf("JSON round trip = %+v, %v", decoded, err)
+	}
+}
+
+func TestCoverageReportEmptyCorpus(t *testing.T) {
+	got := CoverageReport(hitPolicySet(), nil)
+	if got.Total != 0 || len(got.Unused) != len(got.Policies) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-rm", Priority: 1, Effect: EffectDeny, Condition: Condition{Tools: []string{"rm", "dd"}}},
+		{ID: "ask-shell", Priority: 5, Effect: EffectAsk, Condition: Condition{Tools: []string{"bash", "deploy"}}},
+		{ID: "allow-read", Priority: 5, Effect: EffectAllow, Condition: Condition{Tools: []string{"view", "grep", "ls"}}},
+	}, EffectAsk)
+	var universe []EvalContext
+	for _, tool := range []string{"rm", "dd", "bash", "deploy", "view", "grep", "ls", "edit", "curl", "rm"} {
+		universe = append(universe, EvalContext{Tool: tool})
+	}
+
+	got := Distribution(ps, universe)
+	want := map[Effect]int{EffectDeny: 3, EffectAsk: 4, EffectAllow: 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"allow":3,"ask":4,"deny":3}`; string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+
+	if got := Distribution(ps, nil); len(got) != 0 {
+		t.Errorf("empty universe: got %v", got)
+	}
+}
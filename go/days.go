@@ -0,0 +1,67 @@
+package guard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ── Days of the week ───────────────────────────────────────────────────
+
+// weekdaySet is a set of weekdays, bit n standing for time.Weekday(n).
+type weekdaySet uint8
+
+func (s weekdaySet) has(d time.Weekday) bool {
+	return s&(1<<d) != 0
+}
+
+// names lists the days in s as lowercase abbreviations, Sunday first.
+func (s weekdaySet) names() []string {
+	out := []string{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if s.has(d) {
+			out = append(out, strings.ToLower(d.String()[:3]))
+		}
+	}
+	return out
+}
+
+// parseWeekday accepts a day's English name or its three-letter
+// abbreviation, in any case: "sat", "Sat" and "saturday" are all Saturday.
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseDays parses a Condition.Days list.
+func parseDays(names []string) (weekdaySet, error) {
+	var s weekdaySet
+	for _, name := range names {
+		d, ok := parseWeekday(name)
+		if !ok {
+			return 0, fmt.Errorf("invalid day %q: want sun, mon, tue, wed, thu, fri or sat", name)
+		}
+		s |= 1 << d
+	}
+	return s, nil
+}
+
+// usesDays reports whether p's condition or any of its AnyOf blocks
+// constrains the day of the week.
+func usesDays(p *Policy) bool {
+	if p.Condition.Days != nil {
+		return true
+	}
+	for i := range p.AnyOf {
+		if p.AnyOf[i].Days != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package guard
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaysMatchWeekday(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "weekend-deploys", Effect: EffectDeny, Condition: Condition{Tools: []string{"deploy"}, Days: []string{"sat", "Sunday"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	// 2026-05-02 is a Saturday.
+	sat := time.Date(2026, 5, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		now  time.Time
+		tool string
+		want Effect
+	}{
+		{sat, "deploy", EffectDeny},
+		{sat.AddDate(0, 0, 1), "deploy", EffectDeny},
+		{sat.AddDate(0, 0, 2), "deploy", EffectAllow},
+		{sat.AddDate(0, 0, -1), "deploy", EffectAllow},
+		{sat, "view", EffectAllow},
+	}
+	for _, tt := range tests {
+		v := engine.Evaluate(EvalContext{Tool: tt.tool, Now: tt.now})
+		if v.Effect != tt.want {
+			t.Errorf("%s on %s: got %s, want %s", tt.tool, tt.now.Weekday(), v.Effect, tt.want)
+		}
+	}
+
+	// The weekday is the one in Now's own location: late Friday in New
+	// York is already Saturday in UTC.
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	fri := time.Date(2026, 5, 1, 22, 0, 0, 0, ny)
+	if v := engine.Evaluate(EvalContext{Tool: "deploy", Now: fri}); v.Effect != EffectAllow {
+		t.Errorf("Friday night in New York: got %s", v.Effect)
+	}
+}
+
+func TestDaysUseEngineClock(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "weekdays", Effect: EffectAsk, Condition: Condition{Days: []string{"mon", "tue", "wed", "thu", "fri"}}},
+	}, EffectDeny)
+	clock := &fakeClock{now: time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)} // a Monday
+	engine := NewPolicyEngineWithOptions(ps, WithClock(clock))
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Effect != EffectAsk {
+		t.Errorf("Monday: got %s", v.Effect)
+	}
+	clock.now = clock.now.AddDate(0, 0, -1)
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Effect != EffectDeny {
+		t.Errorf("Sunday: got %s", v.Effect)
+	}
+}
+
+func TestDaysValidated(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+version: "1"
+defaults:
+  effect: allow
+policies:
+  - id: weekend
+    effect: deny
+    condition:
+      days: [sat, sunnday]
+`))
+	if !errors.Is(err, ErrInvalidPolicy) || !strings.Contains(err.Error(), `invalid day "sunnday"`) {
+		t.Errorf("expected an invalid day error, got %v", err)
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	s, err := parseDays([]string{"SAT", "monday", "Wed", "sat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.names(), []string{"mon", "wed", "sat"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+	if _, err := parseDays([]string{"weekend"}); err == nil {
+		t.Error("expected an error for weekend")
+	}
+}
+
+func TestAndDays(t *testing.T) {
+	got, err := andDays([]string{"fri", "sat", "sun"}, []string{"Sunday", "sat", "mon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sun", "sat"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := andDays([]string{"sat"}, []string{"mon"}); err == nil {
+		t.Error("expected an error for disjoint days")
+	}
+}
+
+func TestDetectShadowedDays(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "weekend", Priority: 1, Effect: EffectDeny, Condition: Condition{Days: []string{"sat", "sun"}}},
+		{ID: "saturday-bash", Priority: 2, Effect: EffectAsk, Condition: Condition{Tools: []string{"bash"}, Days: []string{"sat"}}},
+		{ID: "any-day-bash", Priority: 3, Effect: EffectAsk, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	if got, want := DetectShadowed(ps), []string{"saturday-bash"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shadowed = %v, want %v", got, want)
+	}
+}
+
+func TestDetectConflictsDays(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "saturday", Priority: 1, Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}, Days: []string{"sat"}}},
+		{ID: "sunday", Priority: 2, Effect: EffectAsk, Condition: Condition{Tools: []string{"bash"}, Days: []string{"sun"}}},
+		{ID: "bash", Priority: 3, Effect: EffectAllow, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	var got []string
+	for _, c := range DetectConflicts(ps) {
+		got = append(got, c.First+"/"+c.Second)
+		if c.Sample.Now.Weekday() != time.Saturday && c.Sample.Now.Weekday() != time.Sunday {
+			t.Errorf("%s/%s: sample on %s", c.First, c.Second, c.Sample.Now.Weekday())
+		}
+	}
+	if want := []string{"saturday/bash", "sunday/bash"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("conflicts = %v, want %v", got, want)
+	}
+}
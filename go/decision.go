@@ -0,0 +1,139 @@
+package guard
+
+import (
+	"sync"
+	"time"
+)
+
+// ── Decision cache ─────────────────────────────────────────────────────
+
+// DecisionKey identifies an approved decision: the session and tool it
+// applies to, and the effect the user approved.
+type DecisionKey struct {
+	Session string
+	Tool    string
+	Effect  Effect
+}
+
+// DecisionCache remembers approvals so that repeated identical invocations
+// within a session are not re-prompted. Implementations own expiry and
+// must be safe for concurrent use.
+type DecisionCache interface {
+	// Get reports whether key holds an unexpired approval.
+	Get(key DecisionKey) bool
+	// Put records an approval for key.
+	Put(key DecisionKey)
+}
+
+// decisionCacheBox lets a nil-able interface live behind an atomic.Pointer.
+type decisionCacheBox struct {
+	cache DecisionCache
+}
+
+// SetDecisionCache installs c as the engine's decision cache, replacing
+// any previous cache. Passing nil disables caching.
+//
+// With a cache installed, a verdict for a context with a session is
+// upgraded to allow when Approve was previously called for the same
+// session, tool and effect and the approval has not expired.
+func (e *PolicyEngine) SetDecisionCache(c DecisionCache) {
+	if c == nil {
+		e.decisions.Store(nil)
+		return
+	}
+	e.decisions.Store(&decisionCacheBox{cache: c})
+}
+
+// Approve records that the user approved v for ctx, e.g. after answering
+// an ask prompt. It is a no-op without a decision cache, when ctx has no
+// session, or when v's effect is allow or terminal (such as deny).
+//
+// ctx is prepared as Evaluate prepares it, running pre-hooks and
+// resolving tool aliases, so the approval is found by the next
+// evaluation of the same invocation.
+func (e *PolicyEngine) Approve(ctx EvalContext, v Verdict) {
+	box := e.decisions.Load()
+	if box == nil || v.Effect == EffectAllow {
+		return
+	}
+	if meta, ok := LookupEffect(v.Effect); ok && meta.Terminal {
+		return
+	}
+	ctx = e.prepare(e.state.Load(), ctx)
+	if ctx.Session == "" {
+		return
+	}
+	box.cache.Put(DecisionKey{Session: ctx.Session, Tool: ctx.toolKey(), Effect: v.Effect})
+}
+
+// applyDecisionCache upgrades v to allow if a matching approval is cached.
+func (e *PolicyEngine) applyDecisionCache(ec EvalContext, v Verdict) Verdict {
+	box := e.decisions.Load()
+	if box == nil || ec.Session == "" || v.Effect == EffectAllow {
+		return v
+	}
+	if box.cache.Get(DecisionKey{Session: ec.Session, Tool: ec.toolKey(), Effect: v.Effect}) {
+		v.Effect = EffectAllow
+	}
+	return v
+}
+
+// MemoryDecisionCache is an in-process DecisionCache whose approvals
+// expire after a fixed TTL.
+type MemoryDecisionCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	expires map[DecisionKey]time.Time
+}
+
+// CacheOption configures a MemoryDecisionCache.
+type CacheOption func(*MemoryDecisionCache)
+
+// WithCacheClock sets the clock the cache reads the current time from when
+// stamping and expiring approvals. The default is the system clock; pass
+// the engine's clock so approvals age with it.
+func WithCacheClock(c Clock) CacheOption {
+	return func(m *MemoryDecisionCache) {
+		if c != nil {
+			m.clock = c
+		}
+	}
+}
+
+// NewMemoryDecisionCache returns a cache whose approvals last for ttl.
+func NewMemoryDecisionCache(ttl time.Duration, opts ...CacheOption) *MemoryDecisionCache {
+	c := &MemoryDecisionCache{
+		ttl:     ttl,
+		clock:   realClock{},
+		expires: make(map[DecisionKey]time.Time),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get reports whether key holds an unexpired approval. Expired entries
+// are removed.
+func (c *MemoryDecisionCache) Get(key DecisionKey) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exp, ok := c.expires[key]
+	if !ok {
+		return false
+	}
+	if !c.clock.Now().Before(exp) {
+		delete(c.expires, key)
+		return false
+	}
+	return true
+}
+
+// Put records an approval for key, valid for the cache's TTL.
+func (c *MemoryDecisionCache) Put(key DecisionKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[key] = c.clock.Now().Add(c.ttl)
+}
@@ -0,0 +1,147 @@
+package guard
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestDecisionCache(ttl time.Duration) (*MemoryDecisionCache, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return NewMemoryDecisionCache(ttl, WithCacheClock(clock)), clock
+}
+
+func decisionPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "ask-bash", Effect: EffectAsk, Priority: 1, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "deny-rm", Effect: EffectDeny, Priority: 1, Condition: Condition{Tools: []string{"rm"}}},
+	}, EffectAsk)
+}
+
+func TestDecisionCacheAllowsAfterApproval(t *testing.T) {
+	engine := NewPolicyEngine(decisionPolicySet())
+	cache, clock := newTestDecisionCache(time.Minute)
+	engine.SetDecisionCache(cache)
+	ctx := EvalContext{Tool: "bash", Session: "s1"}
+
+	v := engine.Evaluate(ctx)
+	if v.Effect != EffectAsk {
+		t.Fatalf("before approval: expected ask, got %s", v.Effect)
+	}
+	engine.Approve(ctx, v)
+
+	v = engine.Evaluate(ctx)
+	if v.Effect != EffectAllow || v.PolicyID != "ask-bash" {
+		t.Errorf("after approval: expected allow from ask-bash, got %+v", v)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Session: "s2"}); v.Effect != EffectAsk {
+		t.Errorf("other session: expected ask, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "view", Session: "s1"}); v.Effect != EffectAsk {
+		t.Errorf("other tool: expected ask, got %s", v.Effect)
+	}
+
+	clock.Advance(59 * time.Second)
+	if v := engine.Evaluate(ctx); v.Effect != EffectAllow {
+		t.Errorf("within TTL: expected allow, got %s", v.Effect)
+	}
+	clock.Advance(time.Second)
+	if v := engine.Evaluate(ctx); v.Effect != EffectAsk {
+		t.Errorf("after TTL: expected ask, got %s", v.Effect)
+	}
+}
+
+func TestDecisionCacheAppliesToAliasedTools(t *testing.T) {
+	ps := decisionPolicySet()
+	ps.Aliases = map[string]string{"shell": "bash"}
+	engine := NewPolicyEngine(ps)
+	cache, _ := newTestDecisionCache(time.Minute)
+	engine.SetDecisionCache(cache)
+
+	shell := EvalContext{Tool: "shell", Session: "s1"}
+	v := engine.Evaluate(shell)
+	if v.Effect != EffectAsk {
+		t.Fatalf("before approval: expected ask, got %s", v.Effect)
+	}
+	engine.Approve(shell, v)
+	if v := engine.Evaluate(shell); v.Effect != EffectAllow {
+		t.Errorf("alias after approval: expected allow, got %s", v.Effect)
+	}
+	// The approval is for the resolved tool, so the canonical name and a
+	// batch naming the alias share it.
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Session: "s1"}); v.Effect != EffectAllow {
+		t.Errorf("canonical name after approval: expected allow, got %s", v.Effect)
+	}
+	engine.Approve(EvalContext{Tools: []string{"shell", "view"}, Session: "s1"}, v)
+	if v := engine.Evaluate(EvalContext{Tools: []string{"bash", "view"}, Session: "s1"}); v.Effect != EffectAllow {
+		t.Errorf("aliased batch after approval: expected allow, got %s", v.Effect)
+	}
+}
+
+func TestDecisionCacheKeyedOnEffect(t *testing.T) {
+	ps := decisionPolicySet()
+	engine := NewPolicyEngine(ps)
+	cache, _ := newTestDecisionCache(time.Minute)
+	engine.SetDecisionCache(cache)
+	ctx := EvalContext{Tool: "bash", Session: "s1"}
+	engine.Approve(ctx, engine.Evaluate(ctx))
+
+	// The policy now requires human review instead; the ask approval no
+	// longer applies.
+	ps.Policies[0].Effect = EffectHITL
+	engine.Load(ps)
+	if v := engine.Evaluate(ctx); v.Effect != EffectHITL {
+		t.Errorf("expected hitl, got %s", v.Effect)
+	}
+}
+
+func TestDecisionCacheIgnoresTerminalAndSessionless(t *testing.T) {
+	engine := NewPolicyEngine(decisionPolicySet())
+	cache, _ := newTestDecisionCache(time.Minute)
+	engine.SetDecisionCache(cache)
+
+	rm := EvalContext{Tool: "rm", Session: "s1"}
+	engine.Approve(rm, engine.Evaluate(rm))
+	if v := engine.Evaluate(rm); v.Effect != EffectDeny {
+		t.Errorf("deny must not be cached, got %s", v.Effect)
+	}
+
+	anon := EvalContext{Tool: "bash"}
+	engine.Approve(anon, engine.Evaluate(anon))
+	if v := engine.Evaluate(anon); v.Effect != EffectAsk {
+		t.Errorf("sessionless approval must not be cached, got %s", v.Effect)
+	}
+}
+
+func TestApproveWithoutCacheIsNoop(t *testing.T) {
+	engine := NewPolicyEngine(decisionPolicySet())
+	ctx := EvalContext{Tool: "bash", Session: "s1"}
+	engine.Approve(ctx, engine.Evaluate(ctx))
+	if v := engine.Evaluate(ctx); v.Effect != EffectAsk {
+		t.Errorf("expected ask without cache, got %s", v.Effect)
+	}
+}
+
+func TestMemoryDecisionCacheExpiry(t *testing.T) {
+	c, clock := newTestDecisionCache(10 * time.Second)
+	key := DecisionKey{Session: "s1", Tool: "bash", Effect: EffectAsk}
+	if c.Get(key) {
+		t.Fatal("empty cache reported a hit")
+	}
+	c.Put(key)
+	clock.Advance(5 * time.Second)
+	if !c.Get(key) {
+		t.Error("expected hit within TTL")
+	}
+	c.Put(key) // refresh
+	clock.Advance(9 * time.Second)
+	if !c.Get(key) {
+		t.Error("expected refreshed approval to still be live")
+	}
+	clock.Advance(time.Second)
+	if c.Get(key) {
+		t.Error("expected miss after TTL")
+	}
+	if len(c.expires) != 0 {
+		t.Errorf("expected expired entry to be evicted, have %d", len(c.expires))
+	}
+}
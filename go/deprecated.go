@@ -0,0 +1,49 @@
+package guard
+
+// ── Deprecation ────────────────────────────────────────────────────────
+
+// DeprecationWarner is told when a deprecated policy produces a verdict,
+// e.g. to log a warning that names the policy so its owners migrate off
+// it. message is the policy's DeprecationMessage and may be empty. It
+// runs synchronously on the evaluating goroutine, so it must be fast and
+// safe for concurrent use.
+type DeprecationWarner func(policyID, message string)
+
+// WithDeprecationWarner calls w whenever Evaluate, EvaluateCtx,
+// EvaluateDetailed or EvaluateBatch returns a verdict from a policy
+// marked Deprecated. The policy is enforced as usual; w only reports it.
+func WithDeprecationWarner(w DeprecationWarner) Option {
+	return func(e *PolicyEngine) {
+		e.deprecation = w
+	}
+}
+
+// deprecatedPolicies returns the DeprecationMessage of every deprecated
+// policy by ID, or nil when there are none.
+func deprecatedPolicies(policies []Policy) map[string]string {
+	var out map[string]string
+	for i := range policies {
+		p := &policies[i]
+		if !p.Deprecated {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		if _, ok := out[p.ID]; !ok {
+			out[p.ID] = p.DeprecationMessage
+		}
+	}
+	return out
+}
+
+// warnDeprecated reports v to the deprecation warner if a deprecated
+// policy produced it.
+func (e *PolicyEngine) warnDeprecated(st *engineState, v Verdict) {
+	if e.deprecation == nil || st.deprecated == nil || v.PolicyID == "" {
+		return
+	}
+	if msg, ok := st.deprecated[v.PolicyID]; ok {
+		e.deprecation(v.PolicyID, msg)
+	}
+}
@@ -0,0 +1,73 @@
+package guard
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDeprecatedPolicyWarns(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: deprecated
+defaults:
+  effect: allow
+  channel: chat
+policies:
+  - id: deny-legacy-shell
+    effect: deny
+    priority: 10
+    deprecated: true
+    deprecation_message: use deny-shell instead
+    condition:
+      tools: [sh]
+  - id: deny-shell
+    extends: deny-legacy-shell
+    condition:
+      tools: [bash]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := ps.Policies[1]; p.Deprecated || p.DeprecationMessage != "" {
+		t.Errorf("deprecation inherited through extends: %+v", p)
+	}
+
+	type warning struct{ id, msg string }
+	var (
+		mu    sync.Mutex
+		warns []warning
+	)
+	engine := NewPolicyEngineWithOptions(ps, WithDeprecationWarner(func(id, msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		warns = append(warns, warning{id, msg})
+	}))
+
+	// The deprecated policy still enforces, and each verdict it produces
+	// is reported.
+	if v := engine.Evaluate(EvalContext{Tool: "sh"}); v.Effect != EffectDeny || v.PolicyID != "deny-legacy-shell" {
+		t.Errorf("sh: got %s from %q", v.Effect, v.PolicyID)
+	}
+	if v, _ := engine.EvaluateDetailed(EvalContext{Tool: "sh"}); v.PolicyID != "deny-legacy-shell" {
+		t.Errorf("sh detailed: got %q", v.PolicyID)
+	}
+	want := warning{"deny-legacy-shell", "use deny-shell instead"}
+	if len(warns) != 2 || warns[0] != want || warns[1] != want {
+		t.Errorf("warnings = %v, want two of %v", warns, want)
+	}
+
+	warns = nil
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	engine.Evaluate(EvalContext{Tool: "view"})
+	if len(warns) != 0 {
+		t.Errorf("warned for verdicts from other policies or the defaults: %v", warns)
+	}
+
+	results := engine.EvaluateAll(EvalContext{Tool: "sh"})
+	if r := results[0]; r.PolicyID != "deny-legacy-shell" || !r.Deprecated || r.DeprecationMessage != want.msg {
+		t.Errorf("EvaluateAll[0] = %+v, want deprecated deny-legacy-shell", r)
+	}
+	if results[1].Deprecated {
+		t.Errorf("EvaluateAll[1] = %+v, want not deprecated", results[1])
+	}
+}
@@ -0,0 +1,264 @@
+package guard
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ── Diff ───────────────────────────────────────────────────────────────
+
+// Diff is a semantic difference between two PolicySets. Policies are
+// matched by ID, so reordering policies in a file produces no diff.
+type Diff struct {
+	Added            []string         `json:"added,omitempty"`    // policy IDs only in the new set
+	Removed          []string         `json:"removed,omitempty"`  // policy IDs only in the old set
+	Modified         []PolicyChange   `json:"modified,omitempty"` // policies present in both that changed
+	Defaults         []FieldChange    `json:"defaults,omitempty"`
+	ContextFallbacks []FallbackChange `json:"context_fallbacks,omitempty"`
+	Aliases          []AliasChange    `json:"aliases,omitempty"`
+}
+
+// PolicyChange lists the fields that changed for a single policy.
+type PolicyChange struct {
+	ID      string        `json:"id"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is a single changed field. Field uses the YAML key, with
+// nested condition fields written as "condition.tools".
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// AliasChange is a changed tool alias. Old is empty when the alias was
+// added and New is empty when it was removed.
+type AliasChange struct {
+	Alias string `json:"alias"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// FallbackChange is a changed context fallback. Old is empty when the
+// fallback was added and New is empty when it was removed.
+type FallbackChange struct {
+	Mode string `json:"mode"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// Empty reports whether the two sets are semantically identical.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 &&
+		len(d.Defaults) == 0 && len(d.ContextFallbacks) == 0 && len(d.Aliases) == 0
+}
+
+// String renders the diff one change per line, e.g.
+// "policy p1 changed effect allow→deny".
+func (d Diff) String() string {
+	var b strings.Builder
+	for _, id := range d.Added {
+		fmt.Fprintf(&b, "policy %s added\n", id)
+	}
+	for _, id := range d.Removed {
+		fmt.Fprintf(&b, "policy %s removed\n", id)
+	}
+	for _, pc := range d.Modified {
+		for _, c := range pc.Changes {
+			fmt.Fprintf(&b, "policy %s changed %s %s→%s\n", pc.ID, c.Field, c.Old, c.New)
+		}
+	}
+	for _, c := range d.Defaults {
+		fmt.Fprintf(&b, "defaults changed %s %s→%s\n", c.Field, c.Old, c.New)
+	}
+	for _, c := range d.ContextFallbacks {
+		switch {
+		case c.Old == "":
+			fmt.Fprintf(&b, "context fallback %s added →%s\n", c.Mode, c.New)
+		case c.New == "":
+			fmt.Fprintf(&b, "context fallback %s removed (was %s)\n", c.Mode, c.Old)
+		default:
+			fmt.Fprintf(&b, "context fallback %s changed %s→%s\n", c.Mode, c.Old, c.New)
+		}
+	}
+	for _, c := range d.Aliases {
+		switch {
+		case c.Old == "":
+			fmt.Fprintf(&b, "alias %s added →%s\n", c.Alias, c.New)
+		case c.New == "":
+			fmt.Fprintf(&b, "alias %s removed (was %s)\n", c.Alias, c.Old)
+		default:
+			fmt.Fprintf(&b, "alias %s changed %s→%s\n", c.Alias, c.Old, c.New)
+		}
+	}
+	return b.String()
+}
+
+// DiffPolicySets reports the policies added, removed, and modified between
+// old and new, along with changes to defaults, context fallbacks and
+// aliases.
+// A nil set is treated as empty.
+func DiffPolicySets(old, new *PolicySet) Diff {
+	if old == nil {
+		old = &PolicySet{}
+	}
+	if new == nil {
+		new = &PolicySet{}
+	}
+	var d Diff
+
+	oldByID := make(map[string]Policy, len(old.Policies))
+	for _, p := range old.Policies {
+		oldByID[p.ID] = p
+	}
+	newIDs := make(map[string]bool, len(new.Policies))
+	for _, p := range new.Policies {
+		newIDs[p.ID] = true
+		prev, ok := oldByID[p.ID]
+		if !ok {
+			d.Added = append(d.Added, p.ID)
+			continue
+		}
+		var changes []FieldChange
+		diffFields("", reflect.ValueOf(normalizeForDiff(prev)), reflect.ValueOf(normalizeForDiff(p)), &changes)
+		if len(changes) > 0 {
+			d.Modified = append(d.Modified, PolicyChange{ID: p.ID, Changes: changes})
+		}
+	}
+	for _, p := range old.Policies {
+		if !newIDs[p.ID] {
+			d.Removed = append(d.Removed, p.ID)
+		}
+	}
+
+	diffFields("", reflect.ValueOf(old.Defaults), reflect.ValueOf(new.Defaults), &d.Defaults)
+
+	for _, m := range changedKeys(old.ContextFallbacks, new.ContextFallbacks) {
+		d.ContextFallbacks = append(d.ContextFallbacks, FallbackChange{Mode: m, Old: old.ContextFallbacks[m], New: new.ContextFallbacks[m]})
+	}
+	for _, a := range changedKeys(old.Aliases, new.Aliases) {
+		d.Aliases = append(d.Aliases, AliasChange{Alias: a, Old: old.Aliases[a], New: new.Aliases[a]})
+	}
+	return d
+}
+
+// changedKeys returns the sorted keys whose values differ between old and
+// new, including keys present in only one of them.
+func changedKeys(old, new map[string]string) []string {
+	keys := make(map[string]bool)
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range new {
+		keys[k] = true
+	}
+	var out []string
+	for k := range keys {
+		if old[k] != new[k] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// normalizeForDiff resolves fields whose zero value has a meaning, so that
+// an omitted "enabled" and "enabled: true" compare equal.
+func normalizeForDiff(p Policy) Policy {
+	enabled := p.IsEnabled()
+	p.Enabled = &enabled
+	return p
+}
+
+// diffFields appends a FieldChange for every differing exported field of
+// the structs old and new, recursing into nested structs.
+func diffFields(prefix string, old, new reflect.Value, out *[]FieldChange) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlFieldName(f)
+		if name == "" {
+			continue
+		}
+		o, n := old.Field(i), new.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			diffFields(prefix+name+".", o, n, out)
+			continue
+		}
+		if reflect.DeepEqual(o.Interface(), n.Interface()) {
+			continue
+		}
+		*out = append(*out, FieldChange{
+			Field: prefix + name,
+			Old:   formatValue(o),
+			New:   formatValue(n),
+		})
+	}
+}
+
+// yamlFieldName returns the YAML key for a struct field, or "" if the
+// field is not serialised.
+func yamlFieldName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := f.Tag.Get("yaml")
+	name, _, _ := strings.Cut(tag, ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// formatValue renders a field value compactly for diff output.
+func formatValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return formatValue(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return ""
+		}
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case reflect.Map:
+		if v.Len() == 0 {
+			return ""
+		}
+		parts := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			parts = append(parts, formatValue(iter.Key())+"="+formatValue(iter.Value()))
+		}
+		sort.Strings(parts)
+		return "{" + strings.Join(parts, ", ") + "}"
+	case reflect.Struct:
+		var parts []string
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := yamlFieldName(t.Field(i))
+			if name == "" || v.Field(i).IsZero() {
+				continue
+			}
+			parts = append(parts, name+": "+formatValue(v.Field(i)))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprint(v.Interface())
+}
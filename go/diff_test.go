@@ -0,0 +1,160 @@
+package guard
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func diffBase() *PolicySet {
+	return &PolicySet{
+		Metadata: Metadata{Name: "diff"},
+		Defaults: Defaults{Effect: EffectAsk, Channel: ChannelChat},
+		Policies: []Policy{
+			{ID: "p1", Effect: EffectAllow, Priority: 10, Channel: ChannelChat, Condition: Condition{Tools: []string{"bash"}}},
+			{ID: "p2", Effect: EffectDeny, Priority: 20, Channel: ChannelChat, Condition: Condition{Modes: []string{"background"}}},
+		},
+		ContextFallbacks: map[string]string{"scheduler": "background"},
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	d := DiffPolicySets(diffBase(), diffBase())
+	if !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+	if d.String() != "" {
+		t.Errorf("expected empty string, got %q", d.String())
+	}
+}
+
+func TestDiffAddedAndRemoved(t *testing.T) {
+	old := diffBase()
+	new := diffBase()
+	new.Policies = append(new.Policies[1:], Policy{ID: "p3", Effect: EffectHITL, Priority: 30})
+
+	d := DiffPolicySets(old, new)
+	if !reflect.DeepEqual(d.Added, []string{"p3"}) {
+		t.Errorf("expected p3 added, got %v", d.Added)
+	}
+	if !reflect.DeepEqual(d.Removed, []string{"p1"}) {
+		t.Errorf("expected p1 removed, got %v", d.Removed)
+	}
+	if len(d.Modified) != 0 {
+		t.Errorf("expected no modifications, got %+v", d.Modified)
+	}
+}
+
+func TestDiffFieldLevelModifications(t *testing.T) {
+	old := diffBase()
+	new := diffBase()
+	new.Policies[0].Effect = EffectDeny
+	new.Policies[0].Priority = 5
+	new.Policies[0].Channel = ChannelPhone
+	new.Policies[0].Condition.Tools = []string{"bash", "sh"}
+	new.Policies[0].Condition.Modes = []string{"interactive"}
+
+	d := DiffPolicySets(old, new)
+	if len(d.Modified) != 1 || d.Modified[0].ID != "p1" {
+		t.Fatalf("expected p1 modified, got %+v", d.Modified)
+	}
+	want := []FieldChange{
+		{Field: "effect", Old: "allow", New: "deny"},
+		{Field: "priority", Old: "10", New: "5"},
+		{Field: "condition.modes", Old: "", New: "[interactive]"},
+		{Field: "condition.tools", Old: "[bash]", New: "[bash, sh]"},
+		{Field: "channel", Old: "chat", New: "phone"},
+	}
+	if !reflect.DeepEqual(d.Modified[0].Changes, want) {
+		t.Errorf("unexpected changes:\n got %+v\nwant %+v", d.Modified[0].Changes, want)
+	}
+	if !strings.Contains(d.String(), "policy p1 changed effect allow→deny\n") {
+		t.Errorf("unexpected rendering:\n%s", d.String())
+	}
+}
+
+func TestDiffIgnoresOrderAndImplicitEnabled(t *testing.T) {
+	old := diffBase()
+	new := diffBase()
+	new.Policies[0], new.Policies[1] = new.Policies[1], new.Policies[0]
+	new.Policies[0].Enabled = boolPtr(true)
+
+	if d := DiffPolicySets(old, new); !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+
+	new.Policies[0].Enabled = boolPtr(false)
+	d := DiffPolicySets(old, new)
+	if len(d.Modified) != 1 || d.Modified[0].Changes[0] != (FieldChange{Field: "enabled", Old: "true", New: "false"}) {
+		t.Errorf("expected enabled change, got %+v", d.Modified)
+	}
+}
+
+func TestDiffDefaultsAndFallbacks(t *testing.T) {
+	old := diffBase()
+	new := diffBase()
+	new.Defaults.Effect = EffectDeny
+	new.ContextFallbacks = map[string]string{"scheduler": "interactive", "bot": "background"}
+
+	d := DiffPolicySets(old, new)
+	if !reflect.DeepEqual(d.Defaults, []FieldChange{{Field: "effect", Old: "ask", New: "deny"}}) {
+		t.Errorf("unexpected defaults diff %+v", d.Defaults)
+	}
+	want := []FallbackChange{
+		{Mode: "bot", New: "background"},
+		{Mode: "scheduler", Old: "background", New: "interactive"},
+	}
+	if !reflect.DeepEqual(d.ContextFallbacks, want) {
+		t.Errorf("unexpected fallback diff %+v", d.ContextFallbacks)
+	}
+
+	delete(new.ContextFallbacks, "scheduler")
+	d = DiffPolicySets(old, new)
+	if !strings.Contains(d.String(), "context fallback scheduler removed (was background)") {
+		t.Errorf("unexpected rendering:\n%s", d.String())
+	}
+}
+
+func TestDiffAliases(t *testing.T) {
+	old := diffBase()
+	old.Aliases = map[string]string{"shell": "bash", "run": "bash"}
+	new := diffBase()
+	new.Aliases = map[string]string{"shell": "sh", "exec": "bash"}
+
+	d := DiffPolicySets(old, new)
+	want := []AliasChange{
+		{Alias: "exec", New: "bash"},
+		{Alias: "run", Old: "bash"},
+		{Alias: "shell", Old: "bash", New: "sh"},
+	}
+	if !reflect.DeepEqual(d.Aliases, want) || d.Empty() {
+		t.Errorf("unexpected alias diff %+v", d.Aliases)
+	}
+	if !strings.Contains(d.String(), "alias run removed (was bash)") {
+		t.Errorf("unexpected rendering:\n%s", d.String())
+	}
+}
+
+func TestDiffNilSets(t *testing.T) {
+	d := DiffPolicySets(nil, diffBase())
+	if !reflect.DeepEqual(d.Added, []string{"p1", "p2"}) {
+		t.Errorf("expected all added, got %v", d.Added)
+	}
+	d = DiffPolicySets(diffBase(), nil)
+	if !reflect.DeepEqual(d.Removed, []string{"p1", "p2"}) {
+		t.Errorf("expected all removed, got %v", d.Removed)
+	}
+}
+
+func TestDiffExpiresAtFormatted(t *testing.T) {
+	before := time.Date(2025, 3, 7, 17, 0, 0, 0, time.UTC)
+	after := before.Add(48 * time.Hour)
+	old := makePolicySet([]Policy{{ID: "p1", Effect: EffectAllow, ExpiresAt: &before}}, EffectAsk)
+	new := makePolicySet([]Policy{{ID: "p1", Effect: EffectAllow, ExpiresAt: &after}}, EffectAsk)
+
+	d := DiffPolicySets(old, new)
+	if got := d.String(); got != "policy p1 changed expires_at 2025-03-07T17:00:00Z→2025-03-09T17:00:00Z\n" {
+		t.Errorf("got %q", got)
+	}
+}
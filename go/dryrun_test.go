@@ -0,0 +1,129 @@
+package guard
+
+import "testing"
+
+func dryRunPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "new-deny-bash", Priority: 1, Effect: EffectDeny, DryRun: true, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "ask-shell", Priority: 5, Effect: EffectAsk, Condition: Condition{Tools: []string{"bash", "sh"}}},
+		{ID: "new-deny-rm", Priority: 1, Effect: EffectDeny, DryRun: true, Condition: Condition{Tools: []string{"rm"}}},
+	}, EffectAllow)
+}
+
+func TestDryRunDoesNotEnforce(t *testing.T) {
+	engine := NewPolicyEngine(dryRunPolicySet())
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+
+	tests := []struct {
+		tool       string
+		effect     Effect
+		policyID   string
+		dryRunID   string
+		dryRunWant Effect
+	}{
+		{"bash", EffectAsk, "ask-shell", "new-deny-bash", EffectDeny},
+		{"rm", EffectAllow, "", "new-deny-rm", EffectDeny},
+		{"sh", EffectAsk, "ask-shell", "", ""},
+		{"view", EffectAllow, "", "", ""},
+	}
+	for _, tt := range tests {
+		v := engine.Evaluate(EvalContext{Tool: tt.tool})
+		if v.Effect != tt.effect || v.PolicyID != tt.policyID {
+			t.Errorf("%s: got %s from %q, want %s from %q", tt.tool, v.Effect, v.PolicyID, tt.effect, tt.policyID)
+		}
+		if v.DryRunPolicyID != tt.dryRunID || v.DryRunEffect != tt.dryRunWant {
+			t.Errorf("%s: dry run = %q/%q, want %q/%q", tt.tool, v.DryRunPolicyID, v.DryRunEffect, tt.dryRunID, tt.dryRunWant)
+		}
+	}
+
+	if len(sink.entries) != len(tests) {
+		t.Fatalf("expected %d audit entries, got %d", len(tests), len(sink.entries))
+	}
+	if e := sink.entries[0]; e.PolicyID != "ask-shell" || e.Verdict.DryRunPolicyID != "new-deny-bash" {
+		t.Errorf("audit entry = %+v", e)
+	}
+	if n := engine.HitCounts()["new-deny-bash"]; n != 0 {
+		t.Errorf("dry-run policy counted %d hits", n)
+	}
+}
+
+func TestDryRunOnlyReportedWhenItWouldWin(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-bash", Priority: 1, Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "new-ask-bash", Priority: 5, Effect: EffectAsk, DryRun: true, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	v := NewPolicyEngine(ps).Evaluate(EvalContext{Tool: "bash"})
+	if v.PolicyID != "deny-bash" || v.DryRunPolicyID != "" {
+		t.Errorf("got %+v", v)
+	}
+}
+
+func TestDryRunWithFallbacks(t *testing.T) {
+	ps := dryRunPolicySet()
+	for i := range ps.Policies {
+		ps.Policies[i].Condition.Modes = []string{"safe"}
+	}
+	ps.ContextFallbacks = map[string]string{"auto": "safe"}
+	engine := NewPolicyEngine(ps)
+
+	v := engine.Evaluate(EvalContext{Mode: "auto", Tool: "bash"})
+	if v.Source != SourceFallbackMatched || v.PolicyID != "ask-shell" || v.DryRunPolicyID != "new-deny-bash" {
+		t.Errorf("bash: got %+v", v)
+	}
+	v = engine.Evaluate(EvalContext{Mode: "auto", Tool: "rm"})
+	if v.Source != SourceDefault || v.DryRunPolicyID != "new-deny-rm" {
+		t.Errorf("rm: got %+v", v)
+	}
+}
+
+func TestDryRunEvaluateAll(t *testing.T) {
+	engine := NewPolicyEngine(dryRunPolicySet())
+	for _, r := range engine.EvaluateAll(EvalContext{Tool: "rm"}) {
+		if r.DryRun != (r.PolicyID != "ask-shell") || r.Matched != (r.PolicyID == "new-deny-rm") {
+			t.Errorf("EvaluateAll result = %+v", r)
+		}
+	}
+
+	matched := engine.EvaluateMatched(EvalContext{Tool: "bash"})
+	if len(matched) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matched)
+	}
+	if matched[0].PolicyID != "new-deny-bash" || !matched[0].DryRun || matched[1].DryRun {
+		t.Errorf("got %+v", matched)
+	}
+
+	v, results := engine.EvaluateDetailed(EvalContext{Tool: "bash"})
+	if v.PolicyID != "ask-shell" || v.DryRunPolicyID != "new-deny-bash" {
+		t.Errorf("detailed verdict = %+v", v)
+	}
+	for _, r := range results {
+		if r.Winner != (r.PolicyID == "ask-shell") || r.DryRun != (r.PolicyID != "ask-shell") {
+			t.Errorf("result = %+v", r)
+		}
+	}
+}
+
+func TestDryRunFromYAML(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+version: "1"
+defaults:
+  effect: allow
+policies:
+  - id: trial
+    effect: deny
+    dry_run: true
+    condition:
+      tools: [bash]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ps.Policies[0].DryRun {
+		t.Fatal("dry_run not loaded")
+	}
+	v := NewPolicyEngine(ps).Evaluate(EvalContext{Tool: "bash"})
+	if v.Effect != EffectAllow || v.DryRunPolicyID != "trial" {
+		t.Errorf("got %+v", v)
+	}
+}
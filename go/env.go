@@ -0,0 +1,52 @@
+package guard
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ── Environment substitution ───────────────────────────────────────────
+
+// envRefRe matches ${VAR} and ${VAR:-default}.
+var envRefRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// WithEnv expands ${VAR} and ${VAR:-default} references from the process
+// environment before the YAML is parsed, including in included files.
+// ${VAR:-default} uses default when VAR is unset or empty. Referencing an
+// unset variable without a default is a load error.
+func WithEnv() LoadOption {
+	return func(o *loadOptions) {
+		o.expandEnv = true
+	}
+}
+
+// expandEnv substitutes environment references in data, reporting every
+// unset variable that has no default.
+func expandEnv(data []byte) ([]byte, error) {
+	missing := make(map[string]bool)
+	out := envRefRe.ReplaceAllFunc(data, func(ref []byte) []byte {
+		idx := envRefRe.FindSubmatchIndex(ref)
+		name := string(ref[idx[2]:idx[3]])
+		hasDefault := idx[4] >= 0
+		if v, ok := os.LookupEnv(name); ok && (v != "" || !hasDefault) {
+			return []byte(v)
+		}
+		if hasDefault {
+			return ref[idx[4]:idx[5]]
+		}
+		missing[name] = true
+		return nil
+	})
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for n := range missing {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("guard: undefined environment variable(s) without default: %s", strings.Join(names, ", "))
+	}
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package guard
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const envDoc = `
+metadata:
+  name: ${GUARD_TEST_NAME:-templated}
+defaults:
+  effect: ${GUARD_TEST_EFFECT}
+policies:
+  - id: bash
+    effect: ${GUARD_TEST_BASH_EFFECT:-ask}
+    condition:
+      tools: [bash]
+`
+
+func TestWithEnvExpandsVariables(t *testing.T) {
+	t.Setenv("GUARD_TEST_EFFECT", "deny")
+	t.Setenv("GUARD_TEST_BASH_EFFECT", "allow")
+
+	ps, err := LoadPolicySetFromBytes([]byte(envDoc), WithEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Defaults.Effect != EffectDeny {
+		t.Errorf("defaults effect = %s, want deny", ps.Defaults.Effect)
+	}
+	if ps.Policies[0].Effect != EffectAllow {
+		t.Errorf("bash effect = %s, want allow", ps.Policies[0].Effect)
+	}
+	if ps.Metadata.Name != "templated" {
+		t.Errorf("name = %q, want default templated", ps.Metadata.Name)
+	}
+}
+
+func TestWithEnvDefaultUsedWhenEmpty(t *testing.T) {
+	t.Setenv("GUARD_TEST_EFFECT", "deny")
+	t.Setenv("GUARD_TEST_BASH_EFFECT", "")
+
+	ps, err := LoadPolicySetFromBytes([]byte(envDoc), WithEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Policies[0].Effect != EffectAsk {
+		t.Errorf("bash effect = %s, want default ask", ps.Policies[0].Effect)
+	}
+}
+
+func TestWithEnvEmptyDefault(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte("metadata: {name: \"x${GUARD_TEST_UNSET_SUFFIX:-}\"}\n"), WithEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Metadata.Name != "x" {
+		t.Errorf("name = %q, want x", ps.Metadata.Name)
+	}
+}
+
+func TestWithEnvMissingVariable(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(envDoc), WithEnv())
+	if err == nil {
+		t.Fatal("expected error for undefined variable")
+	}
+	if !strings.Contains(err.Error(), "GUARD_TEST_EFFECT") {
+		t.Errorf("error should name the variable: %v", err)
+	}
+	if strings.Contains(err.Error(), "GUARD_TEST_BASH_EFFECT") {
+		t.Errorf("variables with defaults must not be reported: %v", err)
+	}
+}
+
+func TestWithoutEnvLeavesReferences(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(envDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Defaults.Effect != "${GUARD_TEST_EFFECT}" {
+		t.Errorf("expected literal reference without WithEnv, got %s", ps.Defaults.Effect)
+	}
+}
+
+func TestWithEnvAppliesToIncludes(t *testing.T) {
+	t.Setenv("GUARD_TEST_EFFECT", "deny")
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml":  "metadata: {name: top}\ninclude: [base.yaml]\n",
+		"base.yaml": "metadata: {name: base}\npolicies: [{id: p, effect: \"${GUARD_TEST_EFFECT}\"}]\n",
+	})
+
+	ps, err := LoadPolicySet(filepath.Join(dir, "top.yaml"), WithEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Policies[0].Effect != EffectDeny {
+		t.Errorf("included effect = %s, want deny", ps.Policies[0].Effect)
+	}
+}
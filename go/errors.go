@@ -0,0 +1,82 @@
+package guard
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ── Errors ─────────────────────────────────────────────────────────────
+
+// Errors reported by the loader and Validate can be classified with
+// errors.Is against these sentinels, and unpacked with errors.As into a
+// *ParseError or *PolicyError. Messages stay human-readable.
+var (
+	// ErrParse reports YAML that could not be decoded; see ParseError.
+	ErrParse = errors.New("failed to parse YAML")
+	// ErrInvalidKind reports a kind other than PolicySet.
+	ErrInvalidKind = errors.New("unsupported kind")
+	// ErrIncludeCycle reports files that include each other.
+	ErrIncludeCycle = errors.New("circular include")
+	// ErrExtendsCycle reports policies that extend each other.
+	ErrExtendsCycle = errors.New("circular extends")
+	// ErrInvalidDefaults reports an unusable defaults block.
+	ErrInvalidDefaults = errors.New("invalid defaults")
+	// ErrInvalidAliases reports an unusable aliases map.
+	ErrInvalidAliases = errors.New("invalid aliases")
+	// ErrInvalidPolicy reports a problem with a single policy; see
+	// PolicyError.
+	ErrInvalidPolicy = errors.New("invalid policy")
+	// ErrMissingID reports a policy without an ID (Validate only).
+	ErrMissingID = errors.New("missing id")
+	// ErrDuplicateID reports a policy ID used twice (Validate only).
+	ErrDuplicateID = errors.New("duplicate id")
+	// ErrDuplicateCode reports a policy code used twice under
+	// WithStrictCodes.
+	ErrDuplicateCode = errors.New("duplicate code")
+	// ErrFallbackCycle reports a cycle in the context fallback chain
+	// (Validate only).
+	ErrFallbackCycle = errors.New("context fallback cycle")
+	// ErrMissingTool reports a NewEvalContext call without a tool.
+	ErrMissingTool = errors.New("missing tool")
+	// ErrNoEnabledPolicies reports a set whose policies are all disabled
+	// under WithRequireEnabled.
+	ErrNoEnabledPolicies = errors.New("no enabled policies")
+)
+
+// ParseError is returned when policy YAML cannot be decoded. It matches
+// ErrParse and wraps the decoder's error.
+type ParseError struct {
+	Document int // 1-based document number for multi-document input, else 0
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	if e.Document > 0 {
+		return fmt.Sprintf("guard: document %d: %s: %v", e.Document, ErrParse, e.Err)
+	}
+	return fmt.Sprintf("guard: %s: %v", ErrParse, e.Err)
+}
+
+func (e *ParseError) Is(target error) bool { return target == ErrParse }
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// PolicyError is returned for a problem with a single policy. It matches
+// ErrInvalidPolicy and wraps the problem, which may itself match a
+// sentinel such as ErrDuplicateID.
+type PolicyError struct {
+	Index int    // the policy's position in PolicySet.Policies
+	ID    string // the policy's ID, empty if it has none
+	Err   error
+}
+
+func (e *PolicyError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("guard: policy #%d: %v", e.Index+1, e.Err)
+	}
+	return fmt.Sprintf("guard: policy %q: %v", e.ID, e.Err)
+}
+
+func (e *PolicyError) Is(target error) bool { return target == ErrInvalidPolicy }
+
+func (e *PolicyError) Unwrap() error { return e.Err }
@@ -0,0 +1,113 @@
+package guard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoaderParseError(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte("policies: [unclosed"))
+	if !errors.Is(err, ErrParse) {
+		t.Fatalf("expected ErrParse, got %v", err)
+	}
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Document != 0 {
+		t.Fatalf("expected a *ParseError, got %#v", err)
+	}
+	var ye *yaml.TypeError
+	if _, err := LoadPolicySetFromBytes([]byte("policies: {a: 1}")); !errors.As(err, &ye) || !errors.Is(err, ErrParse) {
+		t.Errorf("expected the YAML cause to be wrapped, got %v", err)
+	}
+
+	_, err = LoadPolicySetsFromBytes([]byte("policies: []\n---\npolicies: [unclosed\n"))
+	if !errors.As(err, &pe) || pe.Document != 2 {
+		t.Errorf("expected a parse error in document 2, got %v", err)
+	}
+}
+
+func TestLoaderInvalidKind(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte("kind: Deployment\n"))
+	if !errors.Is(err, ErrInvalidKind) {
+		t.Errorf("expected ErrInvalidKind, got %v", err)
+	}
+	if err.Error() != `guard: unsupported kind "Deployment" (expected PolicySet)` {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestLoaderPolicyError(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+policies:
+  - id: ok
+    effect: allow
+  - id: bad
+    effect: deny
+    any_of:
+      - tools: [bash]
+      - source_cidrs: [nope]
+`))
+	if !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected ErrInvalidPolicy, got %v", err)
+	}
+	var pe *PolicyError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected a *PolicyError, got %#v", err)
+	}
+	if pe.Index != 1 || pe.ID != "bad" {
+		t.Errorf("expected policy 1 (bad), got %d (%s)", pe.Index, pe.ID)
+	}
+	if want := `guard: policy "bad": any_of[1]: invalid source CIDR "nope"`; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got message %q, want it to start with %q", err, want)
+	}
+
+	_, err = LoadPolicySetFromBytes([]byte(`
+policies:
+  - {id: a, effect: deny, code: GUARD_X}
+  - {id: b, effect: deny, code: GUARD_X}
+`), WithStrictCodes())
+	if !errors.Is(err, ErrDuplicateCode) || !errors.As(err, &pe) || pe.Index != 1 {
+		t.Errorf("expected a duplicate code at index 1, got %v", err)
+	}
+}
+
+func TestLoaderSetErrors(t *testing.T) {
+	cases := map[string]error{
+		"defaults:\n  per_mode:\n    background: \"\"\n": ErrInvalidDefaults,
+		"aliases:\n  bash: bash\n":                       ErrInvalidAliases,
+	}
+	for doc, want := range cases {
+		if _, err := LoadPolicySetFromBytes([]byte(doc)); !errors.Is(err, want) {
+			t.Errorf("%s: expected %v, got %v", doc, want, err)
+		}
+	}
+}
+
+func TestValidateTypedErrors(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "a", Effect: EffectAllow},
+		{ID: "a", Effect: EffectDeny},
+		{Effect: EffectDeny},
+	}, EffectAsk)
+	ps.ContextFallbacks = map[string]string{"a": "b", "b": "a"}
+
+	var dup, missing, cycle bool
+	for _, err := range Validate(ps) {
+		var pe *PolicyError
+		switch {
+		case errors.Is(err, ErrDuplicateID):
+			dup = errors.As(err, &pe) && pe.Index == 1 && pe.ID == "a"
+		case errors.Is(err, ErrMissingID):
+			missing = errors.As(err, &pe) && pe.Index == 2
+		case errors.Is(err, ErrFallbackCycle):
+			cycle = true
+		default:
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if !dup || !missing || !cycle {
+		t.Errorf("expected duplicate id, missing id and cycle errors: %v %v %v", dup, missing, cycle)
+	}
+}
@@ -0,0 +1,160 @@
+package guard
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// ── EvalContext options ────────────────────────────────────────────────
+
+// ECOption sets a field of an EvalContext built by NewEvalContext.
+type ECOption func(*EvalContext)
+
+// NewEvalContext returns the context for an invocation of tool, with the
+// fields opts set:
+//
+//	ec, err := guard.NewEvalContext("bash",
+//		guard.WithMode("auto"), guard.WithUser("alice"), guard.WithArg("cmd", "ls"))
+//
+// It fails with ErrMissingTool if tool is empty, and rejects a source IP
+// that is not an IP address. Fields without an option can be set on the
+// result.
+func NewEvalContext(tool string, opts ...ECOption) (EvalContext, error) {
+	if tool == "" {
+		return EvalContext{}, fmt.Errorf("guard: eval context: %w", ErrMissingTool)
+	}
+	ec := EvalContext{Tool: tool}
+	for _, opt := range opts {
+		opt(&ec)
+	}
+	if ec.SourceIP != "" {
+		if _, err := netip.ParseAddr(ec.SourceIP); err != nil {
+			return EvalContext{}, fmt.Errorf("guard: eval context: invalid source IP %q", ec.SourceIP)
+		}
+	}
+	return ec, nil
+}
+
+// WithMode sets the execution mode.
+func WithMode(mode string) ECOption {
+	return func(ec *EvalContext) {
+		ec.Mode = mode
+	}
+}
+
+// WithModel sets the model.
+func WithModel(model string) ECOption {
+	return func(ec *EvalContext) {
+		ec.Model = model
+	}
+}
+
+// WithChannel sets the channel the invocation arrives on.
+func WithChannel(channel string) ECOption {
+	return func(ec *EvalContext) {
+		ec.Channel = channel
+	}
+}
+
+// WithMcpServer sets the MCP server and method invoked.
+func WithMcpServer(server, method string) ECOption {
+	return func(ec *EvalContext) {
+		ec.McpServer, ec.McpMethod = server, method
+	}
+}
+
+// WithTools makes the invocation a batch call of the context's tool and
+// tools, setting EvalContext.Tools to all of them.
+func WithTools(tools ...string) ECOption {
+	return func(ec *EvalContext) {
+		if len(ec.Tools) == 0 {
+			ec.Tools = []string{ec.Tool}
+		}
+		ec.Tools = append(ec.Tools, tools...)
+	}
+}
+
+// WithRisk sets the categorical risk level.
+func WithRisk(risk string) ECOption {
+	return func(ec *EvalContext) {
+		ec.Risk = risk
+	}
+}
+
+// WithRisks sets several risk tags, any of which a risk condition may
+// match; see EvalContext.Risks.
+func WithRisks(risks ...string) ECOption {
+	return func(ec *EvalContext) {
+		ec.Risks = append(ec.Risks, risks...)
+	}
+}
+
+// WithUser sets the user.
+func WithUser(user string) ECOption {
+	return func(ec *EvalContext) {
+		ec.User = user
+	}
+}
+
+// WithSession sets the session.
+func WithSession(session string) ECOption {
+	return func(ec *EvalContext) {
+		ec.Session = session
+	}
+}
+
+// WithAgent sets the acting agent.
+func WithAgent(agent string) ECOption {
+	return func(ec *EvalContext) {
+		ec.Agent = agent
+	}
+}
+
+// WithSourceIP sets the caller's IP address.
+func WithSourceIP(ip string) ECOption {
+	return func(ec *EvalContext) {
+		ec.SourceIP = ip
+	}
+}
+
+// WithArg adds a tool argument.
+func WithArg(key, value string) ECOption {
+	return func(ec *EvalContext) {
+		if ec.Args == nil {
+			ec.Args = make(map[string]string)
+		}
+		ec.Args[key] = value
+	}
+}
+
+// WithTag adds a session tag.
+func WithTag(key, value string) ECOption {
+	return func(ec *EvalContext) {
+		if ec.Tags == nil {
+			ec.Tags = make(map[string]string)
+		}
+		ec.Tags[key] = value
+	}
+}
+
+// WithReadOnly says whether the tool only reads.
+func WithReadOnly(readOnly bool) ECOption {
+	return func(ec *EvalContext) {
+		ec.ReadOnly = &readOnly
+	}
+}
+
+// WithAuthenticated says whether the caller is authenticated.
+func WithAuthenticated(authenticated bool) ECOption {
+	return func(ec *EvalContext) {
+		ec.Authenticated = authenticated
+	}
+}
+
+// WithNow sets the evaluation time.
+func WithNow(t time.Time) ECOption {
+	return func(ec *EvalContext) {
+		ec.Now = t
+	}
+}
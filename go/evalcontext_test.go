@@ -0,0 +1,48 @@
+package guard
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewEvalContext(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	got, err := NewEvalContext("bash",
+		WithMode("auto"), WithModel("gpt-5"), WithChannel("chat"),
+		WithMcpServer("fs", "tools/call"), WithRisk("high"), WithUser("alice"),
+		WithSession("s1"), WithAgent("planner"), WithSourceIP("10.0.0.1"),
+		WithArg("cmd", "ls"), WithArg("cwd", "/tmp"), WithTag("team", "payments"),
+		WithReadOnly(false), WithNow(now), WithTools("view"), WithTools("grep"),
+		WithRisks("pii"), WithAuthenticated(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readOnly := false
+	want := EvalContext{
+		Tool: "bash", Mode: "auto", Model: "gpt-5", Channel: "chat",
+		McpServer: "fs", McpMethod: "tools/call", Risk: "high", User: "alice",
+		Session: "s1", Agent: "planner", SourceIP: "10.0.0.1",
+		Args: map[string]string{"cmd": "ls", "cwd": "/tmp"}, Tags: map[string]string{"team": "payments"},
+		ReadOnly: &readOnly, Now: now,
+		Tools: []string{"bash", "view", "grep"}, Risks: []string{"pii"}, Authenticated: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+
+	if got, err := NewEvalContext("view"); err != nil || !reflect.DeepEqual(got, EvalContext{Tool: "view"}) {
+		t.Errorf("no options: got %+v, %v", got, err)
+	}
+}
+
+func TestNewEvalContextValidates(t *testing.T) {
+	if _, err := NewEvalContext("", WithMode("auto")); !errors.Is(err, ErrMissingTool) {
+		t.Errorf("missing tool: got %v, want ErrMissingTool", err)
+	}
+	if _, err := NewEvalContext("bash", WithSourceIP("not-an-ip")); err == nil {
+		t.Error("invalid source IP: expected an error")
+	}
+}
@@ -0,0 +1,91 @@
+package guard
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ── Extends ────────────────────────────────────────────────────────────
+
+// resolveExtends flattens every policy that extends another, so the rest
+// of the loader and the engine only see self-contained policies. A parent
+// is found by ID, the first policy with it if several share one. An
+// undefined parent or a cycle of policies extending each other is an
+// error.
+func resolveExtends(ps *PolicySet) error {
+	index := make(map[string]int, len(ps.Policies))
+	for i := range ps.Policies {
+		if _, ok := index[ps.Policies[i].ID]; !ok {
+			index[ps.Policies[i].ID] = i
+		}
+	}
+	const (
+		unresolved = iota
+		resolving
+		resolved
+	)
+	state := make([]int, len(ps.Policies))
+	var chain []string // IDs being resolved, outermost first
+	var resolve func(i int) error
+	resolve = func(i int) error {
+		p := &ps.Policies[i]
+		switch state[i] {
+		case resolved:
+			return nil
+		case resolving:
+			start := 0
+			for chain[start] != p.ID {
+				start++
+			}
+			cycle := strings.Join(append(chain[start:], p.ID), " -> ")
+			return &PolicyError{Index: i, ID: p.ID, Err: fmt.Errorf("%w: %s", ErrExtendsCycle, cycle)}
+		}
+		if p.Extends == "" {
+			state[i] = resolved
+			return nil
+		}
+		j, ok := index[p.Extends]
+		if !ok {
+			return &PolicyError{Index: i, ID: p.ID, Err: fmt.Errorf("extends undefined policy %q", p.Extends)}
+		}
+		state[i] = resolving
+		chain = append(chain, p.ID)
+		if err := resolve(j); err != nil {
+			return err
+		}
+		chain = chain[:len(chain)-1]
+		parent := copyPolicy(&ps.Policies[j])
+		inheritFields(reflect.ValueOf(p).Elem(), reflect.ValueOf(&parent).Elem())
+		state[i] = resolved
+		return nil
+	}
+	for i := range ps.Policies {
+		if err := resolve(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inheritFields sets every zero field of the struct child to the parent's
+// value, descending into nested structs such as Condition so that their
+// fields are inherited one by one. The ID is never inherited, and nor is
+// a deprecation: extending a deprecated policy is how a set migrates off
+// it.
+func inheritFields(child, parent reflect.Value) {
+	t := child.Type()
+	for i := 0; i < t.NumField(); i++ {
+		switch t.Field(i).Name {
+		case "ID", "Deprecated", "DeprecationMessage":
+			continue
+		}
+		c, p := child.Field(i), parent.Field(i)
+		switch {
+		case c.Kind() == reflect.Struct:
+			inheritFields(c, p)
+		case c.IsZero():
+			c.Set(p)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package guard
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtendsInheritsAndOverrides(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: extends
+defaults:
+  effect: allow
+  channel: chat
+policies:
+  - id: ask-prod-write
+    effect: ask
+    priority: 20
+    channel: phone
+    message: production writes need approval
+    annotations:
+      owner: platform
+    condition:
+      tools: [write, edit]
+      modes: [prod]
+  - id: deny-prod-write-night
+    extends: ask-prod-write
+    effect: deny
+    priority: 10
+    condition:
+      days: [sat, sun]
+  - id: ask-staging-write
+    extends: ask-prod-write
+    condition:
+      modes: [staging]
+  - id: ask-staging-edit
+    extends: ask-staging-write
+    condition:
+      tools: [edit]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]Policy{}
+	for _, p := range ps.Policies {
+		byID[p.ID] = p
+	}
+
+	night := byID["deny-prod-write-night"]
+	if night.Effect != EffectDeny || night.Priority != 10 || night.Channel != ChannelPhone || night.Message != "production writes need approval" {
+		t.Errorf("night = %+v", night)
+	}
+	if !reflect.DeepEqual(night.Condition.Tools, []string{"write", "edit"}) || !reflect.DeepEqual(night.Condition.Modes, []string{"prod"}) ||
+		!reflect.DeepEqual(night.Condition.Days, []string{"sat", "sun"}) {
+		t.Errorf("night condition = %+v", night.Condition)
+	}
+
+	staging := byID["ask-staging-write"]
+	if staging.Effect != EffectAsk || staging.Priority != 20 || !reflect.DeepEqual(staging.Condition.Modes, []string{"staging"}) {
+		t.Errorf("staging = %+v", staging)
+	}
+
+	edit := byID["ask-staging-edit"]
+	if !reflect.DeepEqual(edit.Condition.Tools, []string{"edit"}) || !reflect.DeepEqual(edit.Condition.Modes, []string{"staging"}) || edit.Annotations["owner"] != "platform" {
+		t.Errorf("edit = %+v", edit)
+	}
+
+	// The parent is unchanged and shares nothing with its children.
+	edit.Annotations["owner"] = "changed"
+	if parent := byID["ask-prod-write"]; parent.Annotations["owner"] != "platform" || parent.Condition.Days != nil {
+		t.Errorf("parent = %+v", parent)
+	}
+
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Mode: "staging", Tool: "write"}); v.PolicyID != "ask-staging-write" || v.Channel != ChannelPhone {
+		t.Errorf("got %+v", v)
+	}
+}
+
+func TestExtendsInheritsEmptyLists(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: extends
+policies:
+  - id: deny-nothing
+    effect: deny
+    invert: true
+    condition:
+      tools: []
+  - id: deny-nothing-prod
+    extends: deny-nothing
+    condition:
+      modes: [prod]
+  - id: deny-bash-prod
+    extends: deny-nothing
+    condition:
+      tools: [bash]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]Policy{}
+	for _, p := range ps.Policies {
+		byID[p.ID] = p
+	}
+	if tools := byID["deny-nothing-prod"].Condition.Tools; tools == nil || len(tools) != 0 {
+		t.Errorf("expected tools: [] inherited, got %#v", tools)
+	}
+	if tools := byID["deny-bash-prod"].Condition.Tools; !reflect.DeepEqual(tools, []string{"bash"}) {
+		t.Errorf("expected the child's tools to override, got %#v", tools)
+	}
+}
+
+func TestExtendsErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		policies string
+		want     string
+		sentinel error
+	}{
+		{"missing parent", `
+  - id: a
+    extends: nope`, `extends undefined policy "nope"`, ErrInvalidPolicy},
+		{"self", `
+  - id: a
+    effect: deny
+    extends: a`, "circular extends: a -> a", ErrExtendsCycle},
+		{"cycle", `
+  - id: a
+    extends: b
+  - id: b
+    extends: c
+  - id: c
+    extends: a`, "circular extends: a -> b -> c -> a", ErrExtendsCycle},
+	} {
+		_, err := LoadPolicySetFromBytes([]byte("metadata:\n  name: bad\npolicies:" + tt.policies + "\n"))
+		if !errors.Is(err, tt.sentinel) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
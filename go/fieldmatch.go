@@ -0,0 +1,94 @@
+package guard
+
+import "fmt"
+
+// ── Custom field matchers ──────────────────────────────────────────────
+
+// FieldMatcher reports whether a condition pattern matches a context
+// value. It replaces GlobMatch for the field it is registered for and
+// must be safe for concurrent use.
+type FieldMatcher func(pattern, value string) bool
+
+// SetFieldMatcher makes the engine match the condition field named field
+// with fn instead of GlobMatch, e.g. to compare model names structurally.
+// field is the YAML name of a pattern list: modes, models, channels,
+// tools, mcp_servers, mcp_methods, risk, users, sessions, agents, tenants
+// or output_categories; any other name is an error. Other fields keep
+// using globs, and "group:" users patterns are unaffected. Passing a nil
+// fn restores glob matching.
+//
+// The loaded policies are recompiled, so the change applies to the next
+// evaluation; matchers also apply to later Loads.
+func (e *PolicyEngine) SetFieldMatcher(field string, fn FieldMatcher) error {
+	if (&compiledCondition{}).field(field) == nil {
+		return fmt.Errorf("guard: unknown condition field %q", field)
+	}
+	e.loadMu.Lock()
+	defer e.loadMu.Unlock()
+	if fn == nil {
+		delete(e.fieldMatchers, field)
+	} else {
+		if e.fieldMatchers == nil {
+			e.fieldMatchers = make(map[string]FieldMatcher)
+		}
+		e.fieldMatchers[field] = fn
+	}
+	if st := e.state.Load(); st.loaded {
+		e.state.Store(e.buildState(&PolicySet{
+			Defaults:         st.defaults,
+			Policies:         st.policies,
+			ContextFallbacks: st.contextFallbacks,
+			Aliases:          st.aliases,
+		}))
+	}
+	return nil
+}
+
+// field returns the compiled pattern list for a condition field's YAML
+// name, or nil if there is no such list field.
+func (cc *compiledCondition) field(name string) *patternList {
+	switch name {
+	case "modes":
+		return &cc.modes
+	case "models":
+		return &cc.models
+	case "channels":
+		return &cc.channels
+	case "tools":
+		return &cc.tools
+	case "mcp_servers":
+		return &cc.mcpServers
+	case "mcp_methods":
+		return &cc.mcpMethods
+	case "risk":
+		return &cc.risk
+	case "users":
+		return &cc.users
+	case "sessions":
+		return &cc.sessions
+	case "agents":
+		return &cc.agents
+	case "tenants":
+		return &cc.tenants
+	case "output_categories":
+		return &cc.categories
+	}
+	return nil
+}
+
+// useFieldMatchers switches the fields named in fms, including those of
+// any AnyOf blocks, to their custom matchers.
+func (cc *compiledCondition) useFieldMatchers(fms map[string]FieldMatcher) {
+	for name, fn := range fms {
+		pl := cc.field(name)
+		for i := range pl.matchers {
+			pl.matchers[i] = matcher{kind: matchFunc, pattern: pl.matchers[i].pattern, fn: fn}
+		}
+	}
+	for i := range cc.anyOf {
+		cc.anyOf[i].useFieldMatchers(fms)
+	}
+	if cc.relaxed != nil {
+		cc.relaxed.useFieldMatchers(fms)
+	}
+}
@@ -0,0 +1,81 @@
+package guard
+
+import (
+	"strings"
+	"testing"
+)
+
+// modelFamily matches "family-major" patterns against model names by
+// family and major version, ignoring dated or minor suffixes, so
+// "claude-3" matches "claude-3-5-sonnet-20241022" but not "claude-35".
+func modelFamily(pattern, value string) bool {
+	p, v := strings.Split(pattern, "-"), strings.Split(value, "-")
+	if len(v) < len(p) {
+		return false
+	}
+	for i := range p {
+		if p[i] != v[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func modelPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "allow-claude-3", Effect: EffectAllow, Priority: 10, Condition: Condition{Models: []string{"claude-3"}}},
+		{ID: "deny-bash-gpt", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"bash"}},
+			AnyOf: []Condition{{Models: []string{"gpt-4"}}}},
+	}, EffectAsk)
+}
+
+func TestSetFieldMatcher(t *testing.T) {
+	engine := NewPolicyEngine(modelPolicySet())
+	ctx := EvalContext{Tool: "bash", Model: "claude-3-5-sonnet-20241022"}
+	if v := engine.Evaluate(ctx); v.Effect != EffectAsk {
+		t.Fatalf("glob matching: expected ask, got %s", v.Effect)
+	}
+
+	if err := engine.SetFieldMatcher("models", modelFamily); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		model string
+		want  Effect
+	}{
+		{"claude-3-5-sonnet-20241022", EffectAllow},
+		{"claude-3", EffectAllow},
+		{"claude-35", EffectAsk},
+		{"gpt-4-turbo", EffectDeny}, // any_of blocks use the matcher too
+		{"gpt-40", EffectAsk},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Tool: "bash", Model: tc.model}); v.Effect != tc.want {
+			t.Errorf("%s: expected %s, got %s (%s)", tc.model, tc.want, v.Effect, v.PolicyID)
+		}
+	}
+
+	// Other fields keep glob matching, and the matcher survives reloads.
+	engine.Load(makePolicySet([]Policy{
+		{ID: "p", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash-*"}, Models: []string{"claude-3"}}},
+	}, EffectAsk))
+	if v := engine.Evaluate(EvalContext{Tool: "bash-x", Model: "claude-3-opus"}); v.Effect != EffectDeny {
+		t.Errorf("after reload: expected deny, got %s", v.Effect)
+	}
+
+	if err := engine.SetFieldMatcher("models", nil); err != nil {
+		t.Fatal(err)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash-x", Model: "claude-3-opus"}); v.Effect != EffectAsk {
+		t.Errorf("after removal: expected ask, got %s", v.Effect)
+	}
+}
+
+func TestSetFieldMatcherUnknownField(t *testing.T) {
+	engine := NewPolicyEngine(modelPolicySet())
+	for _, field := range []string{"model", "args", "source_cidrs", ""} {
+		if err := engine.SetFieldMatcher(field, modelFamily); err == nil {
+			t.Errorf("%q: expected error", field)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package guard
+
+import "unicode/utf8"
+
+// ── Double-star matching ───────────────────────────────────────────────
+
+// globTokKind classifies one element of a compiled ** pattern.
+type globTokKind uint8
+
+const (
+	tokLiteral    globTokKind = iota // one rune, possibly escaped
+	tokAnyRune                       // ?: one rune other than "/"
+	tokClass                         // [...]: one rune from a set
+	tokStar                          // *: any run without "/"
+	tokDoubleStar                    // **: any run at all
+)
+
+type globTok struct {
+	kind   globTokKind
+	r      rune
+	ranges []runeRange // tokClass
+	negate bool        // tokClass
+}
+
+type runeRange struct{ lo, hi rune }
+
+// compileDoubleStar splits a well-formed pattern containing ** into
+// tokens. Runs of three or more stars count as **. The pattern must have
+// been checked with path.Match first: malformed input is not diagnosed.
+func compileDoubleStar(pattern string) []globTok {
+	var toks []globTok
+	for i := 0; i < len(pattern); {
+		switch c := pattern[i]; c {
+		case '*':
+			n := 1
+			for i+n < len(pattern) && pattern[i+n] == '*' {
+				n++
+			}
+			i += n
+			kind := tokStar
+			if n > 1 {
+				kind = tokDoubleStar
+			}
+			toks = append(toks, globTok{kind: kind})
+		case '?':
+			toks = append(toks, globTok{kind: tokAnyRune})
+			i++
+		case '[':
+			tok, n := compileClass(pattern[i+1:])
+			toks = append(toks, tok)
+			i += 1 + n
+		default:
+			if c == '\\' && i+1 < len(pattern) {
+				i++
+			}
+			r, n := utf8.DecodeRuneInString(pattern[i:])
+			toks = append(toks, globTok{kind: tokLiteral, r: r})
+			i += n
+		}
+	}
+	return toks
+}
+
+// compileClass parses a character class following its "[", returning the
+// token and the bytes consumed, including the closing "]".
+func compileClass(s string) (globTok, int) {
+	tok := globTok{kind: tokClass}
+	i := 0
+	if i < len(s) && s[i] == '^' {
+		tok.negate = true
+		i++
+	}
+	classRune := func() rune {
+		if s[i] == '\\' {
+			i++
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+		return r
+	}
+	for i < len(s) && (s[i] != ']' || len(tok.ranges) == 0) {
+		lo := classRune()
+		hi := lo
+		if i < len(s) && s[i] == '-' {
+			i++
+			hi = classRune()
+		}
+		tok.ranges = append(tok.ranges, runeRange{lo, hi})
+	}
+	return tok, i + 1
+}
+
+func (t *globTok) matches(r rune) bool {
+	switch t.kind {
+	case tokLiteral:
+		return r == t.r
+	case tokAnyRune, tokStar:
+		return r != '/'
+	case tokClass:
+		in := false
+		for _, rr := range t.ranges {
+			if rr.lo <= r && r <= rr.hi {
+				in = true
+				break
+			}
+		}
+		return in != t.negate
+	}
+	return true // tokDoubleStar
+}
+
+// matchTokens reports whether value matches the compiled pattern. It
+// tracks every token position the value could have reached at once, like
+// a Thompson NFA, so it runs in O(len(toks)·len(value)) time however
+// many stars the pattern has, and allocates only for patterns of more
+// than 255 tokens. It finds a match whenever one exists; path.Match, used
+// for patterns without **, commits to the leftmost match of each piece
+// after a star, which differs only when a class can match "/".
+func matchTokens(toks []globTok, value string) bool {
+	words := (len(toks) + 1 + 63) / 64
+	var bufA, bufB [4]uint64
+	cur, next := bufA[:words:words], bufB[:words:words]
+	if words > len(bufA) {
+		cur, next = make([]uint64, words), make([]uint64, words)
+	}
+	set := func(s []uint64, i int) { s[i/64] |= 1 << (i % 64) }
+	has := func(s []uint64, i int) bool { return s[i/64]&(1<<(i%64)) != 0 }
+	// closeOver lets every star match nothing: its successor is live too.
+	// Stars only point forward, so one ascending pass suffices.
+	closeOver := func(s []uint64) {
+		for i := range toks {
+			if has(s, i) && (toks[i].kind == tokStar || toks[i].kind == tokDoubleStar) {
+				set(s, i+1)
+			}
+		}
+	}
+
+	set(cur, 0)
+	closeOver(cur)
+	for _, r := range value {
+		clear(next)
+		live := false
+		for i := range toks {
+			if !has(cur, i) || !toks[i].matches(r) {
+				continue
+			}
+			live = true
+			if toks[i].kind == tokStar || toks[i].kind == tokDoubleStar {
+				set(next, i) // a star keeps consuming
+			} else {
+				set(next, i+1)
+			}
+		}
+		if !live {
+			return false
+		}
+		closeOver(next)
+		cur, next = next, cur
+	}
+	return has(cur, len(toks))
+}
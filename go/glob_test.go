@@ -0,0 +1,86 @@
+package guard
+
+import (
+	"strings"
+	"testing"
+)
+
+// longDoubleStarCases used to take seconds to minutes each with the
+// recursive matcher, whose cost grew with len(value)^(number of **).
+var longDoubleStarCases = []struct {
+	pattern, value string
+	want           bool
+}{
+	{"**a**a**a**a**b", strings.Repeat("a", 80), false},
+	{"**a**a**a**a**b", strings.Repeat("a", 80) + "b", true},
+	{"**/secrets/**", strings.Repeat("x/", 50000), false},
+	{"**/secrets/**", strings.Repeat("x/", 50000) + "secrets/key", true},
+	{"**rm -rf**", strings.Repeat("echo ok; ", 100000), false},
+	{"**rm -rf**", strings.Repeat("echo ok; ", 100000) + "rm -rf /", true},
+	{"a/**/*.pem", "a/" + strings.Repeat("d/", 50000) + "k.pem", true},
+	{"a/**/*.pem", "a/" + strings.Repeat("d/", 50000) + "k.pem/x", false},
+}
+
+func TestDoubleStarLongValues(t *testing.T) {
+	for _, tc := range longDoubleStarCases {
+		if got := GlobMatch(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("GlobMatch(%q, <%d bytes>) = %v, want %v", tc.pattern, len(tc.value), got, tc.want)
+		}
+		m := compilePattern(tc.pattern)
+		if got := m.match(tc.value); got != tc.want {
+			t.Errorf("compiled %q on <%d bytes> = %v, want %v", tc.pattern, len(tc.value), got, tc.want)
+		}
+	}
+}
+
+func TestDoubleStarTokens(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"a/**", "a/b/c", true},
+		{"a/**", "a", false},
+		{"**/c", "c", false},
+		{"**/c", "/c", true},
+		{"a***b", "a/x/b", true},
+		{"a/**/[bc]?", "a/x/y/cz", true},
+		{"a/**/[bc]?", "a/x/y/dz", false},
+		{"a/**/[^b]", "a/x/c", true},
+		{"a/**/[^b]", "a/x/b", false},
+		{"**.go", "pkg/guard.go", true},
+		{"**/*.go", "guard.go", false},
+		{"x**/*", "x/y/z", true},
+		{"x**/*", "xy", false},
+		// An escaped star is a literal, not half of a **.
+		{`\**`, "*cc", true},
+		{`\**`, "*c/c", false},
+		{`**\*`, "a/b*", true},
+		{"**é?", "a/éx", true},
+	}
+	for _, tc := range cases {
+		if got := GlobMatch(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("GlobMatch(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestCompiledDoubleStarDoesNotAllocate(t *testing.T) {
+	m := compilePattern("mcp:github/**/issues/*")
+	allocs := testing.AllocsPerRun(100, func() {
+		m.match("mcp:github/org/repo/issues/42")
+	})
+	if allocs != 0 {
+		t.Errorf("match allocated %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkDoubleStar(b *testing.B) {
+	for _, tc := range longDoubleStarCases {
+		m := compilePattern(tc.pattern)
+		b.Run(tc.pattern, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m.match(tc.value)
+			}
+		})
+	}
+}
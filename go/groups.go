@@ -0,0 +1,291 @@
+package guard
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// ── Groups ─────────────────────────────────────────────────────────────
+
+// resolveGroups folds each policy's group condition into the policy's own
+// condition. A policy referencing an undefined group is an error.
+func resolveGroups(ps *PolicySet) error {
+	for i := range ps.Policies {
+		p := &ps.Policies[i]
+		if p.Group == "" {
+			continue
+		}
+		g, ok := ps.Groups[p.Group]
+		if !ok {
+			return &PolicyError{Index: i, ID: p.ID, Err: fmt.Errorf("undefined group %q", p.Group)}
+		}
+		cond, err := andConditions(g, p.Condition)
+		if err != nil {
+			return &PolicyError{Index: i, ID: p.ID, Err: fmt.Errorf("group %q: %w", p.Group, err)}
+		}
+		p.Condition = cond
+	}
+	return nil
+}
+
+// andConditions returns a single condition that matches exactly when both
+// a and b match. A field constrained by only one side is copied. A field
+// constrained by both is intersected, which requires at least one side to
+// list literal values (or either side to be "*"); two sets of glob
+// patterns cannot be combined into one list.
+func andConditions(a, b Condition) (Condition, error) {
+	var out Condition
+	lists := []struct {
+		name string
+		a, b []string
+		dst  *[]string
+	}{
+		{"modes", a.Modes, b.Modes, &out.Modes},
+		{"models", a.Models, b.Models, &out.Models},
+		{"channels", a.Channels, b.Channels, &out.Channels},
+		{"tools", a.Tools, b.Tools, &out.Tools},
+		{"mcp_servers", a.McpServers, b.McpServers, &out.McpServers},
+		{"mcp_methods", a.McpMethods, b.McpMethods, &out.McpMethods},
+		{"risk", a.Risk, b.Risk, &out.Risk},
+		{"users", a.Users, b.Users, &out.Users},
+		{"sessions", a.Sessions, b.Sessions, &out.Sessions},
+		{"agents", a.Agents, b.Agents, &out.Agents},
+		{"tenants", a.Tenants, b.Tenants, &out.Tenants},
+	}
+	for _, l := range lists {
+		merged, err := andPatterns(l.name, l.a, l.b)
+		if err != nil {
+			return Condition{}, err
+		}
+		*l.dst = merged
+	}
+
+	args, err := andPatternMaps("args", a.Args, b.Args)
+	if err != nil {
+		return Condition{}, err
+	}
+	out.Args = args
+	tags, err := andPatternMaps("tags", a.Tags, b.Tags)
+	if err != nil {
+		return Condition{}, err
+	}
+	out.Tags = tags
+
+	cidrs, err := andCIDRs(a.SourceCIDRs, b.SourceCIDRs)
+	if err != nil {
+		return Condition{}, err
+	}
+	out.SourceCIDRs = cidrs
+
+	// Both lower bounds must hold, so the larger one wins.
+	out.CostGTE = a.CostGTE
+	if b.CostGTE != nil && (out.CostGTE == nil || *b.CostGTE > *out.CostGTE) {
+		out.CostGTE = b.CostGTE
+	}
+	out.TokensGTE = a.TokensGTE
+	if b.TokensGTE != nil && (out.TokensGTE == nil || *b.TokensGTE > *out.TokensGTE) {
+		out.TokensGTE = b.TokensGTE
+	}
+	out.ReadOnly = a.ReadOnly
+	if b.ReadOnly != nil {
+		if a.ReadOnly != nil && *a.ReadOnly != *b.ReadOnly {
+			return Condition{}, fmt.Errorf("read_only cannot be both %t and %t", *a.ReadOnly, *b.ReadOnly)
+		}
+		out.ReadOnly = b.ReadOnly
+	}
+	out.Authenticated = a.Authenticated
+	if b.Authenticated != nil {
+		if a.Authenticated != nil && *a.Authenticated != *b.Authenticated {
+			return Condition{}, fmt.Errorf("authenticated cannot be both %t and %t", *a.Authenticated, *b.Authenticated)
+		}
+		out.Authenticated = b.Authenticated
+	}
+	out.SessionAgeGTE = a.SessionAgeGTE
+	if b.SessionAgeGTE != nil && (out.SessionAgeGTE == nil || *b.SessionAgeGTE > *out.SessionAgeGTE) {
+		out.SessionAgeGTE = b.SessionAgeGTE
+	}
+	out.RiskScoreGTE = a.RiskScoreGTE
+	if b.RiskScoreGTE != nil && (out.RiskScoreGTE == nil || *b.RiskScoreGTE > *out.RiskScoreGTE) {
+		out.RiskScoreGTE = b.RiskScoreGTE
+	}
+	// Both upper bounds must hold, so the smaller one wins.
+	out.RiskScoreLTE = a.RiskScoreLTE
+	if b.RiskScoreLTE != nil && (out.RiskScoreLTE == nil || *b.RiskScoreLTE < *out.RiskScoreLTE) {
+		out.RiskScoreLTE = b.RiskScoreLTE
+	}
+	if out.RiskScoreGTE != nil && out.RiskScoreLTE != nil && *out.RiskScoreLTE < *out.RiskScoreGTE {
+		return Condition{}, fmt.Errorf("risk scores %d and above and %d and below have no score in common", *out.RiskScoreGTE, *out.RiskScoreLTE)
+	}
+
+	days, err := andDays(a.Days, b.Days)
+	if err != nil {
+		return Condition{}, err
+	}
+	out.Days = days
+
+	// Each side may match a different detected category, so two lists
+	// do not reduce to their intersection.
+	switch {
+	case b.OutputCategories == nil:
+		out.OutputCategories = a.OutputCategories
+	case a.OutputCategories == nil:
+		out.OutputCategories = b.OutputCategories
+	default:
+		return Condition{}, fmt.Errorf("cannot combine output_categories %v and %v", a.OutputCategories, b.OutputCategories)
+	}
+	out.OutputBytesGTE = a.OutputBytesGTE
+	if b.OutputBytesGTE != nil && (out.OutputBytesGTE == nil || *b.OutputBytesGTE > *out.OutputBytesGTE) {
+		out.OutputBytesGTE = b.OutputBytesGTE
+	}
+
+	switch {
+	case a.ModelVersion == "":
+		out.ModelVersion = b.ModelVersion
+	case b.ModelVersion == "":
+		out.ModelVersion = a.ModelVersion
+	default:
+		// Space-separated comparators are already ANDed.
+		out.ModelVersion = a.ModelVersion + " " + b.ModelVersion
+	}
+	return out, nil
+}
+
+// andPatternMaps intersects two keyed pattern fields such as args: keys
+// from both sides are kept and shared keys are intersected.
+func andPatternMaps(field string, a, b map[string][]string) (map[string][]string, error) {
+	if len(a) == 0 && len(b) == 0 {
+		return nil, nil
+	}
+	out := make(map[string][]string, len(a)+len(b))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		merged, err := andPatterns(field+"."+k, out[k], v)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = merged
+	}
+	return out, nil
+}
+
+// andPatterns intersects two pattern lists. nil means unconstrained.
+func andPatterns(field string, a, b []string) ([]string, error) {
+	switch {
+	case a == nil || isMatchAll(a):
+		return b, nil
+	case b == nil || isMatchAll(b):
+		return a, nil
+	}
+	var literals, patterns []string
+	switch {
+	case allLiteral(b):
+		literals, patterns = b, a
+	case allLiteral(a):
+		literals, patterns = a, b
+	default:
+		return nil, fmt.Errorf("cannot combine glob patterns %v and %v for %s", a, b, field)
+	}
+	pl := compilePatterns(patterns)
+	var out []string
+	for _, v := range literals {
+		if pl.matches(v) {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s %v and %v have no value in common", field, a, b)
+	}
+	return out, nil
+}
+
+func isMatchAll(patterns []string) bool {
+	for _, p := range patterns {
+		if p == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func allLiteral(patterns []string) bool {
+	for _, p := range patterns {
+		if !isLiteral(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// andDays intersects two days lists.
+func andDays(a, b []string) ([]string, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+	da, err := parseDays(a)
+	if err != nil {
+		return nil, err
+	}
+	db, err := parseDays(b)
+	if err != nil {
+		return nil, err
+	}
+	both := da & db
+	if both == 0 {
+		return nil, fmt.Errorf("days %v and %v have no day in common", a, b)
+	}
+	return both.names(), nil
+}
+
+// andCIDRs intersects two CIDR lists: an address must fall in a block
+// from each. Overlapping prefixes always nest, so each overlap is the
+// narrower of the two.
+func andCIDRs(a, b []string) ([]string, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+	pa, err := parsePrefixes(a)
+	if err != nil {
+		return nil, err
+	}
+	pb, err := parsePrefixes(b)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, x := range pa {
+		for _, y := range pb {
+			if !x.Overlaps(y) {
+				continue
+			}
+			if x.Bits() >= y.Bits() {
+				out = append(out, x.String())
+			} else {
+				out = append(out, y.String())
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("source_cidrs %v and %v do not overlap", a, b)
+	}
+	return out, nil
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, len(cidrs))
+	for i, c := range cidrs {
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source CIDR %q: %w", c, err)
+		}
+		out[i] = p
+	}
+	return out, nil
+}
@@ -0,0 +1,178 @@
+package guard
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const groupsDoc = `
+metadata: {name: groups}
+groups:
+  prod:
+    args:
+      env: [prod]
+    modes: [background, scheduler]
+  mcp:
+    tools: ["mcp_*"]
+policies:
+  - id: prod-deploy
+    group: prod
+    effect: ask
+    condition:
+      tools: [deploy]
+  - id: prod-scheduler
+    group: prod
+    effect: deny
+    condition:
+      modes: [scheduler]
+  - id: mcp-fs
+    group: mcp
+    effect: allow
+    condition:
+      tools: [mcp_fs, bash]
+`
+
+func TestGroupsResolvedAtLoad(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(groupsDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Condition{
+		{Modes: []string{"background", "scheduler"}, Tools: []string{"deploy"}, Args: map[string][]string{"env": {"prod"}}},
+		{Modes: []string{"scheduler"}, Args: map[string][]string{"env": {"prod"}}},
+		{Tools: []string{"mcp_fs"}},
+	}
+	for i, w := range want {
+		if got := ps.Policies[i].Condition; !reflect.DeepEqual(got, w) {
+			t.Errorf("%s: condition = %+v, want %+v", ps.Policies[i].ID, got, w)
+		}
+	}
+}
+
+func TestGroupsEvaluate(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(groupsDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		ctx  EvalContext
+		want string
+	}{
+		{EvalContext{Mode: "background", Tool: "deploy", Args: map[string]string{"env": "prod"}}, "prod-deploy"},
+		{EvalContext{Mode: "background", Tool: "deploy", Args: map[string]string{"env": "staging"}}, ""},
+		{EvalContext{Mode: "chat", Tool: "deploy", Args: map[string]string{"env": "prod"}}, ""},
+		{EvalContext{Tool: "mcp_fs"}, "mcp-fs"},
+		{EvalContext{Tool: "bash"}, ""},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(tc.ctx); v.PolicyID != tc.want {
+			t.Errorf("%+v: expected %q, got %q", tc.ctx, tc.want, v.PolicyID)
+		}
+	}
+}
+
+func TestGroupsUndefined(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+metadata: {name: groups}
+policies:
+  - id: p1
+    group: missing
+    effect: deny
+`))
+	if err == nil || !strings.Contains(err.Error(), `undefined group "missing"`) {
+		t.Fatalf("expected undefined group error, got %v", err)
+	}
+}
+
+func TestGroupsDisjointConditions(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+metadata: {name: groups}
+groups:
+  mcp: {tools: ["mcp_*"]}
+policies:
+  - id: p1
+    group: mcp
+    effect: deny
+    condition: {tools: [bash]}
+`))
+	if err == nil || !strings.Contains(err.Error(), "no value in common") {
+		t.Fatalf("expected disjoint tools error, got %v", err)
+	}
+}
+
+func TestGroupsFromInclude(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml": `
+metadata: {name: top}
+include: [groups.yaml]
+policies:
+  - id: local
+    group: prod
+    effect: deny
+`,
+		"groups.yaml": `
+metadata: {name: shared}
+groups:
+  prod: {users: [ops]}
+`,
+	})
+	ps, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ps.Policies[0].Condition.Users; !reflect.DeepEqual(got, []string{"ops"}) {
+		t.Errorf("users = %v, want [ops]", got)
+	}
+}
+
+func TestAndPatterns(t *testing.T) {
+	cases := []struct {
+		a, b []string
+		want []string
+		err  bool
+	}{
+		{nil, []string{"x"}, []string{"x"}, false},
+		{[]string{"*"}, []string{"a*"}, []string{"a*"}, false},
+		{[]string{"a*"}, []string{"ab", "ba", "ac"}, []string{"ab", "ac"}, false},
+		{[]string{"ab", "cd"}, []string{"cd", "ef"}, []string{"cd"}, false},
+		{[]string{"a*"}, []string{"*b"}, nil, true},
+		{[]string{"a"}, []string{"b"}, nil, true},
+	}
+	for _, tc := range cases {
+		got, err := andPatterns("tools", tc.a, tc.b)
+		if (err != nil) != tc.err || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("andPatterns(%v, %v) = %v, %v; want %v (err=%v)", tc.a, tc.b, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestAndCIDRs(t *testing.T) {
+	got, err := andCIDRs([]string{"10.0.0.0/8", "192.168.0.0/16"}, []string{"10.1.0.0/16", "172.16.0.0/12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"10.1.0.0/16"}) {
+		t.Errorf("got %v", got)
+	}
+	if _, err := andCIDRs([]string{"10.0.0.0/8"}, []string{"192.168.0.0/16"}); err == nil {
+		t.Error("expected error for disjoint CIDRs")
+	}
+}
+
+func TestBuilderGroups(t *testing.T) {
+	ps, err := NewBuilder("x").
+		Group("prod", Condition{Users: []string{"ops"}}).
+		Policy("p1").Group("prod").Tools("deploy").Effect(EffectAsk).Done().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Condition{Users: []string{"ops"}, Tools: []string{"deploy"}}
+	if got := ps.Policies[0].Condition; !reflect.DeepEqual(got, want) {
+		t.Errorf("condition = %+v, want %+v", got, want)
+	}
+}
@@ -7,10 +7,20 @@
 package guard
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"math"
+	"net/netip"
+	"path"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -30,8 +40,54 @@ const (
 	EffectPITL   Effect = "pitl"
 	EffectAITL   Effect = "aitl"
 	EffectFilter Effect = "filter"
+
+	// EffectRateLimit allows an invocation until the policy's rate limit is
+	// exceeded for the session and tool, then applies RateLimit.Exceeded.
+	EffectRateLimit Effect = "rate-limit"
 )
 
+// EffectMeta describes an effect known to the engine.
+type EffectMeta struct {
+	// Label is a human-readable name for the effect.
+	Label string
+	// Terminal reports whether the effect stops the invocation outright
+	// rather than letting it proceed (possibly after approval or review).
+	Terminal bool
+}
+
+var effectRegistry = struct {
+	sync.RWMutex
+	effects map[Effect]EffectMeta
+}{
+	effects: map[Effect]EffectMeta{
+		EffectAllow:     {Label: "Allow"},
+		EffectDeny:      {Label: "Deny", Terminal: true},
+		EffectAsk:       {Label: "Ask"},
+		EffectHITL:      {Label: "Human in the loop"},
+		EffectPITL:      {Label: "Phone in the loop"},
+		EffectAITL:      {Label: "AI in the loop"},
+		EffectFilter:    {Label: "Content filter"},
+		EffectRateLimit: {Label: "Rate limit"},
+	},
+}
+
+// RegisterEffect makes a custom effect known to the engine so that it is
+// accepted by loaders running in strict mode (see WithStrictEffects).
+// Registering an existing name replaces its metadata.
+func RegisterEffect(name string, meta EffectMeta) {
+	effectRegistry.Lock()
+	defer effectRegistry.Unlock()
+	effectRegistry.effects[Effect(name)] = meta
+}
+
+// LookupEffect returns the metadata for a well-known or registered effect.
+func LookupEffect(e Effect) (EffectMeta, bool) {
+	effectRegistry.RLock()
+	defer effectRegistry.RUnlock()
+	meta, ok := effectRegistry.effects[e]
+	return meta, ok
+}
+
 // Channel represents how the user should be asked for approval.
 type Channel string
 
@@ -44,28 +100,164 @@ const (
 
 // EvalContext is the runtime snapshot for a single tool invocation.
 type EvalContext struct {
-	Mode      string
-	Model     string
-	Channel   string
-	Tool      string
-	McpServer string
-	Risk      string
-	User      string
-	Session   string
+	Mode      string            `json:"mode,omitempty"`
+	Model     string            `json:"model,omitempty"`
+	Channel   string            `json:"channel,omitempty"`
+	Tool      string            `json:"tool,omitempty"`
+	McpServer string            `json:"mcp_server,omitempty"`
+	Risk      string            `json:"risk,omitempty"`
+	User      string            `json:"user,omitempty"`
+	Session   string            `json:"session,omitempty"`
+	Args      map[string]string `json:"args,omitempty"`
+	SourceIP  string            `json:"source_ip,omitempty"`
+
+	// Tags are arbitrary key/value attributes of the session, such as
+	// ticket=INC-123 or team=payments, matched by Condition.Tags.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// EstimatedCostUSD and TokenCount describe the expected spend of the
+	// invocation, matched by Condition.CostGTE and Condition.TokensGTE.
+	EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"`
+	TokenCount       int     `json:"token_count,omitempty"`
+
+	// Agent identifies the agent acting in a multi-agent system, such as
+	// a sub-agent spawned by a supervisor, matched by Condition.Agents.
+	Agent string `json:"agent,omitempty"`
+
+	// McpMethod is the MCP operation invoked on McpServer, such as
+	// tools/call or resources/read, matched by Condition.McpMethods.
+	McpMethod string `json:"mcp_method,omitempty"`
+
+	// RiskScore is a numeric risk assessment, conventionally 0 to 100,
+	// matched by Condition.RiskScoreGTE and Condition.RiskScoreLTE. It is
+	// independent of the categorical Risk.
+	RiskScore int `json:"risk_score,omitempty"`
+
+	// SessionAgeSeconds is how long the session has been running, as
+	// reported by the caller, matched by Condition.SessionAgeGTE.
+	SessionAgeSeconds int `json:"session_age_seconds,omitempty"`
+
+	// Tenant identifies the tenant the invocation belongs to when one
+	// policy set serves several, matched by Condition.Tenants.
+	Tenant string `json:"tenant,omitempty"`
+
+	// ReadOnly says whether the tool only reads, matched by
+	// Condition.ReadOnly. Nil means the caller does not know.
+	ReadOnly *bool `json:"read_only,omitempty"`
+
+	// Authenticated says whether the caller proved its identity, matched
+	// by Condition.Authenticated. Anonymous sessions leave it false.
+	Authenticated bool `json:"authenticated,omitempty"`
+
+	// Tools and Risks describe an invocation that touches several tools,
+	// such as a batch call, or carries several risk tags. When set they
+	// take precedence over Tool and Risk, and Condition.Tools or
+	// Condition.Risk matches if any one of the values matches.
+	Tools []string `json:"tools,omitempty"`
+	Risks []string `json:"risks,omitempty"`
+
+	// Now is the time the invocation is evaluated at, used for policy
+	// expiry. The zero value means the current time.
+	Now time.Time `json:"now,omitempty"`
+
+	// groups are User's groups, filled in by the engine's GroupResolver
+	// when some policy matches users by group.
+	groups []string
+	// result is the tool's output, set only by EvaluateResult.
+	result *ResultContext
+	// simulated marks a what-if evaluation, which must leave hit counts
+	// and rate-limit counters untouched; see evaluateSimulated.
+	simulated bool
+}
+
+// MarshalJSON encodes the context, omitting Now when it is zero.
+func (ec EvalContext) MarshalJSON() ([]byte, error) {
+	type plain EvalContext
+	aux := struct {
+		plain
+		Now *time.Time `json:"now,omitempty"`
+	}{plain: plain(ec)}
+	if !ec.Now.IsZero() {
+		aux.Now = &ec.Now
+	}
+	return json.Marshal(aux)
+}
+
+// toolKey names the invocation's tools in decision-cache and rate-limit
+// keys: Tool, or the Tools joined by commas when set.
+func (ec *EvalContext) toolKey() string {
+	if len(ec.Tools) > 0 {
+		return strings.Join(ec.Tools, ",")
+	}
+	return ec.Tool
 }
 
 // Condition defines matching criteria for a policy.
 // All specified fields must match (AND). Each field list uses OR logic.
-// Nil means "don't care".
+// Nil means "don't care". A context value left empty only matches
+// patterns that match the empty string, such as "*": channels: [phone]
+// does not match a context without a Channel. McpServers, McpMethods,
+// Sessions, Agents and Tenants are stricter and never match a context
+// without an McpServer, McpMethod, Session, Agent or Tenant, even with
+// "*", so sessions: ["*"] means "requires a session".
 type Condition struct {
 	Modes      []string `yaml:"modes,omitempty"      json:"modes,omitempty"`
 	Models     []string `yaml:"models,omitempty"     json:"models,omitempty"`
 	Channels   []string `yaml:"channels,omitempty"   json:"channels,omitempty"`
 	Tools      []string `yaml:"tools,omitempty"      json:"tools,omitempty"`
 	McpServers []string `yaml:"mcp_servers,omitempty" json:"mcp_servers,omitempty"`
+	McpMethods []string `yaml:"mcp_methods,omitempty" json:"mcp_methods,omitempty"`
 	Risk       []string `yaml:"risk,omitempty"       json:"risk,omitempty"`
 	Users      []string `yaml:"users,omitempty"      json:"users,omitempty"`
 	Sessions   []string `yaml:"sessions,omitempty"   json:"sessions,omitempty"`
+	Agents     []string `yaml:"agents,omitempty"     json:"agents,omitempty"`
+	Tenants    []string `yaml:"tenants,omitempty"    json:"tenants,omitempty"`
+	// Args maps an argument name to value patterns. Every listed argument
+	// must be present in the context and match one of its patterns.
+	Args map[string][]string `yaml:"args,omitempty" json:"args,omitempty"`
+	// Tags maps a session tag to value patterns. Like Args, every listed
+	// tag must be present in the context and match one of its patterns.
+	Tags map[string][]string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// SourceCIDRs lists IPv4 or IPv6 CIDR blocks the context's SourceIP
+	// must fall within.
+	SourceCIDRs []string `yaml:"source_cidrs,omitempty" json:"source_cidrs,omitempty"`
+	// ModelVersion constrains the version embedded in the context's Model,
+	// e.g. ">=5.0.0 <6.0.0". Models without a version never match.
+	ModelVersion string `yaml:"model_version,omitempty" json:"model_version,omitempty"`
+	// CostGTE matches invocations whose EstimatedCostUSD is at least this
+	// amount. Unset means don't care.
+	CostGTE *float64 `yaml:"cost_gte,omitempty" json:"cost_gte,omitempty"`
+	// TokensGTE matches invocations whose TokenCount is at least this
+	// many tokens. Unset means don't care.
+	TokensGTE *int `yaml:"tokens_gte,omitempty" json:"tokens_gte,omitempty"`
+	// RiskScoreGTE and RiskScoreLTE bound the context's RiskScore,
+	// inclusively. Either may be unset, meaning don't care.
+	RiskScoreGTE *int `yaml:"risk_score_gte,omitempty" json:"risk_score_gte,omitempty"`
+	RiskScoreLTE *int `yaml:"risk_score_lte,omitempty" json:"risk_score_lte,omitempty"`
+	// SessionAgeGTE matches invocations whose SessionAgeSeconds is at
+	// least this many seconds, e.g. 3600 to ask again after an hour of
+	// unattended operation. Unset means don't care.
+	SessionAgeGTE *int `yaml:"session_age_gte,omitempty" json:"session_age_gte,omitempty"`
+	// ReadOnly, if set, must equal the context's ReadOnly, so read_only:
+	// true covers every read-only tool. A context that does not say never
+	// matches. Unset means don't care.
+	ReadOnly *bool `yaml:"read_only,omitempty" json:"read_only,omitempty"`
+	// Authenticated, if set, must equal the context's Authenticated, so
+	// authenticated: false singles out anonymous callers. Unset means
+	// don't care.
+	Authenticated *bool `yaml:"authenticated,omitempty" json:"authenticated,omitempty"`
+	// Days lists the days of the week the invocation may fall on, e.g.
+	// [sat, sun], judged by the weekday of EvalContext.Now (or the
+	// engine's clock) in that time's location. Names are three-letter
+	// abbreviations or full English names, in any case.
+	Days []string `yaml:"days,omitempty" json:"days,omitempty"`
+
+	// OutputBytesGTE and OutputCategories gate on the tool's result: the
+	// size of its output and the categories detected in it (matching if
+	// any category matches a pattern). A policy using either is a result
+	// policy, evaluated only by EvaluateResult; see ResultContext.
+	OutputBytesGTE   *int     `yaml:"output_bytes_gte,omitempty"  json:"output_bytes_gte,omitempty"`
+	OutputCategories []string `yaml:"output_categories,omitempty" json:"output_categories,omitempty"`
 }
 
 // Policy is a single guardrail policy.
@@ -78,6 +270,96 @@ type Policy struct {
 	Priority    int       `yaml:"priority,omitempty"   json:"priority,omitempty"`
 	Condition   Condition `yaml:"condition,omitempty"  json:"condition,omitempty"`
 	Channel     Channel   `yaml:"channel,omitempty"    json:"channel,omitempty"`
+	// Channels lists approval channels in order of preference, e.g. phone
+	// then chat, surfaced in Verdict.ChannelChain. When set, Channel
+	// defaults to its first element; a lone Channel is a one-element chain.
+	Channels []Channel `yaml:"channels,omitempty" json:"channels,omitempty"`
+	// Weight is the policy's contribution to the total score under
+	// WithWeightedScoring, and is ignored otherwise. It may be negative
+	// for mitigating factors.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+	// AnyOf lists alternative condition blocks. When non-empty the policy
+	// also requires at least one block to match, on top of Condition:
+	// an OR of ANDs within a single policy.
+	AnyOf []Condition `yaml:"any_of,omitempty" json:"any_of,omitempty"`
+	// Invert negates the match: the policy applies to every invocation
+	// its Condition and AnyOf blocks do NOT match, so tools: [view]
+	// matches every tool except view. Because an empty condition matches
+	// everything, an inverted policy with an empty condition never matches.
+	Invert bool `yaml:"invert,omitempty" json:"invert,omitempty"`
+	// DryRun puts the policy in observe-only mode for a safe rollout: it
+	// is matched but never produces the verdict, which comes from the
+	// next enforced match or the defaults instead. When it would have
+	// won, the verdict names it in DryRunPolicyID, so observers and audit
+	// sinks can measure its impact before it is enforced.
+	DryRun bool `yaml:"dry_run,omitempty" json:"dry_run,omitempty"`
+	// RequirePresent names condition fields, e.g. [users, sessions], that
+	// the context must have a value for. Without one the policy does not
+	// match, and if the rest of it does, it fails closed: unless a policy
+	// ahead of it has already decided the verdict, evaluation stops with a
+	// deny whose Source is SourceMissingField instead of falling through
+	// to later policies, context fallbacks or the defaults.
+	RequirePresent []string `yaml:"require_present,omitempty" json:"require_present,omitempty"`
+	// Message is a human-readable reason surfaced in Verdict.Reason.
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+	// Code is a stable machine-readable identifier for the decision, such
+	// as GUARD_PROD_WRITE_BLOCKED, surfaced in Verdict.Code so clients can
+	// branch without parsing Message.
+	Code string `yaml:"code,omitempty" json:"code,omitempty"`
+	// Obligations are key-value instructions (e.g. remediation steps)
+	// surfaced in Verdict.Obligations.
+	Obligations map[string]string `yaml:"obligations,omitempty" json:"obligations,omitempty"`
+	// RequireReason asks the approver to type a justification. It is
+	// surfaced in Verdict.RequireReason and combines with any channel.
+	RequireReason bool `yaml:"require_reason,omitempty" json:"require_reason,omitempty"`
+	// RateLimit configures the rate-limit effect. Required when Effect is
+	// EffectRateLimit and ignored otherwise.
+	RateLimit *RateLimit `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+
+	// ExpiresAt, if set, retires the policy: it no longer matches once the
+	// evaluation time is after ExpiresAt. Written in YAML as an RFC 3339
+	// timestamp.
+	ExpiresAt *time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+
+	// ActiveFrom, if set, delays the policy: it only matches at or after
+	// ActiveFrom. Together with ExpiresAt it bounds the policy's window.
+	ActiveFrom *time.Time `yaml:"active_from,omitempty" json:"active_from,omitempty"`
+
+	// Labels are free-form key/value pairs used to select subsets of
+	// policies; see WithLabelSelector.
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// Annotations are free-form metadata about the policy, such as its
+	// owner or severity, surfaced in Verdict.Annotations so callers can
+	// route approvals without looking the policy up. They do not affect
+	// matching.
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+
+	// Group names an entry in PolicySet.Groups whose condition is ANDed
+	// with this policy's own when the set is loaded.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
+
+	// Extends names another policy in the set, by ID, that this policy
+	// is based on. When the set is loaded, every field and condition
+	// field this policy leaves unset is copied from its parent, which may
+	// itself extend another policy. Booleans can only be turned on and
+	// lists only replaced, not cleared.
+	Extends string `yaml:"extends,omitempty" json:"extends,omitempty"`
+
+	// Deprecated marks a policy scheduled for removal. It is still
+	// enforced, but EvaluateAll flags it and an engine built with
+	// WithDeprecationWarner reports every verdict it produces.
+	// DeprecationMessage says what to migrate to.
+	Deprecated         bool   `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	DeprecationMessage string `yaml:"deprecation_message,omitempty" json:"deprecation_message,omitempty"`
+}
+
+// RateLimit bounds how often a rate-limit policy allows an invocation.
+type RateLimit struct {
+	// Max is the number of invocations allowed per session and tool.
+	Max int `yaml:"max" json:"max"`
+	// Exceeded is the effect applied once Max is exceeded. Default: ask.
+	Exceeded Effect `yaml:"exceeded,omitempty" json:"exceeded,omitempty"`
 }
 
 // IsEnabled returns whether the policy is active.
@@ -88,6 +370,22 @@ func (p *Policy) IsEnabled() bool {
 	return *p.Enabled
 }
 
+// IsExpired reports whether the policy's ExpiresAt is before t.
+func (p *Policy) IsExpired(t time.Time) bool {
+	return p.ExpiresAt != nil && p.ExpiresAt.Before(t)
+}
+
+// IsPending reports whether the policy's ActiveFrom is after t.
+func (p *Policy) IsPending(t time.Time) bool {
+	return p.ActiveFrom != nil && p.ActiveFrom.After(t)
+}
+
+// IsActiveAt reports whether t falls within the policy's activation
+// window, i.e. it is neither pending nor expired.
+func (p *Policy) IsActiveAt(t time.Time) bool {
+	return !p.IsPending(t) && !p.IsExpired(t)
+}
+
 // Metadata holds descriptive information about a PolicySet.
 type Metadata struct {
 	Name        string            `yaml:"name"                 json:"name"`
@@ -100,6 +398,25 @@ type Metadata struct {
 type Defaults struct {
 	Effect  Effect  `yaml:"effect,omitempty"  json:"effect,omitempty"`
 	Channel Channel `yaml:"channel,omitempty" json:"channel,omitempty"`
+	// PerMode overrides Effect for contexts in a given mode, e.g. deny
+	// for background and ask for interactive. It is consulted only once
+	// the context fallback chain is exhausted, trying each mode of the
+	// chain in order, before falling back to Effect.
+	PerMode map[string]Effect `yaml:"per_mode,omitempty" json:"per_mode,omitempty"`
+	// ChannelByEffect overrides Channel for verdicts with a given effect,
+	// e.g. phone for pitl and chat for hitl. It applies to policies that
+	// set no channel of their own and to the default verdict. A rate-limit
+	// policy's verdict takes the channel for the effect it resolves to:
+	// allow within the limit, the exceeded effect beyond it.
+	ChannelByEffect map[Effect]Channel `yaml:"channel_by_effect,omitempty" json:"channel_by_effect,omitempty"`
+}
+
+// channelFor returns the default channel for a verdict with effect.
+func (d *Defaults) channelFor(effect Effect) Channel {
+	if c, ok := d.ChannelByEffect[effect]; ok {
+		return c
+	}
+	return d.Channel
 }
 
 // PolicySet is a complete set of guardrail policies loaded from YAML.
@@ -110,202 +427,1513 @@ type PolicySet struct {
 	Defaults         Defaults          `yaml:"defaults"   json:"defaults"`
 	Policies         []Policy          `yaml:"policies"   json:"policies"`
 	ContextFallbacks map[string]string `yaml:"context_fallbacks,omitempty" json:"context_fallbacks,omitempty"`
+
+	// Includes lists other policy files merged in before this file's own
+	// policies. See LoadPolicySet.
+	Includes []string `yaml:"include,omitempty" json:"include,omitempty"`
+
+	// Groups defines shared conditions that policies reference by name via
+	// Policy.Group. The loader folds each group into its policies'
+	// conditions; the engine itself ignores groups.
+	Groups map[string]Condition `yaml:"groups,omitempty" json:"groups,omitempty"`
+
+	// Aliases maps alternative tool names to a canonical one, e.g. shell
+	// and run to bash, so policies need only list bash. The engine
+	// canonicalizes EvalContext.Tool before matching. Aliases are not
+	// chained: a target must not itself be an alias.
+	Aliases map[string]string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+}
+
+// AssignPriorities numbers policies by their position in the set, so
+// authors can rely on document order: the policy at index i gets priority
+// (i+1)*step, e.g. 10, 20, 30 for a step of 10. Policies with an explicit
+// non-zero priority keep it. A step below 1 defaults to 10.
+func (ps *PolicySet) AssignPriorities(step int) {
+	if step < 1 {
+		step = 10
+	}
+	for i := range ps.Policies {
+		if ps.Policies[i].Priority == 0 {
+			ps.Policies[i].Priority = (i + 1) * step
+		}
+	}
+}
+
+// EffectsUsed returns the distinct effects a Verdict from ps can carry,
+// sorted: the default effects plus every policy's effect, including custom
+// and disabled ones. The rate-limit effect never reaches a verdict, so a
+// rate-limit policy contributes allow and its exceeded effect instead.
+// Dispatchers can use it to check they handle every effect in a file.
+func (ps *PolicySet) EffectsUsed() []Effect {
+	seen := map[Effect]bool{}
+	if ps.Defaults.Effect != "" {
+		seen[ps.Defaults.Effect] = true
+	}
+	for _, effect := range ps.Defaults.PerMode {
+		seen[effect] = true
+	}
+	for i := range ps.Policies {
+		p := &ps.Policies[i]
+		if p.Effect != EffectRateLimit {
+			seen[p.Effect] = true
+			continue
+		}
+		seen[EffectAllow] = true
+		if p.RateLimit != nil && p.RateLimit.Exceeded != "" {
+			seen[p.RateLimit.Exceeded] = true
+		} else {
+			seen[EffectAsk] = true
+		}
+	}
+	out := make([]Effect, 0, len(seen))
+	for effect := range seen {
+		out = append(out, effect)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
 }
 
 // Verdict is the result of evaluating a context against a policy set.
+// Its JSON keys are part of the API and do not change between releases.
 type Verdict struct {
-	Effect   Effect
-	Channel  Channel
-	PolicyID string // empty when no policy matched
+	Effect      Effect            `json:"effect"`
+	Channel     Channel           `json:"channel,omitempty"`
+	PolicyID    string            `json:"policy_id,omitempty"`   // empty when no policy matched
+	Reason      string            `json:"reason,omitempty"`      // the winning policy's message, if any
+	Code        string            `json:"code,omitempty"`        // the winning policy's code, if any
+	Obligations map[string]string `json:"obligations,omitempty"` // copied from the winning policy
+	Source      VerdictSource     `json:"source,omitempty"`      // how the verdict was reached
+	Annotations map[string]string `json:"annotations,omitempty"` // copied from the winning policy
+
+	// RequireReason is set when the winning policy requires the user to
+	// enter a justification, in addition to approving on Channel.
+	RequireReason bool `json:"require_reason,omitempty"`
+
+	// ChannelChain lists the winning policy's channels in order of
+	// preference when it sets Policy.Channels; callers try each in turn.
+	// Its first element equals Channel. It is nil otherwise, in which case
+	// Channel is the only option; see ApprovalChannels.
+	ChannelChain []Channel `json:"channel_chain,omitempty"`
+
+	// DryRunPolicyID and DryRunEffect identify the dry-run policy that
+	// would have produced the verdict had it been enforced, and the effect
+	// it would have had. They are empty when no dry-run policy would have
+	// changed the outcome.
+	DryRunPolicyID string `json:"dry_run_policy_id,omitempty"`
+	DryRunEffect   Effect `json:"dry_run_effect,omitempty"`
+}
+
+// MarshalJSON encodes the verdict with its stable snake_case keys, e.g.
+//
+//	{"effect":"deny","channel":"chat","policy_id":"p1","source":"matched"}
+//
+// Effect, Channel and Source are plain strings; empty optional fields are
+// omitted.
+func (v Verdict) MarshalJSON() ([]byte, error) {
+	type plain Verdict
+	return json.Marshal(plain(v))
+}
+
+// ApprovalChannels returns the channels to try in order: ChannelChain if
+// set, otherwise just Channel.
+func (v Verdict) ApprovalChannels() []Channel {
+	if len(v.ChannelChain) > 0 {
+		return v.ChannelChain
+	}
+	if v.Channel == "" {
+		return nil
+	}
+	return []Channel{v.Channel}
 }
 
+// VerdictSource says how a verdict was reached.
+type VerdictSource string
+
+const (
+	// SourceMatched means a policy matched the context's own mode.
+	SourceMatched VerdictSource = "matched"
+	// SourceFallbackMatched means a policy matched after walking the
+	// context fallback chain.
+	SourceFallbackMatched VerdictSource = "fallback_matched"
+	// SourceDefault means no policy matched and the defaults applied.
+	SourceDefault VerdictSource = "default"
+	// SourceNoMatch means no policy and no per-mode default matched on an
+	// engine built with WithRequireExplicitMatch. The effect is deny.
+	SourceNoMatch VerdictSource = "no_match"
+	// SourceMissingField means a policy's Policy.RequirePresent field was
+	// missing from the context, so it denied. The effect is deny.
+	SourceMissingField VerdictSource = "missing_field"
+	// SourceKillSwitch means the engine's kill switch was set, overriding
+	// every policy; see PolicyEngine.SetKillSwitch.
+	SourceKillSwitch VerdictSource = "kill_switch"
+)
+
 // ── Glob matching ──────────────────────────────────────────────────────
 
 // GlobMatch matches a value against a glob pattern.
-// Supports *, ?, and exact matching.
+// Supports *, ?, **, character classes and exact matching. A single *
+// stays within one "/"-separated segment; ** matches across segments, so
+// "mcp:github/**" matches "mcp:github/org/repo". A class matches one
+// character from a set or range, e.g. "gpt-[45]" or "gpt-[a-c]", and
+// "[^45]" negates it; \ escapes a metacharacter. A malformed pattern such
+// as "gpt-[" is compared literally instead.
+//
+// Matching is the same on every platform: "/" is the only separator and
+// \ always escapes, whatever the OS path conventions. ":" is an ordinary
+// character, so "*" matches across it.
+//
+// A pattern written "prefix:foo" or "suffix:bar" is anchored instead of
+// globbed: it matches any value that starts with "foo" or ends with "bar".
+// The text after the anchor is compared literally, so metacharacters have
+// no special meaning there, and the match may cross "/" separators.
+// Write "prefix\\:foo" to match the literal text "prefix:foo" instead.
 func GlobMatch(pattern, value string) bool {
 	if pattern == "" {
 		return false
 	}
+	if kind, lit, ok := cutAnchor(pattern); ok {
+		if kind == matchHasPrefix {
+			return strings.HasPrefix(value, lit)
+		}
+		return strings.HasSuffix(value, lit)
+	}
 	if pattern == "*" {
 		return true
 	}
-	matched, err := filepath.Match(pattern, value)
-	if err != nil {
+	if !strings.Contains(pattern, "**") {
+		matched, err := path.Match(pattern, value)
+		if err != nil {
+			return pattern == value
+		}
+		return matched
+	}
+	toks, ok := doubleStarTokens(pattern)
+	if !ok {
 		return pattern == value
 	}
-	return matched
+	return matchTokens(toks, value)
+}
+
+// doubleStarTokens compiles a pattern containing **, or reports false if
+// it is malformed. Malformed patterns are rejected up front so the token
+// matcher never has to distinguish "no match" from "bad pattern".
+func doubleStarTokens(pattern string) ([]globTok, bool) {
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return nil, false
+	}
+	return compileDoubleStar(pattern), true
+}
+
+// matchKind classifies a compiled glob pattern.
+type matchKind uint8
+
+const (
+	matchNever     matchKind = iota // empty pattern
+	matchAny                        // "*"
+	matchExact                      // no metacharacters
+	matchPrefix                     // "lit*"
+	matchSuffix                     // "*lit"
+	matchHasPrefix                  // "prefix:lit"
+	matchHasSuffix                  // "suffix:lit"
+	matchGlob                       // anything else; defers to GlobMatch
+	matchFunc                       // custom FieldMatcher registered for the field
+)
+
+// globMeta lists the characters that make a pattern more than a literal.
+const globMeta = `*?[\`
+
+// Anchors that turn a pattern into an exact prefix or suffix match.
+const (
+	prefixAnchor = "prefix:"
+	suffixAnchor = "suffix:"
+)
+
+// cutAnchor splits an anchored pattern into its kind, matchHasPrefix or
+// matchHasSuffix, and the literal that follows the anchor.
+func cutAnchor(pattern string) (matchKind, string, bool) {
+	if lit, ok := strings.CutPrefix(pattern, prefixAnchor); ok {
+		return matchHasPrefix, lit, true
+	}
+	if lit, ok := strings.CutPrefix(pattern, suffixAnchor); ok {
+		return matchHasSuffix, lit, true
+	}
+	return matchNever, "", false
+}
+
+// isLiteral reports whether pattern matches only itself.
+func isLiteral(pattern string) bool {
+	if _, _, ok := cutAnchor(pattern); ok {
+		return false
+	}
+	return !strings.ContainsAny(pattern, globMeta)
+}
+
+// matcher is a glob pattern parsed once at Load time. Common shapes are
+// matched with plain string operations; everything else falls back to
+// GlobMatch, so results are always identical to GlobMatch.
+type matcher struct {
+	kind    matchKind
+	pattern string
+	lit     string
+	fn      FieldMatcher // set for matchFunc
+	toks    []globTok    // set for matchGlob patterns containing **
+}
+
+func compilePattern(pattern string) matcher {
+	m := matcher{kind: matchGlob, pattern: pattern}
+	switch {
+	case pattern == "":
+		m.kind = matchNever
+	case pattern == "*":
+		m.kind = matchAny
+	case strings.HasPrefix(pattern, prefixAnchor) || strings.HasPrefix(pattern, suffixAnchor):
+		m.kind, m.lit, _ = cutAnchor(pattern)
+	case !strings.ContainsAny(pattern, globMeta):
+		m.kind, m.lit = matchExact, pattern
+	case strings.HasSuffix(pattern, "*") && !strings.ContainsAny(pattern[:len(pattern)-1], globMeta):
+		m.kind, m.lit = matchPrefix, pattern[:len(pattern)-1]
+	case strings.HasPrefix(pattern, "*") && !strings.ContainsAny(pattern[1:], globMeta):
+		m.kind, m.lit = matchSuffix, pattern[1:]
+	case strings.Contains(pattern, "**"):
+		m.toks, _ = doubleStarTokens(pattern)
+	}
+	return m
 }
 
-// listMatches returns true if patterns is nil (don't care) or any pattern matches.
-func listMatches(patterns []string, value string) bool {
-	if patterns == nil {
+func (m *matcher) match(value string) bool {
+	switch m.kind {
+	case matchAny:
 		return true
+	case matchExact:
+		return value == m.lit
+	case matchPrefix:
+		// Like GlobMatch, a trailing * does not cross a separator.
+		return strings.HasPrefix(value, m.lit) &&
+			!strings.Contains(value[len(m.lit):], "/")
+	case matchSuffix:
+		return strings.HasSuffix(value, m.lit) &&
+			!strings.Contains(value[:len(value)-len(m.lit)], "/")
+	case matchHasPrefix:
+		return strings.HasPrefix(value, m.lit)
+	case matchHasSuffix:
+		return strings.HasSuffix(value, m.lit)
+	case matchGlob:
+		if m.toks != nil {
+			return matchTokens(m.toks, value)
+		}
+		return GlobMatch(m.pattern, value)
+	case matchFunc:
+		return m.fn(m.pattern, value)
 	}
+	return false
+}
+
+// patternList is a compiled condition field. An unset list (nil in the
+// Condition) is "don't care" and matches any value.
+type patternList struct {
+	set      bool
+	matchers []matcher
+}
+
+func compilePatterns(patterns []string) patternList {
+	pl := patternList{set: patterns != nil}
 	for _, p := range patterns {
-		if GlobMatch(p, value) {
+		pl.matchers = append(pl.matchers, compilePattern(p))
+	}
+	return pl
+}
+
+// matches returns true if the list is unset or any pattern matches.
+func (pl *patternList) matches(value string) bool {
+	if !pl.set {
+		return true
+	}
+	for i := range pl.matchers {
+		if pl.matchers[i].match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether some value matches; an unset list matches
+// anything, even no values.
+func (pl *patternList) matchesAny(values []string) bool {
+	if !pl.set {
+		return true
+	}
+	for _, v := range values {
+		if pl.matches(v) {
 			return true
 		}
 	}
 	return false
 }
 
+// matchesPlural reports whether some value matches, or value does when
+// values is empty, for a context field with a plural form such as Tools.
+func (pl *patternList) matchesPlural(values []string, value string) bool {
+	if len(values) == 0 {
+		return pl.matches(value)
+	}
+	return pl.matchesAny(values)
+}
+
+// compilePatternMap compiles a keyed condition field such as args.
+func compilePatternMap(m map[string][]string) map[string]patternList {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]patternList, len(m))
+	for key, pats := range m {
+		out[key] = compilePatterns(pats)
+	}
+	return out
+}
+
+// patternMapMatches reports whether every key in pm is present in values
+// with a value matching its patterns.
+func patternMapMatches(pm map[string]patternList, values map[string]string) bool {
+	for key, pl := range pm {
+		value, ok := values[key]
+		if !ok || !pl.matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
 // ── Condition matching ─────────────────────────────────────────────────
 
-func conditionMatches(cond Condition, ctx EvalContext) bool {
-	if !listMatches(cond.Modes, ctx.Mode) {
+// compiledCondition is a Condition with its globs, CIDR blocks, and version
+// constraints parsed ahead of time. The engine compiles every policy at
+// Load so that Evaluate never re-parses patterns.
+type compiledCondition struct {
+	modes      patternList
+	models     patternList
+	channels   patternList
+	tools      patternList
+	mcpServers patternList
+	mcpMethods patternList
+	agents     patternList
+	tenants    patternList
+	risk       patternList
+	users      patternList
+	groups     []matcher // "group:" users patterns, prefix stripped
+	sessions   patternList
+	args       map[string]patternList
+	tags       map[string]patternList
+	cidrs      []netip.Prefix
+	hasCIDRs   bool
+	versions   []versionComparator
+	hasVersion bool
+	costGTE    *float64
+	tokensGTE  *int
+	scoreGTE   *int
+	scoreLTE   *int
+	ageGTE     *int
+	readOnly   *bool
+	authed     *bool
+	days       weekdaySet
+	hasDays    bool
+	outputGTE  *int
+	categories patternList
+	anyOf      []compiledCondition // at least one must match, if any
+	invert     bool                // Policy.Invert; only set at the top level
+	result     bool                // a result policy; only set at the top level
+	required   []string            // Policy.RequirePresent; only set at the top level
+	relaxed    *compiledCondition  // the policy without its required fields, if any
+}
+
+// compilePolicy compiles p's condition together with its AnyOf blocks.
+func compilePolicy(p *Policy) compiledCondition {
+	cc := compileCondition(p.Condition)
+	for _, block := range p.AnyOf {
+		cc.anyOf = append(cc.anyOf, compileCondition(block))
+	}
+	cc.invert = p.Invert
+	cc.result = isResultPolicy(p)
+	cc.compileRequired(p)
+	return cc
+}
+
+// compileCondition parses cond. Invalid CIDRs and version constraints are
+// rejected by the loader; here they simply never match.
+func compileCondition(cond Condition) compiledCondition {
+	cc := compiledCondition{
+		modes:      compilePatterns(cond.Modes),
+		models:     compilePatterns(cond.Models),
+		channels:   compilePatterns(cond.Channels),
+		tools:      compilePatterns(cond.Tools),
+		mcpServers: compilePatterns(cond.McpServers),
+		mcpMethods: compilePatterns(cond.McpMethods),
+		agents:     compilePatterns(cond.Agents),
+		tenants:    compilePatterns(cond.Tenants),
+		risk:       compilePatterns(cond.Risk),
+		users:      compileUserPatterns(cond.Users),
+		sessions:   compilePatterns(cond.Sessions),
+		hasCIDRs:   cond.SourceCIDRs != nil,
+		hasDays:    cond.Days != nil,
+		hasVersion: cond.ModelVersion != "",
+		costGTE:    cond.CostGTE,
+		tokensGTE:  cond.TokensGTE,
+		scoreGTE:   cond.RiskScoreGTE,
+		scoreLTE:   cond.RiskScoreLTE,
+		ageGTE:     cond.SessionAgeGTE,
+		readOnly:   cond.ReadOnly,
+		authed:     cond.Authenticated,
+		outputGTE:  cond.OutputBytesGTE,
+		categories: compilePatterns(cond.OutputCategories),
+	}
+	for _, u := range cond.Users {
+		if group, ok := strings.CutPrefix(u, groupPrefix); ok {
+			cc.groups = append(cc.groups, compilePattern(group))
+		}
+	}
+	cc.args = compilePatternMap(cond.Args)
+	cc.tags = compilePatternMap(cond.Tags)
+	for _, c := range cond.SourceCIDRs {
+		if prefix, err := netip.ParsePrefix(c); err == nil {
+			cc.cidrs = append(cc.cidrs, prefix)
+		}
+	}
+	if cc.hasVersion {
+		// On error versions stays nil and the condition never matches.
+		cc.versions, _ = parseVersionConstraint(cond.ModelVersion)
+	}
+	// On error days stays empty and the condition never matches.
+	cc.days, _ = parseDays(cond.Days)
+	return cc
+}
+
+func (cc *compiledCondition) matches(ctx EvalContext) bool {
+	if cc.result != (ctx.result != nil) {
+		return false // result policies only apply to results, and vice versa
+	}
+	if cc.required != nil && cc.missingField(&ctx) != "" {
 		return false
 	}
-	if !listMatches(cond.Models, ctx.Model) {
+	return cc.matchesFields(ctx) != cc.invert
+}
+
+// matchesFields reports whether every field of the condition, and one of
+// its AnyOf blocks if it has any, matches ctx, ignoring invert.
+func (cc *compiledCondition) matchesFields(ctx EvalContext) bool {
+	if !cc.modes.matches(ctx.Mode) {
 		return false
 	}
-	if !listMatches(cond.Channels, ctx.Channel) {
+	if !cc.models.matches(ctx.Model) {
 		return false
 	}
-	if !listMatches(cond.Tools, ctx.Tool) {
+	if !cc.channels.matches(ctx.Channel) {
 		return false
 	}
-	if !listMatches(cond.Risk, ctx.Risk) {
+	if !cc.tools.matchesPlural(ctx.Tools, ctx.Tool) {
 		return false
 	}
-	if !listMatches(cond.Users, ctx.User) {
+	if !cc.risk.matchesPlural(ctx.Risks, ctx.Risk) {
 		return false
 	}
-	if !listMatches(cond.Sessions, ctx.Session) {
+	if !cc.users.matches(ctx.User) && !cc.groupMatches(ctx.groups) {
 		return false
 	}
-
 	// mcp_servers: if patterns specified but no McpServer in context -> no match
-	if cond.McpServers != nil {
+	if cc.mcpServers.set {
 		if ctx.McpServer == "" {
 			return false
 		}
-		if !listMatches(cond.McpServers, ctx.McpServer) {
+		if !cc.mcpServers.matches(ctx.McpServer) {
 			return false
 		}
 	}
 
-	return true
-}
+	// mcp_methods, sessions, agents and tenants: likewise, no McpMethod,
+	// Session, Agent or Tenant in context -> no match
+	if cc.mcpMethods.set && (ctx.McpMethod == "" || !cc.mcpMethods.matches(ctx.McpMethod)) {
+		return false
+	}
+	if cc.sessions.set && (ctx.Session == "" || !cc.sessions.matches(ctx.Session)) {
+		return false
+	}
+	if cc.agents.set && (ctx.Agent == "" || !cc.agents.matches(ctx.Agent)) {
+		return false
+	}
+	if cc.tenants.set && (ctx.Tenant == "" || !cc.tenants.matches(ctx.Tenant)) {
+		return false
+	}
 
-// ── Loader ─────────────────────────────────────────────────────────────
+	// args and tags: every specified key must be present in the context
+	if !patternMapMatches(cc.args, ctx.Args) || !patternMapMatches(cc.tags, ctx.Tags) {
+		return false
+	}
 
-// LoadPolicySetFromBytes parses a PolicySet from YAML bytes.
-func LoadPolicySetFromBytes(data []byte) (*PolicySet, error) {
-	var ps PolicySet
-	if err := yaml.Unmarshal(data, &ps); err != nil {
-		return nil, fmt.Errorf("guard: failed to parse YAML: %w", err)
+	// source_cidrs: if blocks specified but no SourceIP in context -> no match
+	if cc.hasCIDRs {
+		if ctx.SourceIP == "" {
+			return false
+		}
+		if !cidrContains(cc.cidrs, ctx.SourceIP) {
+			return false
+		}
 	}
-	if ps.Kind != "" && ps.Kind != "PolicySet" {
-		return nil, fmt.Errorf("guard: unsupported kind %q (expected PolicySet)", ps.Kind)
+
+	if cc.hasVersion && (cc.versions == nil || !modelVersionAllowed(cc.versions, ctx.Model)) {
+		return false
 	}
-	// Apply defaults
-	if ps.APIVersion == "" {
-		ps.APIVersion = "agent-policy/v1"
+
+	if cc.costGTE != nil && ctx.EstimatedCostUSD < *cc.costGTE {
+		return false
 	}
-	if ps.Kind == "" {
-		ps.Kind = "PolicySet"
+	if cc.tokensGTE != nil && ctx.TokenCount < *cc.tokensGTE {
+		return false
 	}
-	if ps.Defaults.Effect == "" {
-		ps.Defaults.Effect = EffectAsk
+	if cc.scoreGTE != nil && ctx.RiskScore < *cc.scoreGTE {
+		return false
 	}
-	if ps.Defaults.Channel == "" {
-		ps.Defaults.Channel = ChannelChat
+	if cc.scoreLTE != nil && ctx.RiskScore > *cc.scoreLTE {
+		return false
 	}
-	for i := range ps.Policies {
-		if ps.Policies[i].Channel == "" {
-			ps.Policies[i].Channel = ChannelChat
-		}
-		if ps.Policies[i].Priority == 0 {
-			ps.Policies[i].Priority = 100
+	if cc.ageGTE != nil && ctx.SessionAgeSeconds < *cc.ageGTE {
+		return false
+	}
+	if cc.readOnly != nil && (ctx.ReadOnly == nil || *ctx.ReadOnly != *cc.readOnly) {
+		return false
+	}
+	if cc.authed != nil && ctx.Authenticated != *cc.authed {
+		return false
+	}
+	if cc.hasDays && !cc.days.has(ctx.Now.Weekday()) {
+		return false
+	}
+
+	if cc.outputGTE != nil && (ctx.result == nil || ctx.result.OutputBytes < *cc.outputGTE) {
+		return false
+	}
+	if cc.categories.set && (ctx.result == nil || !cc.categories.matchesAny(ctx.result.Categories)) {
+		return false
+	}
+
+	if cc.anyOf != nil {
+		for i := range cc.anyOf {
+			if cc.anyOf[i].matchesFields(ctx) {
+				return true
+			}
 		}
+		return false
 	}
-	return &ps, nil
+
+	return true
 }
 
-// LoadPolicySet loads a PolicySet from a YAML file on disk.
-func LoadPolicySet(path string) (*PolicySet, error) {
-	data, err := os.ReadFile(path)
+// cidrContains returns true if ip parses and falls within any of prefixes.
+func cidrContains(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
 	if err != nil {
-		return nil, fmt.Errorf("guard: failed to read %s: %w", path, err)
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
 	}
-	return LoadPolicySetFromBytes(data)
+	return false
 }
 
-// ── Engine ─────────────────────────────────────────────────────────────
+// ── Loader ─────────────────────────────────────────────────────────────
 
-// PolicyEngine evaluates tool invocations against a PolicySet.
-type PolicyEngine struct {
-	defaults         Defaults
-	policies         []Policy
-	contextFallbacks map[string]string
+// LoadOption configures how a PolicySet is loaded.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	strictEffects  bool
+	strictCodes    bool
+	strictFields   bool
+	expandEnv      bool
+	requireEnabled bool
 }
 
-// NewPolicyEngine creates a new engine, optionally loading a PolicySet.
-func NewPolicyEngine(ps *PolicySet) *PolicyEngine {
-	e := &PolicyEngine{
-		defaults:         Defaults{Effect: EffectAsk, Channel: ChannelChat},
-		contextFallbacks: make(map[string]string),
-	}
-	if ps != nil {
-		e.Load(ps)
+// WithStrictFields rejects unknown or misspelled keys, such as "tool:"
+// for "tools:", instead of silently ignoring them. See LoadPolicySetStrict.
+func WithStrictFields() LoadOption {
+	return func(o *loadOptions) {
+		o.strictFields = true
 	}
-	return e
 }
 
-// Load replaces the active policy set.
-func (e *PolicyEngine) Load(ps *PolicySet) {
-	e.defaults = ps.Defaults
-	e.policies = make([]Policy, len(ps.Policies))
-	copy(e.policies, ps.Policies)
-	sort.Slice(e.policies, func(i, j int) bool {
-		return e.policies[i].Priority < e.policies[j].Priority
-	})
-	e.contextFallbacks = make(map[string]string)
-	for k, v := range ps.ContextFallbacks {
-		e.contextFallbacks[k] = v
+// WithStrictEffects rejects any effect that is neither well-known nor
+// registered with RegisterEffect. By default any string is accepted.
+func WithStrictEffects() LoadOption {
+	return func(o *loadOptions) {
+		o.strictEffects = true
 	}
 }
 
-// Policies returns the currently loaded policies (sorted by priority).
-func (e *PolicyEngine) Policies() []Policy {
-	out := make([]Policy, len(e.policies))
-	copy(out, e.policies)
-	return out
+// WithStrictCodes requires every policy code to be an identifier of
+// letters, digits and underscores starting with a letter, e.g.
+// GUARD_PROD_WRITE_BLOCKED, and to be unique within the set. By default
+// codes are free-form.
+func WithStrictCodes() LoadOption {
+	return func(o *loadOptions) {
+		o.strictCodes = true
+	}
 }
 
-// Evaluate returns a Verdict for the given context.
-// It walks the context fallback chain when no policy matches the
-// original mode.
-func (e *PolicyEngine) Evaluate(ctx EvalContext) Verdict {
-	if v, ok := e.evaluateOnce(ctx); ok {
-		return v
+// WithRequireEnabled rejects a set that has policies but none of them
+// enabled, which would send every invocation to the defaults. It guards
+// generated files against disabling everything by mistake.
+func WithRequireEnabled() LoadOption {
+	return func(o *loadOptions) {
+		o.requireEnabled = true
 	}
+}
 
-	// Walk the context fallback chain
-	mode := ctx.Mode
-	visited := map[string]bool{mode: true}
-	for {
-		next, exists := e.contextFallbacks[mode]
-		if !exists {
-			break
-		}
-		if visited[next] {
-			break
-		}
-		visited[next] = true
-		mode = next
-		fallback := ctx
-		fallback.Mode = mode
-		if v, ok := e.evaluateOnce(fallback); ok {
-			return v
+// LoadPolicySetFromBytes parses a PolicySet from YAML bytes. Relative
+// include paths are resolved against the current working directory.
+func LoadPolicySetFromBytes(data []byte, opts ...LoadOption) (*PolicySet, error) {
+	return LoadPolicySetFrom(bytes.NewReader(data), opts...)
+}
+
+// LoadPolicySetFrom parses a PolicySet from YAML read from r, such as an
+// object storage download, with the same defaults, include resolution and
+// validation as LoadPolicySetFromBytes. Only the first document is read;
+// see LoadPolicySetsFromBytes for streams. The input is decoded as it is
+// read rather than buffered whole, except under WithEnv.
+func LoadPolicySetFrom(r io.Reader, opts ...LoadOption) (*PolicySet, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ps, err := decodePolicySet(r, ".", nil, o)
+	if err != nil {
+		return nil, err
+	}
+	if err := finishPolicySet(ps, o); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// decodePolicySet parses the YAML read from r, applies loader defaults
+// and merges any included files, resolving them relative to dir. stack
+// holds the files currently being loaded, for cycle detection.
+func decodePolicySet(r io.Reader, dir string, stack []string, o loadOptions) (*PolicySet, error) {
+	if o.expandEnv {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("guard: failed to read policies: %w", err)
+		}
+		if data, err = expandEnv(data); err != nil {
+			return nil, err
 		}
+		r = bytes.NewReader(data)
+	}
+	var ps PolicySet
+	if err := decodeYAML(r, &ps, o); err != nil {
+		return nil, err
+	}
+	if err := preparePolicySet(&ps, dir, stack, o); err != nil {
+		return nil, err
 	}
+	return &ps, nil
+}
 
-	return Verdict{
-		Effect:  e.defaults.Effect,
-		Channel: e.defaults.Channel,
+// decodeYAML decodes the first document read from r into v, rejecting
+// unknown keys under WithStrictFields. Empty input leaves v unchanged.
+func decodeYAML(r io.Reader, v any, o loadOptions) error {
+	rr := &errReader{r: r}
+	dec := yaml.NewDecoder(rr)
+	dec.KnownFields(o.strictFields)
+	err := dec.Decode(v)
+	switch {
+	case rr.err != nil:
+		// The decoder flattens read errors into its own message.
+		return fmt.Errorf("guard: failed to read policies: %w", rr.err)
+	case errors.Is(err, io.EOF):
+		return nil
+	case err != nil:
+		return &ParseError{Err: err}
+	}
+	return nil
+}
+
+// errReader remembers the first error other than io.EOF returned by r.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (er *errReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	if err != nil && err != io.EOF && er.err == nil {
+		er.err = err
+	}
+	return n, err
+}
+
+// preparePolicySet checks the kind of a freshly parsed PolicySet, applies
+// loader defaults and merges its includes.
+func preparePolicySet(ps *PolicySet, dir string, stack []string, o loadOptions) error {
+	if ps.Kind != "" && ps.Kind != "PolicySet" {
+		return fmt.Errorf("guard: %w %q (expected PolicySet)", ErrInvalidKind, ps.Kind)
+	}
+	applyLoaderDefaults(ps)
+	return resolveIncludes(ps, dir, stack, o)
+}
+
+// finishPolicySet resolves extends and groups and validates a fully
+// merged PolicySet.
+// Policies without a channel inherit the set's default channel here, once
+// includes are merged, so included policies follow the including file.
+func finishPolicySet(ps *PolicySet, o loadOptions) error {
+	if err := resolveExtends(ps); err != nil {
+		return err
+	}
+	inheritChannels(ps)
+	if err := resolveGroups(ps); err != nil {
+		return err
+	}
+	return validatePolicySet(ps, o)
+}
+
+// applyLoaderDefaults fills in the values the loader assumes for omitted
+// fields.
+func applyLoaderDefaults(ps *PolicySet) {
+	if ps.APIVersion == "" {
+		ps.APIVersion = "agent-policy/v1"
+	}
+	if ps.Kind == "" {
+		ps.Kind = "PolicySet"
+	}
+	if ps.Defaults.Effect == "" {
+		ps.Defaults.Effect = EffectAsk
+	}
+	if ps.Defaults.Channel == "" {
+		ps.Defaults.Channel = ChannelChat
+	}
+	for i := range ps.Policies {
+		// Policies extending another inherit its priority instead.
+		if ps.Policies[i].Priority == 0 && ps.Policies[i].Extends == "" {
+			ps.Policies[i].Priority = 100
+		}
+	}
+}
+
+// inheritChannels gives each policy without a channel the head of its
+// channel chain, or else the default channel for its effect. Rate-limit
+// policies are left without one: their verdict's effect is only known at
+// decision time, when verdictForResult picks the channel for it.
+func inheritChannels(ps *PolicySet) {
+	for i := range ps.Policies {
+		p := &ps.Policies[i]
+		if p.Channel == "" && len(p.Channels) > 0 {
+			p.Channel = p.Channels[0]
+		}
+		if p.Channel == "" && p.Effect != EffectRateLimit {
+			p.Channel = ps.Defaults.channelFor(p.Effect)
+		}
+	}
+}
+
+// validatePolicySet reports policies whose conditions can never be
+// evaluated correctly, such as malformed CIDR blocks, and in strict mode
+// effects that are not registered. It stops at the first problem.
+func validatePolicySet(ps *PolicySet, o loadOptions) error {
+	if err := validateDefaults(ps.Defaults, o); err != nil {
+		return err
+	}
+	if err := validateAliases(ps.Aliases); err != nil {
+		return err
+	}
+	codes := make(map[string]bool)
+	for i := range ps.Policies {
+		p := &ps.Policies[i]
+		if err := validatePolicy(i, p, o); err != nil {
+			return err
+		}
+		if o.strictCodes && p.Code != "" {
+			if codes[p.Code] {
+				return &PolicyError{Index: i, ID: p.ID, Err: fmt.Errorf("%w %q", ErrDuplicateCode, p.Code)}
+			}
+			codes[p.Code] = true
+		}
+	}
+	if o.requireEnabled {
+		return validateEnabled(ps.Policies)
+	}
+	return nil
+}
+
+// validateEnabled reports policies that are all disabled.
+func validateEnabled(policies []Policy) error {
+	if len(policies) == 0 || countEnabled(policies) > 0 {
+		return nil
+	}
+	return fmt.Errorf("guard: %w: all %d policies are disabled", ErrNoEnabledPolicies, len(policies))
+}
+
+func countEnabled(policies []Policy) int {
+	n := 0
+	for i := range policies {
+		if policies[i].IsEnabled() {
+			n++
+		}
 	}
+	return n
+}
+
+func validateDefaults(d Defaults, o loadOptions) error {
+	if o.strictEffects {
+		if _, ok := LookupEffect(d.Effect); !ok {
+			return fmt.Errorf("guard: %w: unknown effect %q", ErrInvalidDefaults, d.Effect)
+		}
+	}
+	modes := make([]string, 0, len(d.PerMode))
+	for mode := range d.PerMode {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		effect := d.PerMode[mode]
+		if effect == "" {
+			return fmt.Errorf("guard: %w: per_mode %q: empty effect", ErrInvalidDefaults, mode)
+		}
+		if _, ok := LookupEffect(effect); o.strictEffects && !ok {
+			return fmt.Errorf("guard: %w: per_mode %q: unknown effect %q", ErrInvalidDefaults, mode, effect)
+		}
+	}
+	effects := make([]string, 0, len(d.ChannelByEffect))
+	for effect := range d.ChannelByEffect {
+		effects = append(effects, string(effect))
+	}
+	sort.Strings(effects)
+	for _, effect := range effects {
+		if d.ChannelByEffect[Effect(effect)] == "" {
+			return fmt.Errorf("guard: %w: channel_by_effect %q: empty channel", ErrInvalidDefaults, effect)
+		}
+		if _, ok := LookupEffect(Effect(effect)); o.strictEffects && !ok {
+			return fmt.Errorf("guard: %w: channel_by_effect: unknown effect %q", ErrInvalidDefaults, effect)
+		}
+	}
+	return nil
+}
+
+func validateAliases(aliases map[string]string) error {
+	for _, alias := range sortedModes(aliases) {
+		target := aliases[alias]
+		switch {
+		case alias == "" || target == "":
+			return fmt.Errorf("guard: %w: %q: empty tool name", ErrInvalidAliases, alias)
+		case alias == target:
+			return fmt.Errorf("guard: %w: %q: aliases itself", ErrInvalidAliases, alias)
+		}
+		if _, ok := aliases[target]; ok {
+			return fmt.Errorf("guard: %w: %q: target %q is itself an alias", ErrInvalidAliases, alias, target)
+		}
+	}
+	return nil
+}
+
+// validatePolicy checks the policy at index i; see validatePolicySet.
+func validatePolicy(i int, p *Policy, o loadOptions) error {
+	if err := checkPolicy(p, o); err != nil {
+		return &PolicyError{Index: i, ID: p.ID, Err: err}
+	}
+	return nil
+}
+
+func checkPolicy(p *Policy, o loadOptions) error {
+	if o.strictEffects {
+		if _, ok := LookupEffect(p.Effect); !ok {
+			return fmt.Errorf("unknown effect %q", p.Effect)
+		}
+	}
+	if len(p.Channels) > 0 && p.Channel != "" && p.Channel != p.Channels[0] {
+		return fmt.Errorf("channel %q is not the first of channels", p.Channel)
+	}
+	if o.strictCodes && p.Code != "" && !isCode(p.Code) {
+		return fmt.Errorf("invalid code %q: want letters, digits and underscores, starting with a letter", p.Code)
+	}
+	if err := validateCondition(&p.Condition); err != nil {
+		return err
+	}
+	for i := range p.AnyOf {
+		if err := validateCondition(&p.AnyOf[i]); err != nil {
+			return fmt.Errorf("any_of[%d]: %w", i, err)
+		}
+	}
+	if p.Effect == EffectRateLimit && p.RateLimit == nil {
+		return fmt.Errorf("effect %q requires rate_limit", p.Effect)
+	}
+	if p.RateLimit != nil && p.RateLimit.Max < 0 {
+		return fmt.Errorf("rate_limit.max must not be negative")
+	}
+	if p.ActiveFrom != nil && p.ExpiresAt != nil && p.ExpiresAt.Before(*p.ActiveFrom) {
+		return fmt.Errorf("expires_at is before active_from")
+	}
+	return validateRequirePresent(p)
+}
+
+// isCode reports whether s is a valid policy code for WithStrictCodes.
+func isCode(s string) bool {
+	for i, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case i > 0 && (r == '_' || r >= '0' && r <= '9'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validateCondition checks the fields of a policy condition or AnyOf block.
+func validateCondition(c *Condition) error {
+	for _, cidr := range c.SourceCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("invalid source CIDR %q: %w", cidr, err)
+		}
+	}
+	if c.ModelVersion != "" {
+		if _, err := parseVersionConstraint(c.ModelVersion); err != nil {
+			return err
+		}
+	}
+	if c.CostGTE != nil && (*c.CostGTE < 0 || math.IsNaN(*c.CostGTE)) {
+		return fmt.Errorf("cost_gte must not be negative")
+	}
+	if c.TokensGTE != nil && *c.TokensGTE < 0 {
+		return fmt.Errorf("tokens_gte must not be negative")
+	}
+	if c.SessionAgeGTE != nil && *c.SessionAgeGTE < 0 {
+		return fmt.Errorf("session_age_gte must not be negative")
+	}
+	if c.RiskScoreGTE != nil && c.RiskScoreLTE != nil && *c.RiskScoreLTE < *c.RiskScoreGTE {
+		return fmt.Errorf("risk_score_lte %d is below risk_score_gte %d", *c.RiskScoreLTE, *c.RiskScoreGTE)
+	}
+	if _, err := parseDays(c.Days); err != nil {
+		return err
+	}
+	if c.OutputBytesGTE != nil && *c.OutputBytesGTE < 0 {
+		return fmt.Errorf("output_bytes_gte must not be negative")
+	}
+	return nil
+}
+
+// LoadPolicySetStrict is LoadPolicySet with WithStrictFields: unknown keys
+// anywhere in the file or its includes are an error.
+func LoadPolicySetStrict(path string, opts ...LoadOption) (*PolicySet, error) {
+	return LoadPolicySet(path, append(opts, WithStrictFields())...)
+}
+
+// LoadPolicySet loads a PolicySet from a YAML file on disk.
+//
+// Files listed under include are loaded relative to the including file and
+// their policies merged ahead of the file's own, with later definitions of
+// a policy ID replacing earlier ones. Circular includes are an error.
+func LoadPolicySet(path string, opts ...LoadOption) (*PolicySet, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ps, err := loadPolicyFile(path, nil, o)
+	if err != nil {
+		return nil, err
+	}
+	if err := finishPolicySet(ps, o); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// ── Engine ─────────────────────────────────────────────────────────────
+
+// Strategy selects which policy wins when several policies match.
+type Strategy int
+
+const (
+	// StrategyPriority picks the matching policy with the lowest priority
+	// number, breaking ties by policy ID. This is the default.
+	StrategyPriority Strategy = iota
+	// StrategySpecificity picks the matching policy that constrains the
+	// most condition fields. Ties are broken by priority.
+	StrategySpecificity
+)
+
+// CombiningAlgorithm decides how the effects of several matching policies
+// combine into one verdict.
+type CombiningAlgorithm int
+
+const (
+	// CombineFirstApplicable returns the policy chosen by the engine's
+	// Strategy. This is the default.
+	CombineFirstApplicable CombiningAlgorithm = iota
+	// CombineDenyOverrides returns a matching deny policy if there is one,
+	// regardless of priority, and otherwise the policy chosen by the
+	// Strategy. Only EffectDeny overrides: allow, ask, rate-limit and
+	// custom effects pass through to normal resolution.
+	CombineDenyOverrides
+	// CombineAllowOverrides is the mirror of CombineDenyOverrides: a
+	// matching allow policy wins regardless of priority, which suits
+	// break-glass rules. Without one, the Strategy picks as usual.
+	CombineAllowOverrides
+)
+
+// Option configures a PolicyEngine.
+type Option func(*PolicyEngine)
+
+// WithStrategy sets the resolution strategy used to pick the winning policy.
+func WithStrategy(s Strategy) Option {
+	return func(e *PolicyEngine) {
+		e.strategy = s
+	}
+}
+
+// WithCombiningAlgorithm sets how the effects of several matching policies
+// combine. When more than one policy has the overriding effect, the
+// Strategy picks among them.
+func WithCombiningAlgorithm(a CombiningAlgorithm) Option {
+	return func(e *PolicyEngine) {
+		e.combining = a
+	}
+}
+
+// WithCounter sets the counter backing the rate-limit effect. Without a
+// counter, rate-limit policies always apply their exceeded effect.
+func WithCounter(c Counter) Option {
+	return func(e *PolicyEngine) {
+		e.counter = c
+	}
+}
+
+// WithLabelSelector restricts the engine to policies whose labels include
+// every key/value pair in sel, e.g. {"team": "payments"}. Policies that do
+// not match are dropped on Load, so Evaluate, EvaluateAll and Policies
+// never see them. An empty selector selects every policy.
+func WithLabelSelector(sel map[string]string) Option {
+	return func(e *PolicyEngine) {
+		e.selector = copyStringMap(sel)
+	}
+}
+
+// WithRequireExplicitMatch disables the set's default effect: when no
+// policy matches and no per-mode default applies, the verdict is deny with
+// Source SourceNoMatch, so coverage gaps fail closed and are easy to spot
+// in audit logs. Strict deployments use it to force explicit catch-all
+// policies. Per-mode defaults still apply, and EvaluateResult is
+// unaffected.
+func WithRequireExplicitMatch() Option {
+	return func(e *PolicyEngine) {
+		e.explicit = true
+	}
+}
+
+// WithDefaultEffect overrides the default effect of every set the engine
+// loads, so one policy file can fall through to deny in production and to
+// ask in development. The loaded PolicySet is not modified; Defaults and
+// PolicyHash report the effective defaults. Per-mode defaults still apply,
+// and WithRequireExplicitMatch takes precedence.
+func WithDefaultEffect(effect Effect) Option {
+	return func(e *PolicyEngine) {
+		e.defaultEffect = effect
+	}
+}
+
+// labelsMatch reports whether labels satisfy selector.
+func labelsMatch(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// PolicyEngine evaluates tool invocations against a PolicySet.
+//
+// A PolicyEngine is safe for concurrent use: Evaluate and friends may run
+// while another goroutine calls Load. Each call observes either the old or
+// the new policy set, never a mixture of both.
+type PolicyEngine struct {
+	state     atomic.Pointer[engineState]
+	strategy  Strategy
+	combining CombiningAlgorithm
+	counter   Counter
+	clock     Clock
+	scorer    RiskScorer
+	resolver  GroupResolver
+	observer  atomic.Pointer[observerBox]
+	audit     atomic.Pointer[auditBox]
+	decisions atomic.Pointer[decisionCacheBox]
+	kill      atomic.Pointer[killSwitch]
+	selector  map[string]string
+	tenant    string // WithTenant
+	hits      hitRegistry
+	bands     []ScoreBand // weighted scoring when non-nil
+	explicit  bool        // WithRequireExplicitMatch
+
+	defaultEffect Effect // WithDefaultEffect; "" keeps the set's
+
+	deprecation DeprecationWarner // WithDeprecationWarner
+
+	// loadMu serializes building snapshots, so that SetFieldMatcher's
+	// recompile cannot overwrite a concurrent Load.
+	loadMu        sync.Mutex
+	fieldMatchers map[string]FieldMatcher // guarded by loadMu
+
+	hooks  atomic.Pointer[hookSet]
+	hookMu sync.Mutex // serializes AddPreHook and AddPostHook
+}
+
+// engineState is an immutable snapshot of a loaded policy set. Load builds
+// a fresh snapshot and swaps it in atomically; it is never mutated after.
+type engineState struct {
+	loaded           bool // false until the first Load
+	defaults         Defaults
+	policies         []Policy
+	conds            []compiledCondition // parallel to policies
+	hits             []*atomic.Uint64    // parallel to policies, shared across loads by ID
+	index            toolIndex
+	contextFallbacks map[string]string
+	fallbackChains   map[string][]string // modes to try after each mode; see modeChains
+	aliases          map[string]string
+	timed            bool              // some policy has an activation window or days, so evaluation needs the time
+	grouped          bool              // some policy matches users by group, so evaluation needs the user's groups
+	dryRun           bool              // some policy is dry-run, so evaluation also tracks what it would have done
+	deprecated       map[string]string // DeprecationMessage by policy ID; nil when no policy is deprecated
+	hash             string
+}
+
+// NewPolicyEngine creates a new engine, optionally loading a PolicySet.
+func NewPolicyEngine(ps *PolicySet) *PolicyEngine {
+	return NewPolicyEngineWithOptions(ps)
+}
+
+// NewPolicyEngineWithOptions creates a new engine configured by opts,
+// optionally loading a PolicySet.
+func NewPolicyEngineWithOptions(ps *PolicySet, opts ...Option) *PolicyEngine {
+	e := &PolicyEngine{clock: realClock{}}
+	for _, opt := range opts {
+		opt(e)
+	}
+	st := &engineState{
+		defaults:         Defaults{Effect: EffectAsk, Channel: ChannelChat},
+		contextFallbacks: make(map[string]string),
+	}
+	if e.defaultEffect != "" {
+		st.defaults.Effect = e.defaultEffect
+	}
+	e.state.Store(st)
+	if ps != nil {
+		e.Load(ps)
+	}
+	return e
+}
+
+// sortPolicies orders policies by precedence: ascending priority, then ID.
+// The sort is stable, so policies sharing both keep their relative order.
+func sortPolicies(policies []Policy) {
+	sort.SliceStable(policies, func(i, j int) bool {
+		a, b := &policies[i], &policies[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.ID < b.ID
+	})
+}
+
+// Load replaces the active policy set. It is safe to call while other
+// goroutines are evaluating.
+//
+// Policies are ordered by ascending priority, with ties broken by policy
+// ID in lexicographic order, so the winner among equal-priority matches
+// does not depend on the order of policies in the file. Only policies
+// sharing an ID as well keep their file order. Policies, EvaluateAll and
+// EvaluateMatched report policies in this order, identically on every call.
+func (e *PolicyEngine) Load(ps *PolicySet) {
+	e.loadMu.Lock()
+	defer e.loadMu.Unlock()
+	e.state.Store(e.buildState(ps))
+}
+
+// buildState compiles ps into a fresh snapshot. The caller holds loadMu.
+func (e *PolicyEngine) buildState(ps *PolicySet) *engineState {
+	st := &engineState{
+		loaded:           true,
+		defaults:         ps.Defaults,
+		policies:         make([]Policy, 0, len(ps.Policies)),
+		contextFallbacks: make(map[string]string, len(ps.ContextFallbacks)),
+	}
+	if e.defaultEffect != "" {
+		st.defaults.Effect = e.defaultEffect
+	}
+	for _, p := range ps.Policies {
+		if labelsMatch(e.selector, p.Labels) && tenantSelected(e.tenant, &p) {
+			st.policies = append(st.policies, p)
+		}
+	}
+	sortPolicies(st.policies)
+	st.conds = make([]compiledCondition, len(st.policies))
+	for i := range st.policies {
+		st.conds[i] = compilePolicy(&st.policies[i])
+		st.conds[i].useFieldMatchers(e.fieldMatchers)
+	}
+	st.hits = e.hits.counters(st.policies)
+	st.index = buildToolIndex(st.conds)
+	st.deprecated = deprecatedPolicies(st.policies)
+	for i := range st.policies {
+		if st.policies[i].ExpiresAt != nil || st.policies[i].ActiveFrom != nil || usesDays(&st.policies[i]) {
+			st.timed = true
+		}
+		if st.conds[i].groups != nil {
+			st.grouped = true
+		}
+		if st.policies[i].DryRun {
+			st.dryRun = true
+		}
+	}
+	for k, v := range ps.ContextFallbacks {
+		st.contextFallbacks[k] = v
+	}
+	if len(st.contextFallbacks) > 0 {
+		st.fallbackChains = modeChains(st.contextFallbacks)
+		for mode, chain := range st.fallbackChains {
+			st.fallbackChains[mode] = chain[1:]
+		}
+	}
+	if len(ps.Aliases) > 0 {
+		st.aliases = make(map[string]string, len(ps.Aliases))
+		for k, v := range ps.Aliases {
+			st.aliases[k] = v
+		}
+	}
+	st.hash = (&PolicySet{
+		Defaults:         st.defaults,
+		Policies:         st.policies,
+		ContextFallbacks: st.contextFallbacks,
+		Aliases:          st.aliases,
+	}).Hash()
+	return st
+}
+
+// PolicyHash returns the canonical hash (see PolicySet.Hash) of the loaded
+// defaults, policies, context fallbacks and aliases, or "" before the
+// first Load. Reloading identical content keeps the hash, so it can key
+// decision caches that must be invalidated only by real changes.
+func (e *PolicyEngine) PolicyHash() string {
+	return e.state.Load().hash
+}
+
+// Policies returns the currently loaded policies, sorted by priority and
+// then by ID.
+func (e *PolicyEngine) Policies() []Policy {
+	st := e.state.Load()
+	out := make([]Policy, len(st.policies))
+	copy(out, st.policies)
+	return out
+}
+
+// PolicyStats counts the loaded policies by state.
+type PolicyStats struct {
+	Total    int `json:"total"`
+	Enabled  int `json:"enabled"`
+	Disabled int `json:"disabled"`
+}
+
+// Stats counts the currently loaded policies. A reload that leaves Total
+// non-zero but Enabled at zero sends every invocation to the defaults;
+// WithRequireEnabled rejects such sets when they are loaded.
+func (e *PolicyEngine) Stats() PolicyStats {
+	st := e.state.Load()
+	enabled := countEnabled(st.policies)
+	return PolicyStats{Total: len(st.policies), Enabled: enabled, Disabled: len(st.policies) - enabled}
+}
+
+// Evaluate returns a Verdict for the given context.
+// It walks the context fallback chain when no policy matches the
+// original mode. Evaluate does not allocate unless the engine has hooks,
+// an observer, an audit sink, a decision cache, a risk scorer or a group
+// resolver, each of which may.
+func (e *PolicyEngine) Evaluate(ctx EvalContext) Verdict {
+	v, _ := e.EvaluateCtx(context.Background(), ctx)
+	return v
+}
+
+// EvaluateCtx is like Evaluate but honours cancellation and deadlines on
+// ctx. If ctx is done before a verdict is reached, it returns a zero
+// Verdict and an error wrapping ctx.Err().
+func (e *PolicyEngine) EvaluateCtx(ctx context.Context, ec EvalContext) (Verdict, error) {
+	if err := ctx.Err(); err != nil {
+		return Verdict{}, fmt.Errorf("guard: evaluation aborted: %w", err)
+	}
+	return e.evaluateObserved(ctx, e.state.Load(), ec, nil)
+}
+
+// EvaluatePolicy is like Evaluate but also returns the winning policy, so
+// callers can read its annotations, message or channels without looking
+// it up by ID. The policy is a deep copy; changing it does not affect the
+// engine. It is nil when no policy decided the verdict. Should several
+// loaded policies share the winner's ID, which Validate reports, the
+// first in precedence order is returned.
+func (e *PolicyEngine) EvaluatePolicy(ctx EvalContext) (*Policy, Verdict) {
+	st := e.state.Load()
+	v, _ := e.evaluateObserved(context.Background(), st, ctx, nil)
+	if v.PolicyID == "" {
+		return nil, v
+	}
+	for i := range st.policies {
+		if st.policies[i].ID == v.PolicyID {
+			p := copyPolicy(&st.policies[i])
+			return &p, v
+		}
+	}
+	return nil, v
+}
+
+// evaluateObserved is evaluate plus the kill switch, the decision cache
+// and observer and audit notification. Aborted evaluations are not
+// reported. trace is passed on to evaluate; under the kill switch it still
+// receives every policy's match result, none flagged as the winner.
+func (e *PolicyEngine) evaluateObserved(ctx context.Context, st *engineState, ec EvalContext, trace *[]MatchResult) (Verdict, error) {
+	if v, ok := e.killSwitched(st, ec); ok {
+		if trace != nil {
+			*trace = e.matchResults(st, e.prepare(st, ec))
+		}
+		return v, nil
+	}
+	ec = e.prepare(st, ec)
+	obs, sink := e.observer.Load(), e.audit.Load()
+	if obs == nil && sink == nil {
+		v, err := e.evaluate(ctx, st, ec, trace)
+		if err != nil {
+			return v, err
+		}
+		v = e.runPostHooks(ec, e.applyDecisionCache(ec, v))
+		e.warnDeprecated(st, v)
+		return v, nil
+	}
+	start := e.clock.Now()
+	v, err := e.evaluate(ctx, st, ec, trace)
+	if err != nil {
+		return v, err
+	}
+	v = e.runPostHooks(ec, e.applyDecisionCache(ec, v))
+	e.warnDeprecated(st, v)
+	e.notify(obs, sink, start, ec, v)
+	return v, nil
+}
+
+// notify reports a completed evaluation that began at start to whichever
+// of obs and sink are installed.
+func (e *PolicyEngine) notify(obs *observerBox, sink *auditBox, start time.Time, ec EvalContext, v Verdict) {
+	if obs != nil {
+		obs.obs.OnVerdict(ec, v, e.clock.Now().Sub(start))
+	}
+	if sink != nil {
+		sink.sink.Record(newAuditEntry(start, ec, v))
+	}
+}
+
+// evaluate resolves a verdict against a single snapshot, walking the
+// context fallback chain when no policy matches the original mode. If
+// trace is non-nil it is set to the match result of every policy for ec's
+// own mode, with the policy that decided the verdict flagged as the
+// winner.
+func (e *PolicyEngine) evaluate(ctx context.Context, st *engineState, ec EvalContext, trace *[]MatchResult) (Verdict, error) {
+	if st.timed && ec.Now.IsZero() {
+		ec.Now = e.clock.Now()
+	}
+	if trace != nil {
+		*trace = st.matchResults(ec)
+	}
+	v, winner := e.evaluateOnce(st, ec)
+	if winner < 0 {
+		fv, fw, err := e.evaluateFallbacks(ctx, st, ec)
+		if err != nil {
+			return fv, err
+		}
+		if v.DryRunPolicyID != "" {
+			fv.DryRunPolicyID, fv.DryRunEffect = v.DryRunPolicyID, v.DryRunEffect
+		}
+		v, winner = fv, fw
+	}
+	if trace != nil && winner >= 0 {
+		(*trace)[winner].Winner = true
+	}
+	return v, nil
+}
+
+// evaluateFallbacks walks the context fallback chain starting after
+// ec.Mode, returning the default verdict if no fallback mode matches,
+// along with the index of the deciding policy or -1. The chains are
+// expanded at load, so the walk does not allocate.
+func (e *PolicyEngine) evaluateFallbacks(ctx context.Context, st *engineState, ec EvalContext) (Verdict, int, error) {
+	effect, perMode := st.defaults.PerMode[ec.Mode]
+	var dry Verdict // the first dry-run policy that would have won
+	for _, mode := range st.fallbackChains[ec.Mode] {
+		if err := ctx.Err(); err != nil {
+			return Verdict{}, -1, fmt.Errorf("guard: evaluation aborted: %w", err)
+		}
+		if !perMode {
+			effect, perMode = st.defaults.PerMode[mode]
+		}
+		fallback := ec
+		fallback.Mode = mode
+		v, winner := e.evaluateOnce(st, fallback)
+		if dry.DryRunPolicyID == "" {
+			dry = v
+		}
+		if winner >= 0 {
+			if v.Source == SourceMatched {
+				v.Source = SourceFallbackMatched
+			}
+			v.DryRunPolicyID, v.DryRunEffect = dry.DryRunPolicyID, dry.DryRunEffect
+			return v, winner, nil
+		}
+	}
+
+	v := Verdict{
+		Effect:         effect,
+		Source:         SourceDefault,
+		DryRunPolicyID: dry.DryRunPolicyID,
+		DryRunEffect:   dry.DryRunEffect,
+	}
+	switch {
+	case ec.result != nil:
+		// Output passes through unless a result policy says otherwise.
+		v.Effect = EffectAllow
+	case perMode:
+	case e.explicit:
+		v.Effect, v.Channel, v.Source = EffectDeny, "", SourceNoMatch
+	default:
+		v.Effect = st.defaults.Effect
+	}
+	if v.Source == SourceDefault {
+		v.Channel = st.defaults.channelFor(v.Effect)
+	}
+	return v, -1, nil
+}
+
+// modeChains expands the context fallback map into the full list of modes
+// tried for each starting mode, stopping at the first repeat just as the
+// engine does.
+func modeChains(fallbacks map[string]string) map[string][]string {
+	chains := make(map[string][]string, len(fallbacks))
+	for start := range fallbacks {
+		chain := []string{start}
+		visited := map[string]bool{start: true}
+		for mode := start; ; {
+			next, ok := fallbacks[mode]
+			if !ok || visited[next] {
+				break
+			}
+			visited[next] = true
+			chain = append(chain, next)
+			mode = next
+		}
+		chains[start] = chain
+	}
+	return chains
 }
 
 // Resolve is a convenience method returning just the effect string.
@@ -313,53 +1941,334 @@ func (e *PolicyEngine) Resolve(ctx EvalContext) string {
 	return string(e.Evaluate(ctx).Effect)
 }
 
-// evaluateOnce tries to match a policy for a single context (no fallback).
-func (e *PolicyEngine) evaluateOnce(ctx EvalContext) (Verdict, bool) {
-	for _, p := range e.policies {
+// evaluateOnce tries to match a policy for a single context (no fallback),
+// returning its verdict and index, or -1 if none decided. When no policy
+// matches, the returned verdict carries only the dry-run fields, if a
+// dry-run policy would have won.
+func (e *PolicyEngine) evaluateOnce(st *engineState, ctx EvalContext) (Verdict, int) {
+	pk, dry := e.newPicker(), e.newDryRunPicker(st)
+	done, dryDone := false, dry == nil
+	failed, missing := -1, ""
+	it := st.index.candidatesFor(&ctx)
+	for i, ok := it.next(); ok; i, ok = it.next() {
+		p := &st.policies[i]
 		if !p.IsEnabled() {
 			continue
 		}
-		if conditionMatches(p.Condition, ctx) {
-			return Verdict{
-				Effect:   p.Effect,
-				Channel:  p.Channel,
-				PolicyID: p.ID,
-			}, true
+		if st.timed && !p.IsActiveAt(ctx.Now) {
+			continue
+		}
+		if !st.conds[i].matches(ctx) {
+			if !done && !p.DryRun {
+				if missing = st.conds[i].failsClosed(ctx); missing != "" {
+					failed = i
+					break
+				}
+			}
+			continue
+		}
+		if !done {
+			done = pk.offer(i, p)
+		}
+		if !dryDone {
+			dryDone = dry.offer(i, p)
 		}
+		if done && dryDone {
+			break
+		}
+	}
+	if failed >= 0 {
+		if !ctx.simulated {
+			st.hits[failed].Add(1)
+		}
+		v := st.missingVerdict(failed, missing)
+		st.noteDryRun(&v, dry, failed)
+		return v, failed
+	}
+	winner, effect := pk.result()
+	var v Verdict
+	if winner >= 0 {
+		if !ctx.simulated {
+			st.hits[winner].Add(1)
+		}
+		v = e.verdictForResult(st, winner, effect, ctx)
+	}
+	st.noteDryRun(&v, dry, winner)
+	return v, winner
+}
+
+// newDryRunPicker returns a picker that also considers dry-run policies,
+// to find what would win were they enforced, or nil if st has none.
+func (e *PolicyEngine) newDryRunPicker(st *engineState) *picker {
+	if !st.dryRun {
+		return nil
+	}
+	pk := e.newPicker()
+	pk.dryRun = true
+	return &pk
+}
+
+// noteDryRun records in v the dry-run policy that dry picked over the
+// enforced winner, if any. dry may be nil.
+func (st *engineState) noteDryRun(v *Verdict, dry *picker, winner int) {
+	if dry == nil {
+		return
 	}
-	return Verdict{}, false
+	if w, effect := dry.result(); w >= 0 && w != winner && st.policies[w].DryRun {
+		if effect == "" {
+			effect = st.policies[w].Effect
+		}
+		v.DryRunPolicyID, v.DryRunEffect = st.policies[w].ID, effect
+	}
+}
+
+// picker chooses the winning policy from matches offered in priority
+// order, applying the engine's strategy and combining algorithm.
+type picker struct {
+	strategy   Strategy
+	override   Effect // effect that beats every other; "" for first-applicable
+	winner     int    // index of the current winner, or -1
+	best       int    // specificity of the current winner
+	overriding bool   // the current winner has the override effect
+
+	bands []ScoreBand // weighted scoring; best is then the winner's weight
+	score int         // total weight of the matches so far
+
+	dryRun bool // dry-run policies compete too, as if enforced
+}
+
+func (e *PolicyEngine) newPicker() picker {
+	pk := picker{strategy: e.strategy, winner: -1, best: -1, bands: e.bands}
+	switch e.combining {
+	case CombineDenyOverrides:
+		pk.override = EffectDeny
+	case CombineAllowOverrides:
+		pk.override = EffectAllow
+	}
+	return pk
+}
+
+// offer considers the matching policy p at index i and reports whether the
+// winner is settled, so the caller can stop scanning.
+func (pk *picker) offer(i int, p *Policy) bool {
+	if p.DryRun && !pk.dryRun {
+		return false
+	}
+	if pk.bands != nil {
+		return pk.offerWeighted(i, p)
+	}
+	isOverride := pk.override != "" && p.Effect == pk.override
+	if pk.overriding && !isOverride {
+		return false
+	}
+	if isOverride && !pk.overriding {
+		// The first overriding match displaces any earlier winner.
+		pk.winner, pk.overriding = i, true
+		if pk.strategy == StrategyPriority {
+			return true
+		}
+		pk.best = conditionSpecificity(p.Condition)
+		return false
+	}
+	if pk.strategy == StrategyPriority {
+		if pk.winner < 0 {
+			pk.winner = i
+		}
+		return pk.override == "" || pk.overriding
+	}
+	// Policies are sorted by priority, so a strictly greater score is
+	// required to displace an earlier match.
+	if score := conditionSpecificity(p.Condition); score > pk.best {
+		pk.winner, pk.best = i, score
+	}
+	return false
+}
+
+// verdictFor builds the verdict for a winning policy, applying its rate
+// limit if it has one.
+func (e *PolicyEngine) verdictFor(winner *Policy, ctx EvalContext) Verdict {
+	effect := winner.Effect
+	if effect == EffectRateLimit {
+		effect = e.rateLimitEffect(winner, ctx)
+	}
+	channel := winner.Channel
+	if channel == "" && len(winner.Channels) > 0 {
+		channel = winner.Channels[0]
+	}
+	return Verdict{
+		Effect:        effect,
+		Channel:       channel,
+		PolicyID:      winner.ID,
+		Reason:        winner.Message,
+		Code:          winner.Code,
+		Obligations:   copyStringMap(winner.Obligations),
+		Annotations:   copyStringMap(winner.Annotations),
+		Source:        SourceMatched,
+		RequireReason: winner.RequireReason,
+		ChannelChain:  copyChannels(winner.Channels),
+	}
+}
+
+// copyChannels returns a copy of chain, or nil if chain is empty.
+func copyChannels(chain []Channel) []Channel {
+	if len(chain) == 0 {
+		return nil
+	}
+	return append([]Channel(nil), chain...)
+}
+
+// copyStringMap returns a shallow copy of m, or nil if m is empty.
+func copyStringMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// conditionSpecificity counts the fields a condition constrains. Each
+// argument and tag key counts as a separate field.
+func conditionSpecificity(cond Condition) int {
+	n := 0
+	for _, list := range [][]string{
+		cond.Modes, cond.Models, cond.Channels, cond.Tools,
+		cond.McpServers, cond.McpMethods, cond.Risk, cond.Users, cond.Sessions,
+		cond.Agents, cond.Tenants, cond.SourceCIDRs, cond.Days, cond.OutputCategories,
+	} {
+		if list != nil {
+			n++
+		}
+	}
+	if cond.ModelVersion != "" {
+		n++
+	}
+	if cond.CostGTE != nil {
+		n++
+	}
+	if cond.TokensGTE != nil {
+		n++
+	}
+	if cond.RiskScoreGTE != nil || cond.RiskScoreLTE != nil {
+		n++
+	}
+	if cond.SessionAgeGTE != nil {
+		n++
+	}
+	if cond.ReadOnly != nil {
+		n++
+	}
+	if cond.Authenticated != nil {
+		n++
+	}
+	if cond.OutputBytesGTE != nil {
+		n++
+	}
+	return n + len(cond.Args) + len(cond.Tags)
 }
 
 // Defaults returns the fallback effect and channel.
 func (e *PolicyEngine) Defaults() Defaults {
-	return e.defaults
+	return e.state.Load().defaults
 }
 
 // ContextFallbacks returns the context fallback chain.
 func (e *PolicyEngine) ContextFallbacks() map[string]string {
-	out := make(map[string]string, len(e.contextFallbacks))
-	for k, v := range e.contextFallbacks {
+	st := e.state.Load()
+	out := make(map[string]string, len(st.contextFallbacks))
+	for k, v := range st.contextFallbacks {
+		out[k] = v
+	}
+	return out
+}
+
+// Aliases returns the tool alias map.
+func (e *PolicyEngine) Aliases() map[string]string {
+	st := e.state.Load()
+	out := make(map[string]string, len(st.aliases))
+	for k, v := range st.aliases {
 		out[k] = v
 	}
 	return out
 }
 
 // MatchResult describes whether a single policy matched.
+// Its JSON keys are stable and always present.
 type MatchResult struct {
-	PolicyID string
-	Name     string
-	Priority int
-	Effect   Effect
-	Matched  bool
-	Enabled  bool
+	PolicyID string `json:"policy_id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	Effect   Effect `json:"effect"`
+	Matched  bool   `json:"matched"`
+	Enabled  bool   `json:"enabled"`
+	Expired  bool   `json:"expired"`           // past its ExpiresAt; an expired policy never matches
+	Pending  bool   `json:"pending"`           // before its ActiveFrom; a pending policy never matches
+	Winner   bool   `json:"winner"`            // produced the verdict; set only by EvaluateDetailed
+	DryRun   bool   `json:"dry_run,omitempty"` // a dry-run policy; a match is observed, never enforced
+
+	Deprecated         bool   `json:"deprecated,omitempty"` // scheduled for removal; still enforced
+	DeprecationMessage string `json:"deprecation_message,omitempty"`
 }
 
-// EvaluateAll returns match results for every policy. Useful for debugging.
+// EvaluateAll returns match results for every policy, in the engine's
+// priority order (see Load). Useful for debugging.
 func (e *PolicyEngine) EvaluateAll(ctx EvalContext) []MatchResult {
-	results := make([]MatchResult, 0, len(e.policies))
-	for _, p := range e.policies {
+	st := e.state.Load()
+	return e.matchResults(st, e.prepare(st, ctx))
+}
+
+// matchResults returns st's match results for the prepared ctx, at the
+// engine's current time unless ctx sets Now.
+func (e *PolicyEngine) matchResults(st *engineState, ctx EvalContext) []MatchResult {
+	if st.timed && ctx.Now.IsZero() {
+		ctx.Now = e.clock.Now()
+	}
+	return st.matchResults(ctx)
+}
+
+// EvaluateMatched returns match results for just the enabled, active
+// policies that match ctx, in priority order. With the default
+// first-applicable combining algorithm the first element not marked
+// DryRun is the policy that wins for ctx's own mode; context fallbacks
+// are not walked. Use EvaluateAll to see every policy.
+func (e *PolicyEngine) EvaluateMatched(ctx EvalContext) []MatchResult {
+	st := e.state.Load()
+	ctx = e.prepare(st, ctx)
+	if st.timed && ctx.Now.IsZero() {
+		ctx.Now = e.clock.Now()
+	}
+	var results []MatchResult
+	for i := range st.policies {
+		p := &st.policies[i]
+		if !p.IsEnabled() || (st.timed && !p.IsActiveAt(ctx.Now)) || !st.conds[i].matches(ctx) {
+			continue
+		}
+		results = append(results, MatchResult{
+			PolicyID: p.ID,
+			Name:     p.Name,
+			Priority: p.Priority,
+			Effect:   p.Effect,
+			Matched:  true,
+			Enabled:  true,
+			DryRun:   p.DryRun,
+
+			Deprecated:         p.Deprecated,
+			DeprecationMessage: p.DeprecationMessage,
+		})
+	}
+	return results
+}
+
+// matchResults reports, in priority order, whether each policy matches ctx
+// in its own mode. ctx.Now must already be resolved.
+func (st *engineState) matchResults(ctx EvalContext) []MatchResult {
+	results := make([]MatchResult, 0, len(st.policies))
+	for i, p := range st.policies {
 		enabled := p.IsEnabled()
-		matched := enabled && conditionMatches(p.Condition, ctx)
+		expired, pending := p.IsExpired(ctx.Now), p.IsPending(ctx.Now)
+		matched := enabled && !expired && !pending && st.conds[i].matches(ctx)
 		results = append(results, MatchResult{
 			PolicyID: p.ID,
 			Name:     p.Name,
@@ -367,7 +2276,34 @@ func (e *PolicyEngine) EvaluateAll(ctx EvalContext) []MatchResult {
 			Effect:   p.Effect,
 			Matched:  matched,
 			Enabled:  enabled,
+			Expired:  expired,
+			Pending:  pending,
+			DryRun:   p.DryRun,
+
+			Deprecated:         p.Deprecated,
+			DeprecationMessage: p.DeprecationMessage,
 		})
 	}
 	return results
 }
+
+// EvaluateDetailed returns the verdict for ctx together with the match
+// result of every policy, in priority order, computed in a single pass
+// over one policy snapshot so the two can never disagree. The result for
+// the winning policy has Winner set; no result does when the defaults
+// applied.
+//
+// Match results describe ctx's own mode. When the verdict comes from a
+// context fallback mode, the winner is flagged but its Matched field
+// reflects the original mode.
+//
+// Apart from returning the trace, EvaluateDetailed is Evaluate: the
+// verdict comes from the same evaluation, so rate limits are counted, the
+// kill switch and decision cache apply, and observers and audit sinks are
+// notified. Under the kill switch no result is flagged as the winner.
+func (e *PolicyEngine) EvaluateDetailed(ec EvalContext) (Verdict, []MatchResult) {
+	var results []MatchResult
+	// Background is never cancelled, so this cannot fail.
+	v, _ := e.evaluateObserved(context.Background(), e.state.Load(), ec, &results)
+	return v, results
+}
@@ -1,9 +1,18 @@
 package guard
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 // ── Helpers ─────────────────────────────────────────────────────────────
@@ -96,6 +105,280 @@ func TestPriorityRegardlessOfInsertionOrder(t *testing.T) {
 	}
 }
 
+func TestAssignPriorities(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "first", Effect: EffectDeny},
+		{ID: "pinned", Effect: EffectAsk, Priority: 5},
+		{ID: "third", Effect: EffectAllow},
+		{ID: "late", Effect: EffectAllow, Priority: 100},
+	}, EffectAsk)
+	ps.AssignPriorities(10)
+
+	want := map[string]int{"first": 10, "pinned": 5, "third": 30, "late": 100}
+	for _, p := range ps.Policies {
+		if p.Priority != want[p.ID] {
+			t.Errorf("%s: expected priority %d, got %d", p.ID, want[p.ID], p.Priority)
+		}
+	}
+
+	ps = makePolicySet([]Policy{{ID: "a"}, {ID: "b"}}, EffectAsk)
+	ps.AssignPriorities(0)
+	if ps.Policies[0].Priority != 10 || ps.Policies[1].Priority != 20 {
+		t.Errorf("default step: got %d, %d", ps.Policies[0].Priority, ps.Policies[1].Priority)
+	}
+}
+
+func TestEqualPriorityTieBrokenByID(t *testing.T) {
+	policies := []Policy{
+		{ID: "delta", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"*"}}},
+		{ID: "bravo", Effect: EffectAsk, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "charlie", Effect: EffectHITL, Priority: 10, Condition: Condition{Tools: []string{"b*"}}},
+		{ID: "alpha", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "echo", Effect: EffectFilter, Priority: 10},
+	}
+	// Every rotation and the reversal of the slice must pick the same
+	// winner and yield the same order.
+	var orders [][]Policy
+	for i := range policies {
+		rotated := append(append([]Policy(nil), policies[i:]...), policies[:i]...)
+		orders = append(orders, rotated)
+	}
+	reversed := make([]Policy, len(policies))
+	for i, p := range policies {
+		reversed[len(policies)-1-i] = p
+	}
+	orders = append(orders, reversed)
+
+	for i, order := range orders {
+		engine := NewPolicyEngine(makePolicySet(order, EffectAsk))
+		if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "alpha" {
+			t.Errorf("order %d: expected alpha, got %s", i, v.PolicyID)
+		}
+		if v := engine.Evaluate(EvalContext{Tool: "view"}); v.PolicyID != "delta" {
+			t.Errorf("order %d: expected delta for view, got %s", i, v.PolicyID)
+		}
+		var ids []string
+		for _, p := range engine.Policies() {
+			ids = append(ids, p.ID)
+		}
+		if got := fmt.Sprint(ids); got != "[alpha bravo charlie delta echo]" {
+			t.Errorf("order %d: policies sorted as %s", i, got)
+		}
+	}
+}
+
+// ── Label selectors ─────────────────────────────────────────────────────
+
+func labelledPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "payments-deny", Effect: EffectDeny, Priority: 1, Labels: map[string]string{"team": "payments", "env": "prod"}, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "search-allow", Effect: EffectAllow, Priority: 2, Labels: map[string]string{"team": "search"}, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "unlabelled", Effect: EffectHITL, Priority: 3, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk)
+}
+
+func TestLabelSelectorMatch(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(labelledPolicySet(), WithLabelSelector(map[string]string{"team": "search"}))
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "search-allow" {
+		t.Errorf("expected search-allow, got %s", v.PolicyID)
+	}
+	if n := len(engine.Policies()); n != 1 {
+		t.Errorf("expected 1 selected policy, got %d", n)
+	}
+}
+
+func TestLabelSelectorRequiresAllPairs(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(labelledPolicySet(), WithLabelSelector(map[string]string{"team": "payments", "env": "staging"}))
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "" || v.Effect != EffectAsk {
+		t.Errorf("expected no policy selected, got %+v", v)
+	}
+
+	engine = NewPolicyEngineWithOptions(labelledPolicySet(), WithLabelSelector(map[string]string{"team": "payments", "env": "prod"}))
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "payments-deny" {
+		t.Errorf("expected payments-deny, got %s", v.PolicyID)
+	}
+}
+
+func TestLabelSelectorNonMatch(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(labelledPolicySet(), WithLabelSelector(map[string]string{"team": "billing"}))
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Source != SourceDefault {
+		t.Errorf("expected default verdict, got %+v", v)
+	}
+	if n := len(engine.EvaluateAll(EvalContext{Tool: "bash"})); n != 0 {
+		t.Errorf("expected no policies in EvaluateAll, got %d", n)
+	}
+}
+
+func TestNoLabelSelectorSelectsAll(t *testing.T) {
+	engine := NewPolicyEngine(labelledPolicySet())
+	if n := len(engine.Policies()); n != 3 {
+		t.Errorf("expected 3 policies, got %d", n)
+	}
+}
+
+// ── Resolution strategy ─────────────────────────────────────────────────
+
+func strategyPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "catch-all", Effect: EffectAsk, Priority: 10, Condition: Condition{Tools: []string{"*"}}},
+		{ID: "bg-bash", Effect: EffectDeny, Priority: 50, Condition: Condition{Modes: []string{"background"}, Tools: []string{"bash"}}},
+		{ID: "bash", Effect: EffectAllow, Priority: 90, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+}
+
+func TestStrategyPriorityIsDefault(t *testing.T) {
+	engine := NewPolicyEngine(strategyPolicySet())
+	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "background"})
+	if v.PolicyID != "catch-all" {
+		t.Errorf("expected catch-all, got %s", v.PolicyID)
+	}
+}
+
+func TestStrategySpecificity(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(strategyPolicySet(), WithStrategy(StrategySpecificity))
+
+	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "background"})
+	if v.PolicyID != "bg-bash" || v.Effect != EffectDeny {
+		t.Errorf("background bash: expected bg-bash/deny, got %s/%s", v.PolicyID, v.Effect)
+	}
+
+	// catch-all and bash both constrain one field: priority breaks the tie
+	v = engine.Evaluate(EvalContext{Tool: "bash", Mode: "interactive"})
+	if v.PolicyID != "catch-all" {
+		t.Errorf("interactive bash: expected catch-all, got %s", v.PolicyID)
+	}
+
+	v = engine.Evaluate(EvalContext{Tool: "grep"})
+	if v.PolicyID != "catch-all" {
+		t.Errorf("grep: expected catch-all, got %s", v.PolicyID)
+	}
+}
+
+func TestStrategySpecificityCountsArgs(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "bash", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "bash-rm", Effect: EffectDeny, Priority: 20, Condition: Condition{
+			Tools: []string{"bash"},
+			Args:  map[string][]string{"command": {"rm *"}},
+		}},
+	}, EffectAsk)
+	engine := NewPolicyEngineWithOptions(ps, WithStrategy(StrategySpecificity))
+	v := engine.Evaluate(EvalContext{Tool: "bash", Args: map[string]string{"command": "rm build"}})
+	if v.PolicyID != "bash-rm" {
+		t.Errorf("expected bash-rm, got %s", v.PolicyID)
+	}
+}
+
+// ── Combining algorithms ────────────────────────────────────────────────
+
+func combiningPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "ask-all", Effect: EffectAsk, Priority: 10, Condition: Condition{Tools: []string{"*"}}},
+		{ID: "allow-bash", Effect: EffectAllow, Priority: 20, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "deny-bg", Effect: EffectDeny, Priority: 90, Condition: Condition{Modes: []string{"background"}}},
+	}, EffectAllow)
+}
+
+func TestCombineFirstApplicableIsDefault(t *testing.T) {
+	engine := NewPolicyEngine(combiningPolicySet())
+	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "background"})
+	if v.PolicyID != "ask-all" {
+		t.Errorf("expected ask-all, got %s", v.PolicyID)
+	}
+}
+
+func TestCombineDenyOverrides(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(combiningPolicySet(), WithCombiningAlgorithm(CombineDenyOverrides))
+
+	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "background"})
+	if v.PolicyID != "deny-bg" || v.Effect != EffectDeny {
+		t.Errorf("background: expected deny-bg/deny, got %s/%s", v.PolicyID, v.Effect)
+	}
+
+	// No deny matches: the highest-priority match wins as usual.
+	v = engine.Evaluate(EvalContext{Tool: "bash", Mode: "interactive"})
+	if v.PolicyID != "ask-all" {
+		t.Errorf("interactive: expected ask-all, got %s", v.PolicyID)
+	}
+}
+
+func TestCombineDenyOverridesPicksHighestPriorityDeny(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "allow", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "deny-a", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "deny-b", Effect: EffectDeny, Priority: 30, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"background"}}},
+	}, EffectAsk)
+
+	engine := NewPolicyEngineWithOptions(ps, WithCombiningAlgorithm(CombineDenyOverrides))
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "background"}); v.PolicyID != "deny-a" {
+		t.Errorf("priority: expected deny-a, got %s", v.PolicyID)
+	}
+
+	engine = NewPolicyEngineWithOptions(ps,
+		WithCombiningAlgorithm(CombineDenyOverrides), WithStrategy(StrategySpecificity))
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "background"}); v.PolicyID != "deny-b" {
+		t.Errorf("specificity: expected deny-b, got %s", v.PolicyID)
+	}
+}
+
+func TestCombineDenyOverridesCustomEffectsPassThrough(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "quarantine", Effect: "quarantine", Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "allow", Effect: EffectAllow, Priority: 20, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk)
+	engine := NewPolicyEngineWithOptions(ps, WithCombiningAlgorithm(CombineDenyOverrides))
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "quarantine" {
+		t.Errorf("expected quarantine, got %s", v.PolicyID)
+	}
+}
+
+func TestCombineDenyOverridesEvaluateDetailed(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(combiningPolicySet(), WithCombiningAlgorithm(CombineDenyOverrides))
+	ctx := EvalContext{Tool: "bash", Mode: "background"}
+	v, results := engine.EvaluateDetailed(ctx)
+	if v.PolicyID != "deny-bg" {
+		t.Errorf("expected deny-bg, got %s", v.PolicyID)
+	}
+	for _, r := range results {
+		if r.Winner != (r.PolicyID == "deny-bg") {
+			t.Errorf("%s: unexpected Winner=%v", r.PolicyID, r.Winner)
+		}
+	}
+}
+
+func TestCombineAllowOverrides(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(combiningPolicySet(), WithCombiningAlgorithm(CombineAllowOverrides))
+
+	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "background"})
+	if v.PolicyID != "allow-bash" || v.Effect != EffectAllow {
+		t.Errorf("bash: expected allow-bash/allow, got %s/%s", v.PolicyID, v.Effect)
+	}
+
+	// No allow matches: the highest-priority match wins as usual.
+	v = engine.Evaluate(EvalContext{Tool: "grep", Mode: "background"})
+	if v.PolicyID != "ask-all" {
+		t.Errorf("grep: expected ask-all, got %s", v.PolicyID)
+	}
+}
+
+func TestCombiningAlgorithmsDisagree(t *testing.T) {
+	ctx := EvalContext{Tool: "bash", Mode: "background"}
+	cases := []struct {
+		algo CombiningAlgorithm
+		want Effect
+	}{
+		{CombineFirstApplicable, EffectAsk},
+		{CombineDenyOverrides, EffectDeny},
+		{CombineAllowOverrides, EffectAllow},
+	}
+	for _, tc := range cases {
+		engine := NewPolicyEngineWithOptions(combiningPolicySet(), WithCombiningAlgorithm(tc.algo))
+		if v := engine.Evaluate(ctx); v.Effect != tc.want {
+			t.Errorf("algorithm %d: expected %s, got %s", tc.algo, tc.want, v.Effect)
+		}
+	}
+}
+
 // ── Condition matching ──────────────────────────────────────────────────
 
 func TestModeMatch(t *testing.T) {
@@ -171,6 +454,103 @@ func TestMcpServersNoServerInContext(t *testing.T) {
 	}
 }
 
+func TestAgentsMatch(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "sub-agents-no-shell", Effect: EffectDeny, Priority: 10, Condition: Condition{
+			Agents: []string{"supervisor/*"}, Tools: []string{"bash"},
+		}},
+		{ID: "researchers", Effect: EffectAsk, Priority: 20, Condition: Condition{Agents: []string{"*researcher*"}}},
+		{ID: "any-agent", Effect: EffectHITL, Priority: 30, Condition: Condition{Agents: []string{"*"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		agent, tool string
+		want        Effect
+	}{
+		{"supervisor/coder", "bash", EffectDeny},
+		{"supervisor/coder", "view", EffectHITL}, // tools must match too
+		{"supervisor", "bash", EffectHITL},
+		{"web-researcher", "bash", EffectAsk},
+		{"", "bash", EffectAllow}, // no agent never matches, even "*"
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Agent: tc.agent, Tool: tc.tool}); v.Effect != tc.want {
+			t.Errorf("%q/%s: expected %s, got %s (%s)", tc.agent, tc.tool, tc.want, v.Effect, v.PolicyID)
+		}
+	}
+}
+
+func TestMcpMethodsMatch(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "github-reads", Effect: EffectAllow, Priority: 10, Condition: Condition{
+			McpServers: []string{"github"}, McpMethods: []string{"resources/*", "*/list"},
+		}},
+		{ID: "github-calls", Effect: EffectAsk, Priority: 20, Condition: Condition{
+			McpServers: []string{"github"}, McpMethods: []string{"tools/call"},
+		}},
+		{ID: "any-method", Effect: EffectHITL, Priority: 30, Condition: Condition{McpMethods: []string{"*"}}},
+	}, EffectDeny)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		server, method string
+		want           Effect
+	}{
+		{"github", "resources/read", EffectAllow},
+		{"github", "tools/list", EffectAllow},
+		{"github", "tools/call", EffectAsk},
+		{"github", "resources/templates/list", EffectHITL}, // * stays within one segment
+		{"jira", "tools/call", EffectHITL},
+		{"github", "", EffectDeny}, // no method never matches, even "*"
+	}
+	for _, tc := range cases {
+		v := engine.Evaluate(EvalContext{McpServer: tc.server, McpMethod: tc.method, Tool: "mcp"})
+		if v.Effect != tc.want {
+			t.Errorf("%s %q: expected %s, got %s (%s)", tc.server, tc.method, tc.want, v.Effect, v.PolicyID)
+		}
+	}
+}
+
+func TestSessionsMatch(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "prod", Effect: EffectDeny, Priority: 10, Condition: Condition{Sessions: []string{"sess-prod-*"}}},
+		{ID: "pinned", Effect: EffectHITL, Priority: 20, Condition: Condition{Sessions: []string{"sess-[0-9]?", "debug"}}},
+		{ID: "any-session", Effect: EffectAsk, Priority: 30, Condition: Condition{Sessions: []string{"*"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		session string
+		want    Effect
+	}{
+		{"sess-prod-42", EffectDeny},
+		{"sess-prod-", EffectDeny},
+		{"sess-prod-eu/1", EffectAsk}, // * stays within one segment
+		{"sess-7a", EffectHITL},
+		{"debug", EffectHITL},
+		{"sess-dev-1", EffectAsk},
+		{"", EffectAllow}, // no session never matches, even "*"
+	}
+	for _, tc := range cases {
+		v := engine.Evaluate(EvalContext{Tool: "bash", Session: tc.session})
+		if v.Effect != tc.want {
+			t.Errorf("session %q: expected %s, got %s (%s)", tc.session, tc.want, v.Effect, v.PolicyID)
+		}
+	}
+
+	// An empty sessions list matches nothing, not even a context without
+	// a session.
+	empty := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "none", Effect: EffectDeny, Condition: Condition{Sessions: []string{}}},
+	}, EffectAllow))
+	for _, session := range []string{"", "sess-1"} {
+		if v := empty.Evaluate(EvalContext{Tool: "bash", Session: session}); v.Effect != EffectAllow {
+			t.Errorf("empty list, session %q: got %s", session, v.Effect)
+		}
+	}
+}
+
 func TestRiskMatch(t *testing.T) {
 	ps := makePolicySet([]Policy{
 		{ID: "high", Effect: EffectDeny, Priority: 10, Condition: Condition{Risk: []string{"high", "critical"}}},
@@ -189,6 +569,242 @@ func TestRiskMatch(t *testing.T) {
 	}
 }
 
+func TestRiskScoreMatch(t *testing.T) {
+	low, high := 50, 80
+	ps := makePolicySet([]Policy{
+		{ID: "critical", Effect: EffectDeny, Priority: 10, Condition: Condition{Risk: []string{"critical"}, RiskScoreGTE: &low}},
+		{ID: "elevated", Effect: EffectAsk, Priority: 20, Condition: Condition{RiskScoreGTE: &low, RiskScoreLTE: &high}},
+		{ID: "scored-high", Effect: EffectDeny, Priority: 30, Condition: Condition{RiskScoreGTE: &high}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		score int
+		risk  string
+		want  string
+	}{
+		{0, "", ""},
+		{49, "", ""},
+		{50, "", "elevated"},
+		{80, "", "elevated"},
+		{81, "", "scored-high"},
+		{49, "critical", ""},
+		{50, "critical", "critical"},
+		{100, "critical", "critical"},
+		{100, "low", "scored-high"},
+	}
+	for _, c := range cases {
+		v := engine.Evaluate(EvalContext{Tool: "bash", RiskScore: c.score, Risk: c.risk})
+		if v.PolicyID != c.want {
+			t.Errorf("score %d risk %q: got %q, want %q", c.score, c.risk, v.PolicyID, c.want)
+		}
+	}
+
+	bad := Policy{ID: "empty", Effect: EffectDeny, Condition: Condition{RiskScoreGTE: &high, RiskScoreLTE: &low}}
+	if err := validatePolicy(0, &bad, loadOptions{}); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("risk_score_lte below risk_score_gte: got %v", err)
+	}
+}
+
+func TestMultiValueContext(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-rm", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"rm"}}},
+		{ID: "ask-pii", Effect: EffectAsk, Priority: 20, Condition: Condition{Risk: []string{"pii", "secrets"}}},
+		{ID: "ask-mcp", Effect: EffectAsk, Priority: 30, Condition: Condition{Tools: []string{"mcp:*"}}},
+	}, EffectAllow)
+	ps.Aliases = map[string]string{"del": "rm"}
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		name string
+		ec   EvalContext
+		want string
+	}{
+		{"one of several tools", EvalContext{Tools: []string{"ls", "rm"}}, "deny-rm"},
+		{"glob on a later tool", EvalContext{Tools: []string{"ls", "mcp:github"}}, "ask-mcp"},
+		{"alias in tools", EvalContext{Tools: []string{"ls", "del"}}, "deny-rm"},
+		{"no tool matches", EvalContext{Tools: []string{"ls", "cat"}}, ""},
+		{"tools take precedence", EvalContext{Tool: "rm", Tools: []string{"ls"}}, ""},
+		{"empty tools fall back", EvalContext{Tool: "rm", Tools: []string{}}, "deny-rm"},
+		{"one of several risks", EvalContext{Tool: "ls", Risks: []string{"low", "secrets"}}, "ask-pii"},
+		{"risks take precedence", EvalContext{Tool: "ls", Risk: "pii", Risks: []string{"low"}}, ""},
+		{"earliest policy wins", EvalContext{Tools: []string{"mcp:x", "rm"}, Risks: []string{"pii"}}, "deny-rm"},
+	}
+	for _, c := range cases {
+		if v := engine.Evaluate(c.ec); v.PolicyID != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, v.PolicyID, c.want)
+		}
+		var matched string
+		for _, r := range engine.EvaluateAll(c.ec) {
+			if r.Matched {
+				matched = r.PolicyID
+				break
+			}
+		}
+		if matched != c.want {
+			t.Errorf("%s: EvaluateAll matched %q first, want %q", c.name, matched, c.want)
+		}
+	}
+
+	tools := []string{"ls", "del"}
+	engine.Evaluate(EvalContext{Tools: tools})
+	if tools[1] != "del" {
+		t.Errorf("alias resolution modified the caller's Tools: %v", tools)
+	}
+}
+
+func TestSessionAgeMatch(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: session-age
+defaults:
+  effect: allow
+policies:
+  - id: reapprove
+    effect: ask
+    condition:
+      session_age_gte: 3600
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	for _, tt := range []struct {
+		age  int
+		want Effect
+	}{
+		{0, EffectAllow},
+		{3599, EffectAllow},
+		{3600, EffectAsk},
+		{86400, EffectAsk},
+	} {
+		if v := engine.Evaluate(EvalContext{Tool: "bash", SessionAgeSeconds: tt.age}); v.Effect != tt.want {
+			t.Errorf("age %d: got %s, want %s", tt.age, v.Effect, tt.want)
+		}
+	}
+
+	negative := -1
+	bad := Policy{ID: "p", Effect: EffectAsk, Condition: Condition{SessionAgeGTE: &negative}}
+	if err := validatePolicy(0, &bad, loadOptions{}); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("negative threshold: got %v", err)
+	}
+}
+
+func TestReadOnlyMatch(t *testing.T) {
+	yes, no := true, false
+	ps := makePolicySet([]Policy{
+		{ID: "allow-read-only", Effect: EffectAllow, Priority: 10, Condition: Condition{ReadOnly: &yes}},
+		{ID: "ask-mutating", Effect: EffectAsk, Priority: 20, Condition: Condition{ReadOnly: &no}},
+	}, EffectDeny)
+	engine := NewPolicyEngine(ps)
+
+	for _, tt := range []struct {
+		name     string
+		readOnly *bool
+		want     string
+	}{
+		{"read-only", &yes, "allow-read-only"},
+		{"mutating", &no, "ask-mutating"},
+		{"unknown", nil, ""},
+	} {
+		if v := engine.Evaluate(EvalContext{Tool: "anything", ReadOnly: tt.readOnly}); v.PolicyID != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, v.PolicyID, tt.want)
+		}
+	}
+
+	// A condition without read_only does not care.
+	unset := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow))
+	for _, readOnly := range []*bool{&yes, &no, nil} {
+		if v := unset.Evaluate(EvalContext{Tool: "bash", ReadOnly: readOnly}); v.PolicyID != "deny-bash" {
+			t.Errorf("read_only unset, context %v: got %+v", readOnly, v)
+		}
+	}
+}
+
+func TestAuthenticatedMatch(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: authenticated
+defaults:
+  effect: allow
+  channel: chat
+policies:
+  - id: deny-anonymous-shell
+    effect: deny
+    priority: 10
+    condition:
+      tools: [bash, deploy]
+      authenticated: false
+  - id: ask-admin-deploy
+    effect: ask
+    priority: 20
+    condition:
+      tools: [deploy]
+      users: ["admin-*"]
+      authenticated: true
+  - id: deny-deploy
+    effect: deny
+    priority: 30
+    condition:
+      tools: [deploy]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+
+	for _, tt := range []struct {
+		name string
+		ec   EvalContext
+		want string
+	}{
+		{"anonymous shell", EvalContext{Tool: "bash"}, "deny-anonymous-shell"},
+		{"authenticated shell", EvalContext{Tool: "bash", Authenticated: true}, ""},
+		{"anonymous admin deploy", EvalContext{Tool: "deploy", User: "admin-1"}, "deny-anonymous-shell"},
+		{"authenticated admin deploy", EvalContext{Tool: "deploy", User: "admin-1", Authenticated: true}, "ask-admin-deploy"},
+		{"authenticated user deploy", EvalContext{Tool: "deploy", User: "dev-1", Authenticated: true}, "deny-deploy"},
+		{"anonymous view", EvalContext{Tool: "view"}, ""},
+	} {
+		if v := engine.Evaluate(tt.ec); v.PolicyID != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, v.PolicyID, tt.want)
+		}
+	}
+
+	// A condition without authenticated does not care.
+	for _, authenticated := range []bool{true, false} {
+		if v := engine.Evaluate(EvalContext{Tool: "deploy", User: "dev-1", Authenticated: authenticated}); v.PolicyID == "" {
+			t.Errorf("authenticated %t: deploy fell through to the defaults", authenticated)
+		}
+	}
+}
+
+func TestChannelMatch(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "phone-only", Effect: EffectDeny, Priority: 10, Condition: Condition{Channels: []string{"phone"}}},
+		{ID: "any-channel", Effect: EffectAsk, Priority: 20, Condition: Condition{Tools: []string{"deploy"}, Channels: []string{"*"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		channel, tool string
+		want          string
+	}{
+		{"phone", "bash", "phone-only"},
+		{"chat", "bash", ""},
+		{"phone-bridge", "bash", ""},
+		{"", "bash", ""}, // an empty channel does not match a listed one
+		{"", "deploy", "any-channel"},
+		{"slack", "deploy", "any-channel"},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Channel: tc.channel, Tool: tc.tool}); v.PolicyID != tc.want {
+			t.Errorf("channel %q, tool %s: expected %q, got %q", tc.channel, tc.tool, tc.want, v.PolicyID)
+		}
+	}
+}
+
 func TestUserMatch(t *testing.T) {
 	ps := makePolicySet([]Policy{
 		{ID: "admin", Effect: EffectAllow, Priority: 10, Condition: Condition{Users: []string{"admin-*"}}},
@@ -238,52 +854,1113 @@ func TestANDLogicAcrossFields(t *testing.T) {
 	}
 }
 
-func TestChannelOverride(t *testing.T) {
+func TestArgsCommandSubstring(t *testing.T) {
 	ps := makePolicySet([]Policy{
 		{
-			ID: "phone", Effect: EffectAsk, Priority: 10,
-			Channel:   ChannelPhone,
-			Condition: Condition{Tools: []string{"make_voice_call"}},
+			ID: "rm-rf", Effect: EffectDeny, Priority: 10,
+			Condition: Condition{
+				Tools: []string{"bash"},
+				Args:  map[string][]string{"command": {"*rm -rf*"}},
+			},
 		},
-	}, EffectAsk)
+	}, EffectAllow)
 	engine := NewPolicyEngine(ps)
-	v := engine.Evaluate(EvalContext{Tool: "make_voice_call"})
-	if v.Effect != EffectAsk {
-		t.Errorf("expected ask, got %s", v.Effect)
+
+	v := engine.Evaluate(EvalContext{Tool: "bash", Args: map[string]string{"command": "sudo rm -rf build"}})
+	if v.Effect != EffectDeny {
+		t.Errorf("rm -rf: expected deny, got %s", v.Effect)
+	}
+
+	v = engine.Evaluate(EvalContext{Tool: "bash", Args: map[string]string{"command": "ls -la"}})
+	if v.Effect != EffectAllow {
+		t.Errorf("ls: expected allow, got %s", v.Effect)
+	}
+
+	// Missing arg key -> no match
+	v = engine.Evaluate(EvalContext{Tool: "bash"})
+	if v.Effect != EffectAllow {
+		t.Errorf("no args: expected allow, got %s", v.Effect)
+	}
+}
+
+func TestArgsPathPrefix(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{
+			ID: "etc", Effect: EffectDeny, Priority: 10,
+			Condition: Condition{Args: map[string][]string{"path": {"/etc/*"}}},
+		},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	v := engine.Evaluate(EvalContext{Tool: "edit", Args: map[string]string{"path": "/etc/passwd"}})
+	if v.Effect != EffectDeny {
+		t.Errorf("/etc/passwd: expected deny, got %s", v.Effect)
+	}
+
+	v = engine.Evaluate(EvalContext{Tool: "edit", Args: map[string]string{"path": "/home/user/notes"}})
+	if v.Effect != EffectAllow {
+		t.Errorf("/home: expected allow, got %s", v.Effect)
+	}
+
+	v = engine.Evaluate(EvalContext{Tool: "edit", Args: map[string]string{"file": "/etc/passwd"}})
+	if v.Effect != EffectAllow {
+		t.Errorf("other key: expected allow, got %s", v.Effect)
+	}
+}
+
+func TestArgsLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: args
+policies:
+  - id: deny-etc
+    effect: deny
+    condition:
+      tools: [edit]
+      args:
+        path: ["/etc/*", "/usr/*"]
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ps.Policies[0].Condition.Args["path"]; len(got) != 2 {
+		t.Fatalf("expected 2 path patterns, got %v", got)
+	}
+	engine := NewPolicyEngine(ps)
+	if got := engine.Resolve(EvalContext{Tool: "edit", Args: map[string]string{"path": "/usr/bin"}}); got != "deny" {
+		t.Errorf("expected deny, got %s", got)
+	}
+}
+
+func TestTagsMatch(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "incident", Effect: EffectAllow, Priority: 10, Condition: Condition{
+			Tools: []string{"deploy"},
+			Tags:  map[string][]string{"ticket": {"INC-*"}, "team": {"payments", "billing"}},
+		}},
+	}, EffectDeny)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		tags map[string]string
+		want Effect
+	}{
+		{map[string]string{"ticket": "INC-123", "team": "payments"}, EffectAllow},
+		{map[string]string{"ticket": "INC-9", "team": "billing", "extra": "x"}, EffectAllow},
+		{map[string]string{"ticket": "CHG-1", "team": "payments"}, EffectDeny},
+		{map[string]string{"ticket": "INC-123"}, EffectDeny}, // missing team
+		{nil, EffectDeny},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Tool: "deploy", Tags: tc.tags}); v.Effect != tc.want {
+			t.Errorf("tags %v: expected %s, got %s", tc.tags, tc.want, v.Effect)
+		}
+	}
+}
+
+func TestTagsLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: tags
+policies:
+  - id: payments-ask
+    effect: ask
+    condition:
+      tags:
+        team: [payments]
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Tags: map[string]string{"team": "payments"}}); v.PolicyID != "payments-ask" {
+		t.Errorf("expected payments-ask, got %q", v.PolicyID)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Tags: map[string]string{"team": "search"}}); v.PolicyID != "" {
+		t.Errorf("expected default, got %q", v.PolicyID)
+	}
+}
+
+func TestCostAndTokenThresholds(t *testing.T) {
+	cost, tokens := 5.0, 100000
+	ps := makePolicySet([]Policy{
+		{ID: "expensive-bg", Effect: EffectAsk, Priority: 10, Condition: Condition{
+			Tools: []string{"bash"}, Modes: []string{"background"}, CostGTE: &cost,
+		}},
+		{ID: "big", Effect: EffectHITL, Priority: 20, Condition: Condition{TokensGTE: &tokens}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		name string
+		ctx  EvalContext
+		want string
+	}{
+		{"at cost threshold", EvalContext{Tool: "bash", Mode: "background", EstimatedCostUSD: 5}, "expensive-bg"},
+		{"above cost threshold", EvalContext{Tool: "bash", Mode: "background", EstimatedCostUSD: 12.5}, "expensive-bg"},
+		{"just below cost threshold", EvalContext{Tool: "bash", Mode: "background", EstimatedCostUSD: 4.99}, ""},
+		{"cost but other tool", EvalContext{Tool: "grep", Mode: "background", EstimatedCostUSD: 50}, ""},
+		{"cost but interactive", EvalContext{Tool: "bash", Mode: "interactive", EstimatedCostUSD: 50}, ""},
+		{"at token threshold", EvalContext{Tool: "grep", TokenCount: 100000}, "big"},
+		{"below token threshold", EvalContext{Tool: "grep", TokenCount: 99999}, ""},
+		{"unset in context", EvalContext{Tool: "bash", Mode: "background"}, ""},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(tc.ctx); v.PolicyID != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, v.PolicyID)
+		}
+	}
+}
+
+func TestCostThresholdLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: budget
+policies:
+  - id: budget
+    effect: ask
+    condition:
+      cost_gte: 0.5
+      tokens_gte: 2000
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{EstimatedCostUSD: 0.5, TokenCount: 2000}); v.PolicyID != "budget" {
+		t.Errorf("expected budget, got %q", v.PolicyID)
+	}
+	if v := engine.Evaluate(EvalContext{EstimatedCostUSD: 0.5, TokenCount: 1999}); v.PolicyID != "" {
+		t.Errorf("expected default, got %q", v.PolicyID)
+	}
+
+	bad := strings.Replace(yamlDoc, "cost_gte: 0.5", "cost_gte: -1", 1)
+	if _, err := LoadPolicySetFromBytes([]byte(bad)); err == nil || !strings.Contains(err.Error(), "cost_gte") {
+		t.Errorf("expected cost_gte error, got %v", err)
+	}
+}
+
+func TestSourceCIDRMatch(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{
+			ID: "corp", Effect: EffectAllow, Priority: 10,
+			Condition: Condition{SourceCIDRs: []string{"10.0.0.0/8", "2001:db8::/32"}},
+		},
+	}, EffectDeny)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		ip   string
+		want Effect
+	}{
+		{"10.1.2.3", EffectAllow},
+		{"::ffff:10.1.2.3", EffectAllow},
+		{"2001:db8::1", EffectAllow},
+		{"192.168.1.1", EffectDeny},
+		{"2001:db9::1", EffectDeny},
+		{"not-an-ip", EffectDeny},
+		{"", EffectDeny}, // no IP in context -> no match
+	}
+	for _, tc := range cases {
+		v := engine.Evaluate(EvalContext{Tool: "deploy", SourceIP: tc.ip})
+		if v.Effect != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.ip, tc.want, v.Effect)
+		}
+	}
+}
+
+func TestSourceCIDRLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: cidr
+defaults:
+  effect: allow
+policies:
+  - id: deny-external-deploy
+    effect: deny
+    condition:
+      tools: [deploy]
+      source_cidrs: ["0.0.0.0/0"]
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if got := engine.Resolve(EvalContext{Tool: "deploy", SourceIP: "203.0.113.9"}); got != "deny" {
+		t.Errorf("expected deny, got %s", got)
+	}
+}
+
+func TestInvalidSourceCIDR(t *testing.T) {
+	bad := `
+metadata:
+  name: bad-cidr
+policies:
+  - id: p1
+    effect: deny
+    condition:
+      source_cidrs: ["10.0.0.0/33"]
+`
+	if _, err := LoadPolicySetFromBytes([]byte(bad)); err == nil {
+		t.Fatal("expected error for invalid CIDR")
+	}
+}
+
+func TestChannelOverride(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{
+			ID: "phone", Effect: EffectAsk, Priority: 10,
+			Channel:   ChannelPhone,
+			Condition: Condition{Tools: []string{"make_voice_call"}},
+		},
+	}, EffectAsk)
+	engine := NewPolicyEngine(ps)
+	v := engine.Evaluate(EvalContext{Tool: "make_voice_call"})
+	if v.Effect != EffectAsk {
+		t.Errorf("expected ask, got %s", v.Effect)
+	}
+	if v.Channel != ChannelPhone {
+		t.Errorf("expected phone, got %s", v.Channel)
+	}
+}
+
+func TestAnyOfMatchesAnyBlock(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-prod-writes", Effect: EffectDeny, Priority: 10,
+			Condition: Condition{Modes: []string{"prod"}},
+			AnyOf: []Condition{
+				{Tools: []string{"write_*"}},
+				{Tools: []string{"bash"}, Args: map[string][]string{"cmd": {"rm *"}}},
+			}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		name string
+		ctx  EvalContext
+		want Effect
+	}{
+		{"first block", EvalContext{Mode: "prod", Tool: "write_file"}, EffectDeny},
+		{"second block", EvalContext{Mode: "prod", Tool: "bash", Args: map[string]string{"cmd": "rm -rf"}}, EffectDeny},
+		{"second block partial", EvalContext{Mode: "prod", Tool: "bash", Args: map[string]string{"cmd": "ls"}}, EffectAllow},
+		{"no block", EvalContext{Mode: "prod", Tool: "read_file"}, EffectAllow},
+		{"condition fails", EvalContext{Mode: "dev", Tool: "write_file"}, EffectAllow},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(tc.ctx); v.Effect != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, v.Effect)
+		}
+	}
+}
+
+func TestAnyOfLoadedFromYAML(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: any-of
+policies:
+  - id: risky
+    effect: deny
+    any_of:
+      - tools: [rm]
+      - risk: [critical]
+        models: ["gpt-*"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Condition{{Tools: []string{"rm"}}, {Risk: []string{"critical"}, Models: []string{"gpt-*"}}}
+	if !reflect.DeepEqual(ps.Policies[0].AnyOf, want) {
+		t.Errorf("any_of = %+v", ps.Policies[0].AnyOf)
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Risk: "critical", Model: "gpt-5"}); v.PolicyID != "risky" {
+		t.Errorf("expected risky to match the second block, got %q", v.PolicyID)
+	}
+	if v := engine.Evaluate(EvalContext{Risk: "critical", Model: "claude"}); v.PolicyID != "" {
+		t.Errorf("expected no match, got %q", v.PolicyID)
+	}
+}
+
+func TestAnyOfBlocksAreValidated(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: bad-any-of
+policies:
+  - id: p1
+    effect: deny
+    any_of:
+      - tools: [bash]
+      - source_cidrs: ["10.0.0.0/33"]
+`))
+	if err == nil || !strings.Contains(err.Error(), `policy "p1": any_of[1]: invalid source CIDR`) {
+		t.Errorf("expected any_of CIDR error, got %v", err)
+	}
+}
+
+// ── Messages & obligations ──────────────────────────────────────────────
+
+func TestInvertMatchesComplement(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "allow-view", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"view"}}},
+		{ID: "ask-not-view", Effect: EffectAsk, Priority: 20, Invert: true, Condition: Condition{Tools: []string{"view"}}},
+		{ID: "never", Effect: EffectDeny, Priority: 5, Invert: true},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		tool string
+		want string
+	}{
+		{"view", "allow-view"},
+		{"bash", "ask-not-view"},
+		{"", "ask-not-view"}, // an empty tool is not view either
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Tool: tc.tool}); v.PolicyID != tc.want {
+			t.Errorf("%q: expected %s, got %q", tc.tool, tc.want, v.PolicyID)
+		}
+	}
+	for _, r := range engine.EvaluateAll(EvalContext{Tool: "bash"}) {
+		if r.PolicyID == "never" && r.Matched {
+			t.Error("an inverted empty condition should never match")
+		}
+	}
+}
+
+func TestInvertWithAnyOf(t *testing.T) {
+	// Inverts the whole match: everything except writes in prod.
+	engine := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "deny-outside-prod-writes", Effect: EffectDeny, Invert: true,
+			Condition: Condition{Modes: []string{"prod"}}, AnyOf: []Condition{{Tools: []string{"write_*"}}}},
+	}, EffectAllow))
+	cases := []struct {
+		ctx  EvalContext
+		want Effect
+	}{
+		{EvalContext{Mode: "prod", Tool: "write_file"}, EffectAllow},
+		{EvalContext{Mode: "prod", Tool: "read_file"}, EffectDeny},
+		{EvalContext{Mode: "dev", Tool: "write_file"}, EffectDeny},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(tc.ctx); v.Effect != tc.want {
+			t.Errorf("%s/%s: expected %s, got %s", tc.ctx.Mode, tc.ctx.Tool, tc.want, v.Effect)
+		}
+	}
+}
+
+func TestInvertLoadedFromYAML(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: invert
+policies:
+  - id: only-github
+    effect: deny
+    invert: true
+    condition:
+      mcp_servers: [github]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ps.Policies[0].Invert {
+		t.Fatal("invert not loaded")
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Tool: "x", McpServer: "slack"}); v.Effect != EffectDeny {
+		t.Errorf("slack: expected deny, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "x", McpServer: "github"}); v.Effect != EffectAsk {
+		t.Errorf("github: expected ask, got %s", v.Effect)
+	}
+}
+
+func TestMessageAndObligationsInVerdict(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{
+			ID: "prod-write", Effect: EffectDeny, Priority: 10,
+			Message:     "production writes require a change ticket",
+			Obligations: map[string]string{"remediation": "open a change ticket"},
+			Condition:   Condition{Tools: []string{"deploy"}},
+		},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+	v := engine.Evaluate(EvalContext{Tool: "deploy"})
+	if v.Reason != "production writes require a change ticket" {
+		t.Errorf("unexpected reason %q", v.Reason)
+	}
+	if v.Obligations["remediation"] != "open a change ticket" {
+		t.Errorf("unexpected obligations %v", v.Obligations)
+	}
+
+	// Mutating the verdict must not leak into the engine.
+	v.Obligations["remediation"] = "changed"
+	v = engine.Evaluate(EvalContext{Tool: "deploy"})
+	if v.Obligations["remediation"] != "open a change ticket" {
+		t.Errorf("obligations leaked: %v", v.Obligations)
+	}
+}
+
+func TestEmptyMessageGivesEmptyReason(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+	v := engine.Evaluate(EvalContext{Tool: "bash"})
+	if v.Reason != "" || v.Obligations != nil {
+		t.Errorf("expected empty reason and obligations, got %q %v", v.Reason, v.Obligations)
+	}
+	v = engine.Evaluate(EvalContext{Tool: "grep"})
+	if v.Reason != "" {
+		t.Errorf("default: expected empty reason, got %q", v.Reason)
+	}
+}
+
+func TestMessageLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: messages
+policies:
+  - id: deny-prod
+    effect: deny
+    message: "Denied: production writes require a change ticket"
+    obligations:
+      ticket_url: https://tickets.example.com/new
+    condition:
+      tools: [deploy]
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewPolicyEngine(ps).Evaluate(EvalContext{Tool: "deploy"})
+	if v.Reason != "Denied: production writes require a change ticket" {
+		t.Errorf("unexpected reason %q", v.Reason)
+	}
+	if v.Obligations["ticket_url"] != "https://tickets.example.com/new" {
+		t.Errorf("unexpected obligations %v", v.Obligations)
+	}
+}
+
+func TestAnnotationsInVerdict(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: annotations
+defaults:
+  effect: allow
+policies:
+  - id: ask-deploy
+    effect: ask
+    annotations:
+      owner: platform-team
+      jira: PLAT-42
+      severity: high
+    condition:
+      tools: [deploy]
+  - id: deny-rm
+    effect: deny
+    condition:
+      tools: [rm]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ps.Policies[0].Annotations["jira"]; got != "PLAT-42" {
+		t.Fatalf("loaded annotations = %v", ps.Policies[0].Annotations)
+	}
+	engine := NewPolicyEngine(ps)
+
+	v := engine.Evaluate(EvalContext{Tool: "deploy"})
+	want := map[string]string{"owner": "platform-team", "jira": "PLAT-42", "severity": "high"}
+	if !reflect.DeepEqual(v.Annotations, want) {
+		t.Errorf("annotations = %v, want %v", v.Annotations, want)
+	}
+	v.Annotations["owner"] = "changed"
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.Annotations["owner"] != "platform-team" {
+		t.Errorf("annotations leaked: %v", v.Annotations)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "rm"}); v.Annotations != nil {
+		t.Errorf("unannotated winner: got %v", v.Annotations)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "view"}); v.Annotations != nil {
+		t.Errorf("default: got %v", v.Annotations)
+	}
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{
+			ID: "ask-deploy", Effect: EffectAsk, Priority: 10, Message: "deploys need approval",
+			Channels:    []Channel{ChannelPhone, ChannelChat},
+			Annotations: map[string]string{"owner": "platform"},
+			Condition:   Condition{Tools: []string{"deploy"}},
+		},
+		{ID: "deny-rm", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"rm"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+
+	p, v := engine.EvaluatePolicy(EvalContext{Tool: "deploy"})
+	if p == nil || p.ID != v.PolicyID || v.PolicyID != "ask-deploy" {
+		t.Fatalf("got policy %+v for verdict %+v", p, v)
+	}
+	if p.Message != v.Reason || p.Annotations["owner"] != "platform" || !reflect.DeepEqual(p.Channels, v.ChannelChain) {
+		t.Errorf("policy %+v does not match verdict %+v", p, v)
+	}
+	if want := engine.Evaluate(EvalContext{Tool: "deploy"}); !reflect.DeepEqual(v, want) {
+		t.Errorf("verdict = %+v, want %+v as from Evaluate", v, want)
+	}
+
+	p.Annotations["owner"] = "changed"
+	p.Condition.Tools[0] = "rm"
+	if p, _ := engine.EvaluatePolicy(EvalContext{Tool: "deploy"}); p == nil || p.Annotations["owner"] != "platform" || p.Condition.Tools[0] != "deploy" {
+		t.Errorf("changes to the returned policy leaked into the engine: %+v", p)
+	}
+
+	if p, v := engine.EvaluatePolicy(EvalContext{Tool: "view"}); p != nil || v.Source != SourceDefault {
+		t.Errorf("default: got %+v, %+v", p, v)
+	}
+}
+
+func TestEvaluatePolicyKeepsEmptyLists(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "not-tools", Effect: EffectDeny, Invert: true, Condition: Condition{Tools: []string{}}},
+	}, EffectAllow))
+	p, v := engine.EvaluatePolicy(EvalContext{Tool: "bash"})
+	if p == nil || v.PolicyID != "not-tools" {
+		t.Fatalf("got policy %+v for verdict %+v", p, v)
+	}
+	if p.Condition.Tools == nil {
+		t.Error("tools: [] came back as an absent tools condition")
+	}
+	if !reflect.DeepEqual(*p, engine.Policies()[0]) {
+		t.Errorf("copy differs from the loaded policy:\n got %+v\nwant %+v", *p, engine.Policies()[0])
+	}
+}
+
+func TestRequireReasonLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: reasons
+policies:
+  - id: prod-deploy
+    effect: ask
+    channel: phone
+    require_reason: true
+    condition:
+      tools: [deploy]
+  - id: bash
+    effect: ask
+    condition:
+      tools: [bash]
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	v := engine.Evaluate(EvalContext{Tool: "deploy"})
+	if !v.RequireReason || v.Channel != ChannelPhone {
+		t.Errorf("expected phone + reason, got %s, RequireReason=%v", v.Channel, v.RequireReason)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.RequireReason {
+		t.Error("bash: expected RequireReason unset")
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "grep"}); v.RequireReason {
+		t.Error("default: expected RequireReason unset")
+	}
+}
+
+func TestCodeLoadedFromYAML(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: codes
+policies:
+  - id: prod-write
+    effect: deny
+    priority: 10
+    message: production writes are blocked
+    code: GUARD_PROD_WRITE_BLOCKED
+    condition:
+      tools: [write_file]
+      modes: [prod]
+  - id: prod-fallback
+    effect: deny
+    priority: 20
+    code: GUARD_PROD_DEFAULT
+    condition:
+      modes: [prod]
+`), WithStrictCodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Mode: "prod", Tool: "write_file"}); v.Code != "GUARD_PROD_WRITE_BLOCKED" {
+		t.Errorf("expected GUARD_PROD_WRITE_BLOCKED, got %q", v.Code)
+	}
+	if v := engine.Evaluate(EvalContext{Mode: "prod", Tool: "bash"}); v.Code != "GUARD_PROD_DEFAULT" {
+		t.Errorf("expected GUARD_PROD_DEFAULT, got %q", v.Code)
+	}
+	if v := engine.Evaluate(EvalContext{Mode: "dev", Tool: "bash"}); v.Code != "" {
+		t.Errorf("default: expected no code, got %q", v.Code)
+	}
+}
+
+func TestLoadPolicySetFromReader(t *testing.T) {
+	ps, err := LoadPolicySetFrom(strings.NewReader(`
+defaults:
+  effect: deny
+policies:
+  - id: allow-view
+    effect: allow
+    condition:
+      tools: [view]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Kind != "PolicySet" || ps.Defaults.Channel != ChannelChat || ps.Policies[0].Priority != 100 {
+		t.Errorf("expected loader defaults applied, got %+v", ps)
+	}
+	if v := NewPolicyEngine(ps).Evaluate(EvalContext{Tool: "view"}); v.Effect != EffectAllow {
+		t.Errorf("expected allow, got %+v", v)
+	}
+
+	_, err = LoadPolicySetFrom(strings.NewReader("policies:\n  - id: p\n    effect: deny\n    tool: [bash]\n"), WithStrictFields())
+	if !errors.Is(err, ErrParse) {
+		t.Errorf("expected strict fields to apply, got %v", err)
+	}
+	_, err = LoadPolicySetFrom(strings.NewReader("policies:\n  - id: p\n    effect: deny\n    condition: {source_cidrs: [nope]}\n"))
+	if !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("expected validation to apply, got %v", err)
+	}
+
+	readErr := errors.New("connection reset")
+	_, err = LoadPolicySetFrom(iotest.ErrReader(readErr))
+	if !errors.Is(err, readErr) || errors.Is(err, ErrParse) {
+		t.Errorf("expected the read error, got %v", err)
+	}
+}
+
+func TestStrictCodes(t *testing.T) {
+	cases := []struct {
+		name    string
+		codes   [2]string
+		wantErr string
+	}{
+		{"valid", [2]string{"GUARD_A", "guard_b2"}, ""},
+		{"leading digit", [2]string{"1_BAD", "GUARD_B"}, `invalid code "1_BAD"`},
+		{"blank", [2]string{" ", "GUARD_B"}, `invalid code " "`},
+		{"dash", [2]string{"GUARD-A", "GUARD_B"}, `invalid code "GUARD-A"`},
+		{"duplicate", [2]string{"GUARD_A", "GUARD_A"}, `policy "b": duplicate code "GUARD_A"`},
+	}
+	for _, tc := range cases {
+		doc := fmt.Sprintf(`
+metadata:
+  name: codes
+policies:
+  - id: a
+    effect: deny
+    code: %q
+  - id: b
+    effect: deny
+    code: %q
+`, tc.codes[0], tc.codes[1])
+		if _, err := LoadPolicySetFromBytes([]byte(doc)); err != nil {
+			t.Errorf("%s: lenient load failed: %v", tc.name, err)
+		}
+		_, err := LoadPolicySetFromBytes([]byte(doc), WithStrictCodes())
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+// ── EvaluateAll ─────────────────────────────────────────────────────────
+
+func TestEvaluateAll(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "p2", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"grep"}}},
+	}, EffectAsk)
+	engine := NewPolicyEngine(ps)
+	results := engine.EvaluateAll(EvalContext{Tool: "bash"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	matched := 0
+	for _, r := range results {
+		if r.Matched {
+			matched++
+			if r.PolicyID != "p1" {
+				t.Errorf("expected p1, got %s", r.PolicyID)
+			}
+		}
+	}
+	if matched != 1 {
+		t.Errorf("expected 1 match, got %d", matched)
+	}
+}
+
+func TestEvaluateAllOrderIsStable(t *testing.T) {
+	policies := []Policy{
+		{ID: "b", Effect: EffectAllow, Priority: 10},
+		{ID: "dup", Effect: EffectDeny, Priority: 20, Name: "first"},
+		{ID: "a", Effect: EffectAsk, Priority: 10},
+		{ID: "c", Effect: EffectDeny, Priority: 5},
+		{ID: "dup", Effect: EffectAllow, Priority: 20, Name: "second"},
+	}
+	for i := 0; i < 12; i++ {
+		policies = append(policies, Policy{ID: fmt.Sprintf("p%02d", i), Effect: EffectAsk, Priority: 30})
+	}
+	ps := makePolicySet(policies, EffectAsk)
+	ps.ContextFallbacks = map[string]string{"a": "b", "b": "c", "c": "d"}
+	engine := NewPolicyEngine(ps)
+
+	ctx := EvalContext{Tool: "bash", Mode: "a"}
+	first := engine.EvaluateAll(ctx)
+	var order []string
+	for _, r := range first {
+		order = append(order, r.PolicyID+"/"+r.Name)
+	}
+	want := []string{"c/", "a/", "b/", "dup/first", "dup/second"}
+	if !reflect.DeepEqual(order[:len(want)], want) {
+		t.Fatalf("expected order to start %v, got %v", want, order)
+	}
+	for i := 0; i < 50; i++ {
+		if got := engine.EvaluateAll(ctx); !reflect.DeepEqual(got, first) {
+			t.Fatalf("call %d returned a different order:\n got %+v\nwant %+v", i, got, first)
+		}
+	}
+
+	// Reordering the file only moves policies that share priority and ID.
+	reversed := make([]Policy, len(policies))
+	for i, p := range policies {
+		reversed[len(policies)-1-i] = p
+	}
+	got := NewPolicyEngine(makePolicySet(reversed, EffectAsk)).EvaluateAll(ctx)
+	for i := range got {
+		if got[i].PolicyID != first[i].PolicyID {
+			t.Fatalf("position %d: got %s, want %s", i, got[i].PolicyID, first[i].PolicyID)
+		}
+	}
+}
+
+func TestEvaluateMatched(t *testing.T) {
+	disabled := false
+	expired := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ps := makePolicySet([]Policy{
+		{ID: "p3", Effect: EffectAsk, Priority: 30, Condition: Condition{Tools: []string{"*"}}},
+		{ID: "p1", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "p2", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"b*"}}},
+		{ID: "grep", Effect: EffectDeny, Priority: 5, Condition: Condition{Tools: []string{"grep"}}},
+		{ID: "off", Effect: EffectDeny, Priority: 1, Enabled: &disabled},
+		{ID: "old", Effect: EffectDeny, Priority: 2, ExpiresAt: &expired},
+	}, EffectAsk)
+	engine := NewPolicyEngine(ps)
+
+	results := engine.EvaluateMatched(EvalContext{Tool: "bash"})
+	var ids []string
+	for _, r := range results {
+		if !r.Matched || !r.Enabled {
+			t.Errorf("%s: matched=%v enabled=%v", r.PolicyID, r.Matched, r.Enabled)
+		}
+		ids = append(ids, r.PolicyID)
+	}
+	if want := []string{"p1", "p2", "p3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected %v, got %v", want, ids)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != results[0].PolicyID {
+		t.Errorf("first result %s is not the winner %s", results[0].PolicyID, v.PolicyID)
+	}
+	if results := engine.EvaluateMatched(EvalContext{Tool: "view", Mode: "x"}); len(results) != 1 {
+		t.Errorf("expected only p3 to match, got %+v", results)
+	}
+}
+
+// ── EvaluateDetailed ────────────────────────────────────────────────────
+
+func TestEvaluateDetailedFlagsWinner(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "p2", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"b*"}}},
+		{ID: "p3", Effect: EffectDeny, Priority: 30, Condition: Condition{Tools: []string{"grep"}}},
+	}, EffectAsk)
+	engine := NewPolicyEngine(ps)
+
+	v, results := engine.EvaluateDetailed(EvalContext{Tool: "bash"})
+	if v.PolicyID != "p1" || v.Effect != EffectAllow {
+		t.Errorf("verdict = %+v", v)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	want := []struct{ matched, winner bool }{{true, true}, {true, false}, {false, false}}
+	for i, w := range want {
+		if results[i].Matched != w.matched || results[i].Winner != w.winner {
+			t.Errorf("%s: matched=%v winner=%v, want %v %v", results[i].PolicyID, results[i].Matched, results[i].Winner, w.matched, w.winner)
+		}
+	}
+}
+
+func TestEvaluateDetailedDefaultHasNoWinner(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectAllow, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectDeny))
+	v, results := engine.EvaluateDetailed(EvalContext{Tool: "view"})
+	if v.Source != SourceDefault {
+		t.Errorf("expected default verdict, got %+v", v)
+	}
+	for _, r := range results {
+		if r.Winner {
+			t.Errorf("unexpected winner %s", r.PolicyID)
+		}
+	}
+}
+
+func TestEvaluateDetailedFallbackWinner(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "bg", Effect: EffectAllow, Condition: Condition{Modes: []string{"background"}, Tools: []string{"bash"}}},
+	}, EffectDeny)
+	ps.ContextFallbacks = map[string]string{"scheduler": "background"}
+	engine := NewPolicyEngine(ps)
+
+	v, results := engine.EvaluateDetailed(EvalContext{Mode: "scheduler", Tool: "bash"})
+	if v.PolicyID != "bg" || v.Source != SourceFallbackMatched {
+		t.Errorf("verdict = %+v", v)
+	}
+	if !results[0].Winner || results[0].Matched {
+		t.Errorf("expected bg flagged as winner but unmatched in scheduler mode, got %+v", results[0])
+	}
+}
+
+func TestEvaluateDetailedAgreesWithEvaluate(t *testing.T) {
+	for _, strategy := range []Strategy{StrategyPriority, StrategySpecificity} {
+		engine := NewPolicyEngineWithOptions(realisticPolicySet(), WithStrategy(strategy))
+		for _, ctx := range []EvalContext{
+			{Tool: "tool-7"},
+			{Tool: "mcp:server3-read"},
+			{Tool: "tool-7", Mode: "background", Risk: "level-4"},
+			{Tool: "unknown"},
+		} {
+			v, results := engine.EvaluateDetailed(ctx)
+			if want := engine.Evaluate(ctx); !reflect.DeepEqual(v, want) {
+				t.Errorf("strategy %d, %+v: detailed %+v, evaluate %+v", strategy, ctx, v, want)
+			}
+			all := engine.EvaluateAll(ctx)
+			for i := range results {
+				r := results[i]
+				r.Winner = false
+				if r != all[i] {
+					t.Errorf("strategy %d, %+v: result %d = %+v, EvaluateAll %+v", strategy, ctx, i, results[i], all[i])
+				}
+			}
+		}
+	}
+}
+
+// ── Expiry ──────────────────────────────────────────────────────────────
+
+func expiringPolicySet(expires time.Time) *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "incident-deploy", Effect: EffectAllow, Priority: 1, ExpiresAt: &expires, Condition: Condition{Tools: []string{"deploy"}}},
+		{ID: "deploy", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"deploy"}}},
+	}, EffectAsk)
+}
+
+func TestExpiredPolicySkipped(t *testing.T) {
+	expires := time.Date(2025, 3, 7, 17, 0, 0, 0, time.UTC)
+	engine := NewPolicyEngine(expiringPolicySet(expires))
+
+	cases := []struct {
+		now  time.Time
+		want string
+	}{
+		{expires.Add(-time.Hour), "incident-deploy"},
+		{expires, "incident-deploy"},
+		{expires.Add(time.Nanosecond), "deploy"},
+		{expires.Add(24 * time.Hour), "deploy"},
 	}
-	if v.Channel != ChannelPhone {
-		t.Errorf("expected phone, got %s", v.Channel)
+	for _, tc := range cases {
+		v := engine.Evaluate(EvalContext{Tool: "deploy", Now: tc.now})
+		if v.PolicyID != tc.want {
+			t.Errorf("at %s: expected %s, got %s", tc.now.Format(time.RFC3339Nano), tc.want, v.PolicyID)
+		}
 	}
 }
 
-// ── EvaluateAll ─────────────────────────────────────────────────────────
+func TestExpiryDefaultsToCurrentTime(t *testing.T) {
+	engine := NewPolicyEngine(expiringPolicySet(time.Now().Add(-time.Minute)))
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.PolicyID != "deploy" {
+		t.Errorf("expected expired policy skipped, got %s", v.PolicyID)
+	}
 
-func TestEvaluateAll(t *testing.T) {
-	ps := makePolicySet([]Policy{
-		{ID: "p1", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
-		{ID: "p2", Effect: EffectDeny, Priority: 20, Condition: Condition{Tools: []string{"grep"}}},
+	engine = NewPolicyEngine(expiringPolicySet(time.Now().Add(time.Hour)))
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.PolicyID != "incident-deploy" {
+		t.Errorf("expected unexpired policy to match, got %s", v.PolicyID)
+	}
+}
+
+func TestEvaluateAllFlagsExpired(t *testing.T) {
+	expires := time.Date(2025, 3, 7, 17, 0, 0, 0, time.UTC)
+	engine := NewPolicyEngine(expiringPolicySet(expires))
+
+	results := engine.EvaluateAll(EvalContext{Tool: "deploy", Now: expires.Add(time.Second)})
+	if !results[0].Expired || results[0].Matched {
+		t.Errorf("expected incident-deploy expired and unmatched, got %+v", results[0])
+	}
+	if results[1].Expired || !results[1].Matched {
+		t.Errorf("expected deploy live and matched, got %+v", results[1])
+	}
+}
+
+func TestLoadExpiresAt(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata: {name: expiry}
+policies:
+  - id: temp
+    effect: allow
+    expires_at: 2025-03-07T17:00:00+01:00
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2025, 3, 7, 16, 0, 0, 0, time.UTC)
+	if got := ps.Policies[0].ExpiresAt; got == nil || !got.Equal(want) {
+		t.Errorf("expires_at = %v, want %v", got, want)
+	}
+
+	_, err = LoadPolicySetFromBytes([]byte(`
+metadata: {name: expiry}
+policies:
+  - id: temp
+    effect: allow
+    expires_at: next friday
+`))
+	if err == nil {
+		t.Error("expected error for malformed expires_at")
+	}
+}
+
+func windowPolicySet(from, until time.Time) *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "strict", Effect: EffectDeny, Priority: 1, ActiveFrom: &from, ExpiresAt: &until, Condition: Condition{Tools: []string{"deploy"}}},
+		{ID: "lenient", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"deploy"}}},
 	}, EffectAsk)
-	engine := NewPolicyEngine(ps)
-	results := engine.EvaluateAll(EvalContext{Tool: "bash"})
-	if len(results) != 2 {
-		t.Fatalf("expected 2 results, got %d", len(results))
+}
+
+func TestActivationWindow(t *testing.T) {
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	engine := NewPolicyEngine(windowPolicySet(from, until))
+
+	cases := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"before", from.Add(-time.Nanosecond), "lenient"},
+		{"at start", from, "strict"},
+		{"within", from.Add(72 * time.Hour), "strict"},
+		{"at end", until, "strict"},
+		{"after", until.Add(time.Nanosecond), "lenient"},
 	}
-	matched := 0
-	for _, r := range results {
-		if r.Matched {
-			matched++
-			if r.PolicyID != "p1" {
-				t.Errorf("expected p1, got %s", r.PolicyID)
-			}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Tool: "deploy", Now: tc.now}); v.PolicyID != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, v.PolicyID)
 		}
 	}
-	if matched != 1 {
-		t.Errorf("expected 1 match, got %d", matched)
+}
+
+func TestActiveFromWithoutExpiry(t *testing.T) {
+	from := time.Now().Add(time.Hour)
+	ps := windowPolicySet(from, from)
+	ps.Policies[0].ExpiresAt = nil
+	engine := NewPolicyEngine(ps)
+
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.PolicyID != "lenient" {
+		t.Errorf("now: expected lenient, got %s", v.PolicyID)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "deploy", Now: from.Add(365 * 24 * time.Hour)}); v.PolicyID != "strict" {
+		t.Errorf("next year: expected strict, got %s", v.PolicyID)
+	}
+}
+
+func TestEvaluateAllFlagsPending(t *testing.T) {
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	engine := NewPolicyEngine(windowPolicySet(from, from.Add(time.Hour)))
+
+	r := engine.EvaluateAll(EvalContext{Tool: "deploy", Now: from.Add(-time.Second)})[0]
+	if !r.Pending || r.Expired || r.Matched {
+		t.Errorf("expected strict pending and unmatched, got %+v", r)
+	}
+}
+
+func TestLoadRejectsEmptyWindow(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+metadata: {name: window}
+policies:
+  - id: backwards
+    effect: deny
+    active_from: 2025-07-01T00:00:00Z
+    expires_at: 2025-06-01T00:00:00Z
+`))
+	if err == nil || !strings.Contains(err.Error(), "expires_at is before active_from") {
+		t.Errorf("expected window error, got %v", err)
 	}
 }
 
 // ── Custom effects ──────────────────────────────────────────────────────
 
+func TestEffectsUsed(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: effects
+defaults:
+  effect: deny
+policies:
+  - id: mfa
+    effect: my-org-mfa
+  - id: off
+    effect: hitl
+    enabled: false
+  - id: view
+    effect: allow
+  - id: throttle
+    effect: rate-limit
+    rate_limit: {max: 5, exceeded: quarantine}
+  - id: throttle-default
+    effect: rate-limit
+    rate_limit: {max: 5}
+  - id: deny-rm
+    effect: deny
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Effect{EffectAllow, EffectAsk, EffectDeny, EffectHITL, "my-org-mfa", "quarantine"}
+	if got := ps.EffectsUsed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
 func TestWellKnownEffects(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -344,6 +2021,63 @@ policies:
 	}
 }
 
+func TestLookupWellKnownEffects(t *testing.T) {
+	for _, e := range []Effect{EffectAllow, EffectDeny, EffectAsk, EffectHITL, EffectPITL, EffectAITL, EffectFilter} {
+		if _, ok := LookupEffect(e); !ok {
+			t.Errorf("%s should be well-known", e)
+		}
+	}
+	if meta, _ := LookupEffect(EffectDeny); !meta.Terminal {
+		t.Error("deny should be terminal")
+	}
+	if _, ok := LookupEffect("never-registered"); ok {
+		t.Error("unregistered effect should not be found")
+	}
+}
+
+func TestRegisterEffect(t *testing.T) {
+	RegisterEffect("test-register-mfa", EffectMeta{Label: "MFA", Terminal: false})
+	meta, ok := LookupEffect("test-register-mfa")
+	if !ok {
+		t.Fatal("expected registered effect to be found")
+	}
+	if meta.Label != "MFA" {
+		t.Errorf("expected label MFA, got %s", meta.Label)
+	}
+}
+
+func TestStrictEffects(t *testing.T) {
+	doc := func(effect string) []byte {
+		return []byte("metadata:\n  name: strict\npolicies:\n  - id: p1\n    effect: " + effect + "\n")
+	}
+
+	// Default: any string is accepted.
+	if _, err := LoadPolicySetFromBytes(doc("test-strict-typo")); err != nil {
+		t.Fatalf("lenient mode should accept custom effects: %v", err)
+	}
+
+	// Strict: unknown effects are rejected.
+	if _, err := LoadPolicySetFromBytes(doc("test-strict-typo"), WithStrictEffects()); err == nil {
+		t.Fatal("strict mode should reject unknown effects")
+	}
+
+	// Strict: well-known and registered effects are accepted.
+	if _, err := LoadPolicySetFromBytes(doc("hitl"), WithStrictEffects()); err != nil {
+		t.Fatalf("strict mode should accept well-known effects: %v", err)
+	}
+	RegisterEffect("test-strict-auth", EffectMeta{Label: "Org auth"})
+	if _, err := LoadPolicySetFromBytes(doc("test-strict-auth"), WithStrictEffects()); err != nil {
+		t.Fatalf("strict mode should accept registered effects: %v", err)
+	}
+}
+
+func TestStrictEffectsChecksDefault(t *testing.T) {
+	bad := "metadata:\n  name: strict\ndefaults:\n  effect: test-strict-bad-default\npolicies: []\n"
+	if _, err := LoadPolicySetFromBytes([]byte(bad), WithStrictEffects()); err == nil {
+		t.Fatal("strict mode should reject an unknown default effect")
+	}
+}
+
 func TestResolve(t *testing.T) {
 	ps := makePolicySet([]Policy{
 		{ID: "p1", Effect: EffectAITL, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
@@ -357,6 +2091,129 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+// ── Cancellation ────────────────────────────────────────────────────────
+
+func TestEvaluateCtxMatchesEvaluate(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+	v, err := engine.EvaluateCtx(context.Background(), EvalContext{Tool: "bash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, engine.Evaluate(EvalContext{Tool: "bash"})) {
+		t.Errorf("EvaluateCtx and Evaluate disagree: %+v", v)
+	}
+}
+
+func TestEvaluateCtxCancelled(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	v, err := engine.EvaluateCtx(ctx, EvalContext{Tool: "bash"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if v.Effect != "" {
+		t.Errorf("expected zero verdict, got %+v", v)
+	}
+}
+
+func TestEvaluateCtxDeadlineExceeded(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet(nil, EffectAllow))
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := engine.EvaluateCtx(ctx, EvalContext{Tool: "bash"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// ── Concurrency ─────────────────────────────────────────────────────────
+
+func TestConcurrentEvaluateAndLoad(t *testing.T) {
+	allowSet := makePolicySet([]Policy{
+		{ID: "allow", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk)
+	denySet := makePolicySet([]Policy{
+		{ID: "deny", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk)
+	engine := NewPolicyEngine(allowSet)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				v := engine.Evaluate(EvalContext{Tool: "bash"})
+				// A verdict must come entirely from one policy set.
+				if !(v.Effect == EffectAllow && v.PolicyID == "allow") &&
+					!(v.Effect == EffectDeny && v.PolicyID == "deny") {
+					t.Errorf("torn verdict: %+v", v)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		if i%2 == 0 {
+			engine.Load(denySet)
+		} else {
+			engine.Load(allowSet)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkEvaluateDuringReload(b *testing.B) {
+	sets := []*PolicySet{
+		makePolicySet([]Policy{
+			{ID: "allow", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		}, EffectAsk),
+		makePolicySet([]Policy{
+			{ID: "deny", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		}, EffectAsk),
+	}
+	engine := NewPolicyEngine(sets[0])
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				engine.Load(sets[i%2])
+			}
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := EvalContext{Tool: "bash"}
+		for pb.Next() {
+			engine.Evaluate(ctx)
+		}
+	})
+	b.StopTimer()
+	close(stop)
+	<-done
+}
+
 // ── Context fallbacks ───────────────────────────────────────────────────
 
 func TestContextFallbackToBackground(t *testing.T) {
@@ -402,62 +2259,325 @@ func TestContextFallbackMultiLevel(t *testing.T) {
 		},
 	}
 	engine := NewPolicyEngine(ps)
-	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "scheduler"})
-	if v.Effect != EffectHITL {
-		t.Errorf("expected hitl, got %s", v.Effect)
+	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "scheduler"})
+	if v.Effect != EffectHITL {
+		t.Errorf("expected hitl, got %s", v.Effect)
+	}
+	if v.PolicyID != "bg" {
+		t.Errorf("expected bg, got %s", v.PolicyID)
+	}
+}
+
+func TestContextFallbackCyclePrevention(t *testing.T) {
+	ps := &PolicySet{
+		Metadata: Metadata{Name: "test"},
+		Defaults: Defaults{Effect: EffectDeny, Channel: ChannelChat},
+		ContextFallbacks: map[string]string{
+			"a": "b",
+			"b": "a",
+		},
+	}
+	engine := NewPolicyEngine(ps)
+	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "a"})
+	if v.Effect != EffectDeny {
+		t.Errorf("expected deny (default), got %s", v.Effect)
+	}
+}
+
+func TestContextFallbackLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: fallback-test
+defaults:
+  effect: allow
+context_fallbacks:
+  scheduler: background
+  bot_processor: background
+policies:
+  - id: deny-bg-bash
+    effect: deny
+    condition:
+      modes: [background]
+      tools: [bash]
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.ContextFallbacks) != 2 {
+		t.Fatalf("expected 2 fallbacks, got %d", len(ps.ContextFallbacks))
+	}
+	engine := NewPolicyEngine(ps)
+	if got := engine.Resolve(EvalContext{Tool: "bash", Mode: "scheduler"}); got != "deny" {
+		t.Errorf("expected deny, got %s", got)
+	}
+	if got := engine.Resolve(EvalContext{Tool: "grep", Mode: "scheduler"}); got != "allow" {
+		t.Errorf("expected allow, got %s", got)
+	}
+}
+
+func TestPerModeDefaults(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: per-mode
+defaults:
+  effect: allow
+  per_mode:
+    background: deny
+    interactive: ask
+context_fallbacks:
+  scheduler: background
+  nightly: scheduler
+policies:
+  - id: allow-scheduled-grep
+    effect: allow
+    condition:
+      modes: [scheduler]
+      tools: [grep]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]Effect{"background": EffectDeny, "interactive": EffectAsk}; !reflect.DeepEqual(ps.Defaults.PerMode, want) {
+		t.Fatalf("per_mode = %v", ps.Defaults.PerMode)
+	}
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		mode, tool string
+		want       Effect
+		source     VerdictSource
+	}{
+		{"background", "bash", EffectDeny, SourceDefault},
+		{"interactive", "bash", EffectAsk, SourceDefault},
+		{"cli", "bash", EffectAllow, SourceDefault},             // no per-mode default
+		{"scheduler", "bash", EffectDeny, SourceDefault},        // inherits background's default
+		{"nightly", "grep", EffectAllow, SourceFallbackMatched}, // chain is walked first
+	}
+	for _, tc := range cases {
+		v := engine.Evaluate(EvalContext{Mode: tc.mode, Tool: tc.tool})
+		if v.Effect != tc.want || v.Source != tc.source || v.Channel != ChannelChat {
+			t.Errorf("%s/%s: expected %s (%s), got %+v", tc.mode, tc.tool, tc.want, tc.source, v)
+		}
+	}
+}
+
+func TestPerModeDefaultsValidated(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: per-mode
+defaults:
+  per_mode:
+    background: ""
+policies: []
+`))
+	if err == nil || !strings.Contains(err.Error(), `per_mode "background": empty effect`) {
+		t.Errorf("expected empty effect error, got %v", err)
+	}
+	_, err = LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: per-mode
+defaults:
+  per_mode:
+    background: quarantine
+policies: []
+`), WithStrictEffects())
+	if err == nil || !strings.Contains(err.Error(), "unknown effect") {
+		t.Errorf("expected unknown effect error, got %v", err)
+	}
+}
+
+func TestRequireExplicitMatch(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "allow-view", Effect: EffectAllow, Condition: Condition{Tools: []string{"view"}}},
+		{ID: "deny-rm-safe", Effect: EffectDeny, Condition: Condition{Tools: []string{"rm"}, Modes: []string{"safe"}}},
+	}, EffectAllow)
+	ps.Defaults.PerMode = map[string]Effect{"interactive": EffectAsk}
+	ps.ContextFallbacks = map[string]string{"auto": "safe"}
+	engine := NewPolicyEngineWithOptions(ps, WithRequireExplicitMatch())
+
+	tests := []struct {
+		name   string
+		ctx    EvalContext
+		effect Effect
+		source VerdictSource
+	}{
+		{"no match", EvalContext{Tool: "bash"}, EffectDeny, SourceNoMatch},
+		{"fallback exhausted", EvalContext{Tool: "bash", Mode: "auto"}, EffectDeny, SourceNoMatch},
+		{"matched", EvalContext{Tool: "view"}, EffectAllow, SourceMatched},
+		{"via fallback", EvalContext{Tool: "rm", Mode: "auto"}, EffectDeny, SourceFallbackMatched},
+		{"per-mode default", EvalContext{Tool: "bash", Mode: "interactive"}, EffectAsk, SourceDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := engine.Evaluate(tt.ctx)
+			if v.Effect != tt.effect || v.Source != tt.source {
+				t.Errorf("got %s/%s, want %s/%s", v.Effect, v.Source, tt.effect, tt.source)
+			}
+			if d, _ := engine.EvaluateDetailed(tt.ctx); d.Source != tt.source {
+				t.Errorf("EvaluateDetailed: got source %s, want %s", d.Source, tt.source)
+			}
+		})
+	}
+
+	if v := NewPolicyEngine(ps).Evaluate(EvalContext{Tool: "bash"}); v.Effect != EffectAllow || v.Source != SourceDefault {
+		t.Errorf("expected the default without the option, got %+v", v)
+	}
+}
+
+func TestChannelByEffect(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: channels
+defaults:
+  effect: hitl
+  channel: slack
+  channel_by_effect:
+    pitl: phone
+    hitl: chat
+policies:
+  - id: pitl-deploy
+    effect: pitl
+    condition:
+      tools: [deploy]
+  - id: hitl-edit
+    effect: hitl
+    condition:
+      tools: [edit]
+  - id: pitl-rm-chat
+    effect: pitl
+    channel: chat
+    condition:
+      tools: [rm]
+  - id: ask-bash
+    effect: ask
+    condition:
+      tools: [bash]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ps.Defaults.ChannelByEffect[EffectPITL]; got != ChannelPhone {
+		t.Fatalf("loaded channel_by_effect = %v", ps.Defaults.ChannelByEffect)
+	}
+	engine := NewPolicyEngine(ps)
+	for _, tt := range []struct {
+		tool    string
+		channel Channel
+	}{
+		{"deploy", ChannelPhone},
+		{"edit", ChannelChat},
+		{"rm", ChannelChat},   // the policy's own channel wins
+		{"bash", "slack"},     // no mapping for ask
+		{"view", ChannelChat}, // the default verdict is hitl
+	} {
+		if v := engine.Evaluate(EvalContext{Tool: tt.tool}); v.Channel != tt.channel {
+			t.Errorf("%s: got channel %q (%s), want %q", tt.tool, v.Channel, v.Effect, tt.channel)
+		}
 	}
-	if v.PolicyID != "bg" {
-		t.Errorf("expected bg, got %s", v.PolicyID)
+
+	bad := makePolicySet(nil, EffectAsk)
+	bad.Defaults.ChannelByEffect = map[Effect]Channel{EffectPITL: ""}
+	if errs := Validate(bad); len(errs) != 1 || !errors.Is(errs[0], ErrInvalidDefaults) {
+		t.Errorf("empty channel: got %v", errs)
 	}
 }
 
-func TestContextFallbackCyclePrevention(t *testing.T) {
-	ps := &PolicySet{
-		Metadata: Metadata{Name: "test"},
-		Defaults: Defaults{Effect: EffectDeny, Channel: ChannelChat},
-		ContextFallbacks: map[string]string{
-			"a": "b",
-			"b": "a",
-		},
+func TestWithDefaultEffect(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: shared
+defaults:
+  effect: allow
+policies:
+  - id: deny-rm
+    effect: deny
+    condition:
+      tools: [rm]
+`))
+	if err != nil {
+		t.Fatal(err)
 	}
-	engine := NewPolicyEngine(ps)
-	v := engine.Evaluate(EvalContext{Tool: "bash", Mode: "a"})
-	if v.Effect != EffectDeny {
-		t.Errorf("expected deny (default), got %s", v.Effect)
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		want Effect
+	}{
+		{"file default", nil, EffectAllow},
+		{"prod", []Option{WithDefaultEffect(EffectDeny)}, EffectDeny},
+		{"dev", []Option{WithDefaultEffect(EffectAsk)}, EffectAsk},
+	} {
+		engine := NewPolicyEngineWithOptions(ps, tt.opts...)
+		if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Effect != tt.want || v.Source != SourceDefault {
+			t.Errorf("%s: got %s/%s, want %s from the defaults", tt.name, v.Effect, v.Source, tt.want)
+		}
+		if v := engine.Evaluate(EvalContext{Tool: "rm"}); v.Effect != EffectDeny {
+			t.Errorf("%s: rm got %s, want deny", tt.name, v.Effect)
+		}
+		if got := engine.Defaults().Effect; got != tt.want {
+			t.Errorf("%s: Defaults().Effect = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if ps.Defaults.Effect != EffectAllow {
+		t.Errorf("source set modified: default effect %s", ps.Defaults.Effect)
 	}
 }
 
-func TestContextFallbackLoadedFromYAML(t *testing.T) {
-	yamlDoc := `
-apiVersion: agent-policy/v1
-kind: PolicySet
+func TestToolAliases(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
 metadata:
-  name: fallback-test
+  name: aliases
 defaults:
   effect: allow
-context_fallbacks:
-  scheduler: background
-  bot_processor: background
+aliases:
+  shell: bash
+  run: bash
 policies:
-  - id: deny-bg-bash
+  - id: deny-bash
     effect: deny
     condition:
-      modes: [background]
       tools: [bash]
-`
-	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+`))
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(ps.ContextFallbacks) != 2 {
-		t.Fatalf("expected 2 fallbacks, got %d", len(ps.ContextFallbacks))
-	}
 	engine := NewPolicyEngine(ps)
-	if got := engine.Resolve(EvalContext{Tool: "bash", Mode: "scheduler"}); got != "deny" {
-		t.Errorf("expected deny, got %s", got)
+	for _, tool := range []string{"bash", "shell", "run"} {
+		if v := engine.Evaluate(EvalContext{Tool: tool}); v.PolicyID != "deny-bash" {
+			t.Errorf("%s: expected deny-bash, got %q", tool, v.PolicyID)
+		}
 	}
-	if got := engine.Resolve(EvalContext{Tool: "grep", Mode: "scheduler"}); got != "allow" {
-		t.Errorf("expected allow, got %s", got)
+	if v := engine.Evaluate(EvalContext{Tool: "sh"}); v.Effect != EffectAllow {
+		t.Errorf("sh: expected allow, got %s", v.Effect)
+	}
+	if r := engine.EvaluateAll(EvalContext{Tool: "shell"}); !r[0].Matched {
+		t.Error("EvaluateAll should resolve aliases")
+	}
+
+	aliases := engine.Aliases()
+	if want := map[string]string{"shell": "bash", "run": "bash"}; !reflect.DeepEqual(aliases, want) {
+		t.Errorf("Aliases() = %v", aliases)
+	}
+	aliases["sh"] = "bash" // a copy
+	if _, ok := engine.Aliases()["sh"]; ok {
+		t.Error("Aliases() exposed the engine's map")
+	}
+}
+
+func TestToolAliasesValidated(t *testing.T) {
+	cases := map[string]string{
+		"shell: bash\n  bash: sh": `"shell": target "bash" is itself an alias`,
+		"bash: bash":              `"bash": aliases itself`,
+		`shell: ""`:               `"shell": empty tool name`,
+	}
+	for aliases, want := range cases {
+		_, err := LoadPolicySetFromBytes([]byte("metadata:\n  name: a\naliases:\n  " + aliases + "\npolicies: []\n"))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", aliases, want, err)
+		}
 	}
 }
 
@@ -474,6 +2594,72 @@ func TestContextFallbacksProperty(t *testing.T) {
 	}
 }
 
+func TestVerdictJSONShape(t *testing.T) {
+	v := Verdict{
+		Effect:        EffectHITL,
+		Channel:       ChannelPhone,
+		PolicyID:      "p1",
+		Reason:        "prod write",
+		Code:          "PROD_WRITE",
+		Obligations:   map[string]string{"ticket": "required"},
+		Source:        SourceFallbackMatched,
+		RequireReason: true,
+		ChannelChain:  []Channel{ChannelPhone, ChannelChat},
+	}
+	cases := []struct {
+		v    any
+		want string
+	}{
+		{v, `{"effect":"hitl","channel":"phone","policy_id":"p1","reason":"prod write","code":"PROD_WRITE",` +
+			`"obligations":{"ticket":"required"},"source":"fallback_matched","require_reason":true,"channel_chain":["phone","chat"]}`},
+		{&v, `{"effect":"hitl","channel":"phone","policy_id":"p1","reason":"prod write","code":"PROD_WRITE",` +
+			`"obligations":{"ticket":"required"},"source":"fallback_matched","require_reason":true,"channel_chain":["phone","chat"]}`},
+		{Verdict{Effect: EffectAsk, Channel: ChannelChat, Source: SourceDefault}, `{"effect":"ask","channel":"chat","source":"default"}`},
+		{MatchResult{PolicyID: "p1", Name: "P", Priority: 10, Effect: EffectDeny, Matched: true, Enabled: true, Winner: true},
+			`{"policy_id":"p1","name":"P","priority":10,"effect":"deny","matched":true,"enabled":true,"expired":false,"pending":false,"winner":true}`},
+	}
+	for _, tc := range cases {
+		got, err := json.Marshal(tc.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("got  %s\nwant %s", got, tc.want)
+		}
+	}
+
+	var back Verdict
+	if err := json.Unmarshal([]byte(cases[0].want), &back); err != nil || !reflect.DeepEqual(back, v) {
+		t.Errorf("round trip: %+v, %v", back, err)
+	}
+}
+
+func TestVerdictSource(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "bg-bash", Effect: EffectAllow, Condition: Condition{Modes: []string{"background"}, Tools: []string{"bash"}}},
+		{ID: "chat-view", Effect: EffectAllow, Condition: Condition{Modes: []string{"chat"}, Tools: []string{"view"}}},
+	}, EffectDeny)
+	ps.ContextFallbacks = map[string]string{"scheduler": "background"}
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		ctx    EvalContext
+		source VerdictSource
+		id     string
+	}{
+		{EvalContext{Mode: "chat", Tool: "view"}, SourceMatched, "chat-view"},
+		{EvalContext{Mode: "scheduler", Tool: "bash"}, SourceFallbackMatched, "bg-bash"},
+		{EvalContext{Mode: "scheduler", Tool: "view"}, SourceDefault, ""},
+		{EvalContext{Mode: "chat", Tool: "rm"}, SourceDefault, ""},
+	}
+	for _, tc := range cases {
+		v := engine.Evaluate(tc.ctx)
+		if v.Source != tc.source || v.PolicyID != tc.id {
+			t.Errorf("%s/%s: got source %q policy %q, want %q %q", tc.ctx.Mode, tc.ctx.Tool, v.Source, v.PolicyID, tc.source, tc.id)
+		}
+	}
+}
+
 // ── Loader ──────────────────────────────────────────────────────────────
 
 func TestLoadFromBytes(t *testing.T) {
@@ -560,6 +2746,145 @@ policies:
 	}
 }
 
+func TestPolicyChannelInheritsDefault(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: phone-first
+defaults:
+  effect: deny
+  channel: phone
+policies:
+  - id: ask-deploy
+    effect: ask
+    condition:
+      tools: [deploy]
+  - id: ask-bash
+    effect: ask
+    channel: chat
+    condition:
+      tools: [bash]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.Channel != ChannelPhone {
+		t.Errorf("omitted channel: expected phone, got %s", v.Channel)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Channel != ChannelChat {
+		t.Errorf("explicit channel: expected chat, got %s", v.Channel)
+	}
+
+	ps, err = LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: no-defaults
+policies:
+  - id: ask-deploy
+    effect: ask
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Policies[0].Channel != ChannelChat {
+		t.Errorf("without defaults: expected chat, got %s", ps.Policies[0].Channel)
+	}
+}
+
+func TestChannelChain(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: chains
+policies:
+  - id: prod-deploy
+    effect: ask
+    priority: 10
+    channels: [phone, chat]
+    condition:
+      tools: [deploy]
+  - id: bash
+    effect: ask
+    priority: 20
+    channel: phone
+    condition:
+      tools: [bash]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Policies[0].Channel != ChannelPhone {
+		t.Errorf("channel should default to the head of channels, got %s", ps.Policies[0].Channel)
+	}
+	engine := NewPolicyEngine(ps)
+
+	v := engine.Evaluate(EvalContext{Tool: "deploy"})
+	want := []Channel{ChannelPhone, ChannelChat}
+	if v.Channel != ChannelPhone || !reflect.DeepEqual(v.ChannelChain, want) {
+		t.Errorf("deploy: channel %s, chain %v", v.Channel, v.ChannelChain)
+	}
+	v.ChannelChain[0] = "pager"
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.ChannelChain[0] != ChannelPhone {
+		t.Error("mutating the verdict's chain leaked into the engine")
+	}
+
+	v = engine.Evaluate(EvalContext{Tool: "bash"})
+	if v.ChannelChain != nil || !reflect.DeepEqual(v.ApprovalChannels(), []Channel{ChannelPhone}) {
+		t.Errorf("bash: chain %v, approval channels %v", v.ChannelChain, v.ApprovalChannels())
+	}
+	if got := engine.Evaluate(EvalContext{Tool: "grep"}).ApprovalChannels(); !reflect.DeepEqual(got, []Channel{ChannelChat}) {
+		t.Errorf("default: approval channels %v", got)
+	}
+}
+
+func TestChannelMustHeadChannelChain(t *testing.T) {
+	_, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: bad-chain
+policies:
+  - id: p1
+    effect: ask
+    channel: chat
+    channels: [phone, chat]
+`))
+	if err == nil || !strings.Contains(err.Error(), `channel "chat" is not the first of channels`) {
+		t.Errorf("expected channel mismatch error, got %v", err)
+	}
+}
+
+func TestStrictFields(t *testing.T) {
+	typo := []byte(`
+metadata:
+  name: typo
+policies:
+  - id: deny-bash
+    effect: deny
+    condition:
+      tool: [bash]
+`)
+	ps, err := LoadPolicySetFromBytes(typo)
+	if err != nil {
+		t.Fatalf("lenient mode should ignore unknown keys: %v", err)
+	}
+	if ps.Policies[0].Condition.Tools != nil {
+		t.Errorf("misspelled key should not populate tools: %+v", ps.Policies[0].Condition)
+	}
+
+	_, err = LoadPolicySetFromBytes(typo, WithStrictFields())
+	if err == nil || !strings.Contains(err.Error(), "field tool not found") {
+		t.Errorf("strict mode: expected unknown field error, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "typo.yaml")
+	if err := os.WriteFile(path, typo, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicySetStrict(path); err == nil {
+		t.Error("LoadPolicySetStrict: expected unknown field error")
+	}
+	if _, err := LoadPolicySetStrict(filepath.Join("..", "examples", "balanced.yaml")); err != nil {
+		t.Errorf("LoadPolicySetStrict: example should load: %v", err)
+	}
+}
+
 func TestInvalidKind(t *testing.T) {
 	bad := `apiVersion: agent-policy/v1
 kind: NotAPolicy
@@ -613,6 +2938,321 @@ func TestGlobMatchEmpty(t *testing.T) {
 	}
 }
 
+func TestGlobMatchDoubleStar(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"a/**", "a/b", true},
+		{"a/**", "a/b/c/d", true},
+		{"a/**", "b/c", false},
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/x/c", false},
+		{"a/**/c", "a/b/x/c", true},
+		{"mcp:github/**", "mcp:github/org/repo", true},
+		{"mcp:github/*", "mcp:github/org/repo", false},
+		{"mcp:github/*", "mcp:github/org", true},
+		{"**/repo", "mcp:github/org/repo", true},
+		{"a/**[", "a/b", false},
+	}
+	for _, tc := range cases {
+		if got := GlobMatch(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("GlobMatch(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestGlobMatchCharacterClasses(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"gpt-[45]", "gpt-4", true},
+		{"gpt-[45]", "gpt-5", true},
+		{"gpt-[45]", "gpt-3", false},
+		{"gpt-[45]", "gpt-45", false},
+		{"gpt-[a-c]", "gpt-a", true},
+		{"gpt-[a-c]", "gpt-c", true},
+		{"gpt-[a-c]", "gpt-d", false},
+		{"gpt-[a-c]", "gpt-B", false},
+		{"gpt-[^45]", "gpt-3", true},
+		{"gpt-[^45]", "gpt-4", false},
+		{"gpt-[45]*", "gpt-4o", true},
+		{"gpt-[45]/**", "gpt-4/mini/2024", true},
+		// Malformed classes fall back to literal comparison.
+		{"gpt-[", "gpt-[", true},
+		{"gpt-[", "gpt-4", false},
+		{"gpt-[a-", "gpt-a", false},
+		{"gpt-[]", "gpt-[]", true},
+	}
+	for _, tc := range cases {
+		if got := GlobMatch(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("GlobMatch(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+		m := compilePattern(tc.pattern)
+		if got := m.match(tc.value); got != tc.want {
+			t.Errorf("compiled %q on %q = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
+
+// TestGlobMatchSeparators pins behaviour that must not vary with GOOS:
+// only "/" separates segments, ":" is an ordinary character and \
+// escapes rather than separating, as it would in a Windows path.
+func TestGlobMatchSeparators(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"mcp:*", "mcp:github", true},
+		{"mcp:*", "mcp:github:issues", true},
+		{"mcp:*", "mcp:github/issues", false},
+		{"mcp:**", "mcp:github/issues", true},
+		{"*:read", "fs:read", true},
+		{"*:read", "fs/sub:read", false},
+		{"fs:?ead", "fs:read", true},
+		{"a*", `a\b`, true},
+		{`a\\*`, `a\b`, true},
+		{`a\\*`, `a\b/c`, false},
+		{`a\b`, "ab", true},
+		{`a\b`, `a\b`, false},
+		{`\*`, "*", true},
+		{`\*`, "x", false},
+		{"*/b", `a\b`, false},
+		{"*/b", "a/b", true},
+	}
+	for _, tc := range cases {
+		if got := GlobMatch(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("GlobMatch(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+		m := compilePattern(tc.pattern)
+		if got := m.match(tc.value); got != tc.want {
+			t.Errorf("compiled %q on %q = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestAnchoredPatterns(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"prefix:mcp:", "mcp:github", true},
+		{"prefix:mcp:", "mcp:github/issues/42", true},
+		{"prefix:mcp:", "xmcp:github", false},
+		{"suffix:.pem", "/etc/ssl/key.pem", true},
+		{"suffix:.pem", "key.pem.bak", false},
+		{"prefix:gpt-*", "gpt-5", false},
+		{"prefix:gpt-*", "gpt-*-mini", true},
+		{"suffix:[1]", "arr[1]", true},
+		{"prefix:", "anything", true},
+		{"prefix:", "", true},
+		{`prefix\:mcp`, "prefix:mcp", true},
+		{`prefix\:mcp`, "mcp:github", false},
+		// Without an anchor the same text is an ordinary glob.
+		{"mcp:*", "mcp:github/issues", false},
+		{"*.pem", "/etc/ssl/key.pem", false},
+	}
+	for _, tc := range cases {
+		if got := GlobMatch(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("GlobMatch(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+		m := compilePattern(tc.pattern)
+		if got := m.match(tc.value); got != tc.want {
+			t.Errorf("compiled %q on %q = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+
+	ps := makePolicySet([]Policy{
+		{ID: "keys", Effect: EffectDeny, Priority: 20, Condition: Condition{Args: map[string][]string{"path": {"suffix:.pem"}}}},
+		{ID: "mcp", Effect: EffectAsk, Priority: 10, Condition: Condition{Tools: []string{"prefix:mcp:", "bash"}}},
+	}, EffectAllow)
+	e := NewPolicyEngine(ps)
+	for _, tc := range []struct {
+		ec   EvalContext
+		want string
+	}{
+		{EvalContext{Tool: "mcp:github/issues"}, "mcp"},
+		{EvalContext{Tool: "bash"}, "mcp"},
+		{EvalContext{Tool: "read", Args: map[string]string{"path": "/home/u/.ssh/id.pem"}}, "keys"},
+		{EvalContext{Tool: "read", Args: map[string]string{"path": "/home/u/notes.txt"}}, ""},
+	} {
+		if v := e.Evaluate(tc.ec); v.PolicyID != tc.want {
+			t.Errorf("%+v: got policy %q, want %q", tc.ec, v.PolicyID, tc.want)
+		}
+	}
+}
+
+func TestCoversAnchoredPattern(t *testing.T) {
+	cases := []struct {
+		broad, narrow string
+		want          bool
+	}{
+		{"prefix:mcp:", "mcp:github", true},
+		{"prefix:mcp:", "mcp:github/**", true},
+		{"prefix:mcp:", "prefix:mcp:github", true},
+		{"prefix:mcp:github", "prefix:mcp:", false},
+		{"suffix:.pem", "*.pem", true},
+		{"suffix:.pem", "suffix:key.pem", true},
+		{"suffix:.pem", "prefix:key.pem", false},
+		{"mcp:**", "prefix:mcp:github", true},
+		{"mcp:*", "prefix:mcp:github", false},
+		{"**.pem", "suffix:.pem", true},
+		{"prefix:mcp:", "*:github", false},
+	}
+	for _, tc := range cases {
+		if got := coversPattern(tc.broad, tc.narrow); got != tc.want {
+			t.Errorf("coversPattern(%q, %q) = %v, want %v", tc.broad, tc.narrow, got, tc.want)
+		}
+	}
+}
+
+// ── Compiled patterns ───────────────────────────────────────────────────
+
+func TestCompiledPatternMatchesGlobMatch(t *testing.T) {
+	patterns := []string{
+		"", "*", "bash", "gpt-*", "*-server", "mcp:github-*", "/etc/*", "*/passwd",
+		"gpt-?", "a*b", "[ab]*", "gpt-[", `esc\*`, "**", "*mid*", "a/**", "a/*/c",
+		"prefix:/etc/", "suffix:-server", "prefix:", "prefix:gpt-*",
+	}
+	values := []string{
+		"", "bash", "gpt-5", "gpt-5.2", "gpt-", "azure-mcp-server", "-server",
+		"mcp:github-issues", "/etc/passwd", "/etc/ssh/sshd_config", "etc/passwd",
+		"ab", "axxb", "gpt-[", `esc*`, "a/b", "amidb", "a/b/c",
+	}
+	for _, p := range patterns {
+		m := compilePattern(p)
+		for _, v := range values {
+			if got, want := m.match(v), GlobMatch(p, v); got != want {
+				t.Errorf("pattern %q value %q: compiled %v, GlobMatch %v", p, v, got, want)
+			}
+		}
+	}
+}
+
+func TestEmptyPatternListMatchesNothing(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{}}},
+	}, EffectAllow)
+	if v := NewPolicyEngine(ps).Evaluate(EvalContext{Tool: "bash"}); v.Effect != EffectAllow {
+		t.Errorf("expected allow, got %s", v.Effect)
+	}
+}
+
+// largePolicySet builds n tool-specific policies plus a few broad ones.
+func largePolicySet(n int) *PolicySet {
+	policies := make([]Policy, 0, n)
+	for i := 0; i < n; i++ {
+		cond := Condition{Tools: []string{fmt.Sprintf("tool-%d", i), fmt.Sprintf("mcp:server-%d-*", i)}}
+		switch i % 4 {
+		case 1:
+			cond.Modes = []string{"background", "scheduler"}
+		case 2:
+			cond.Models = []string{"gpt-*", "claude-*"}
+		case 3:
+			cond.Risk = []string{"high", "critical"}
+		}
+		policies = append(policies, Policy{
+			ID: fmt.Sprintf("p%d", i), Effect: EffectDeny, Priority: 100 + i,
+			Channel: ChannelChat, Condition: cond,
+		})
+	}
+	return makePolicySet(policies, EffectAsk)
+}
+
+// globListMatches is the uncompiled reference used to benchmark compilation.
+func globListMatches(patterns []string, value string) bool {
+	if patterns == nil {
+		return true
+	}
+	for _, p := range patterns {
+		if GlobMatch(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func BenchmarkEvaluate500(b *testing.B) {
+	engine := NewPolicyEngine(largePolicySet(500))
+	ctx := EvalContext{Tool: "tool-499", Mode: "interactive", Model: "gpt-5", Risk: "low"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.Evaluate(ctx)
+	}
+}
+
+// BenchmarkEvaluateSizes measures Evaluate against small, medium and
+// large policy sets, for a context matching the last policy, one matching
+// none and one walking a context fallback.
+func BenchmarkEvaluateSizes(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		ps := largePolicySet(n)
+		ps.ContextFallbacks = map[string]string{"auto": "interactive", "interactive": "auto"}
+		engine := NewPolicyEngine(ps)
+		last := fmt.Sprintf("tool-%d", n-1)
+		for _, bc := range []struct {
+			name string
+			ctx  EvalContext
+		}{
+			{"match", EvalContext{Tool: last, Mode: "interactive", Model: "gpt-5", Risk: "low"}},
+			{"default", EvalContext{Tool: "unknown", Mode: "interactive", Model: "gpt-5", Risk: "low"}},
+			{"fallback", EvalContext{Tool: "unknown", Mode: "auto", Model: "gpt-5", Risk: "low"}},
+		} {
+			b.Run(fmt.Sprintf("%d/%s", n, bc.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					engine.Evaluate(bc.ctx)
+				}
+			})
+		}
+	}
+}
+
+// TestEvaluateDoesNotAllocate guards the zero-allocation hot path: no
+// hooks, observers or audit sinks, with or without context fallbacks or
+// several Tools.
+func TestEvaluateDoesNotAllocate(t *testing.T) {
+	ps := largePolicySet(100)
+	ps.Defaults.PerMode = map[string]Effect{"auto": EffectDeny}
+	ps.ContextFallbacks = map[string]string{"auto": "interactive", "interactive": "auto"}
+	withFallbacks := NewPolicyEngine(ps)
+	without := NewPolicyEngine(largePolicySet(100))
+	indexed := NewPolicyEngine(realisticPolicySet())
+	for _, tc := range []struct {
+		name   string
+		engine *PolicyEngine
+		ctx    EvalContext
+	}{
+		{"match", without, EvalContext{Tool: "tool-99", Mode: "interactive", Risk: "high"}},
+		{"default", without, EvalContext{Tool: "unknown", Mode: "interactive"}},
+		{"fallback match", withFallbacks, EvalContext{Tool: "tool-0", Mode: "auto"}},
+		{"fallback default", withFallbacks, EvalContext{Tool: "unknown", Mode: "auto"}},
+		{"tools", indexed, EvalContext{Tools: []string{"tool-399", "tool-7", "tool-42"}, Mode: "interactive"}},
+	} {
+		if n := testing.AllocsPerRun(100, func() { tc.engine.Evaluate(tc.ctx) }); n != 0 {
+			t.Errorf("%s: %v allocations per Evaluate, want 0", tc.name, n)
+		}
+	}
+}
+
+func BenchmarkEvaluate500Uncompiled(b *testing.B) {
+	policies := largePolicySet(500).Policies
+	ctx := EvalContext{Tool: "tool-499", Mode: "interactive", Model: "gpt-5", Risk: "low"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range policies {
+			c := p.Condition
+			if globListMatches(c.Modes, ctx.Mode) && globListMatches(c.Models, ctx.Model) &&
+				globListMatches(c.Tools, ctx.Tool) && globListMatches(c.Risk, ctx.Risk) {
+				break
+			}
+		}
+	}
+}
+
 // ── Example file tests ──────────────────────────────────────────────────
 
 func TestExamplePermissive(t *testing.T) {
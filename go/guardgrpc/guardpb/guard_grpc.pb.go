@@ -0,0 +1,224 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v5.27.1
+// source: guard.proto
+
+package guardpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Guard_Evaluate_FullMethodName       = "/agentpolicy.guard.v1.Guard/Evaluate"
+	Guard_EvaluateAll_FullMethodName    = "/agentpolicy.guard.v1.Guard/EvaluateAll"
+	Guard_EvaluateStream_FullMethodName = "/agentpolicy.guard.v1.Guard/EvaluateStream"
+)
+
+// GuardClient is the client API for Guard service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GuardClient interface {
+	// Evaluate returns the verdict for a single context.
+	Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error)
+	// EvaluateAll returns the match result of every policy for a context.
+	EvaluateAll(ctx context.Context, in *EvaluateAllRequest, opts ...grpc.CallOption) (*EvaluateAllResponse, error)
+	// EvaluateStream evaluates a stream of contexts, replying to each request
+	// in order. Intended for bulk replay of recorded traffic.
+	EvaluateStream(ctx context.Context, opts ...grpc.CallOption) (Guard_EvaluateStreamClient, error)
+}
+
+type guardClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGuardClient(cc grpc.ClientConnInterface) GuardClient {
+	return &guardClient{cc}
+}
+
+func (c *guardClient) Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error) {
+	out := new(EvaluateResponse)
+	err := c.cc.Invoke(ctx, Guard_Evaluate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guardClient) EvaluateAll(ctx context.Context, in *EvaluateAllRequest, opts ...grpc.CallOption) (*EvaluateAllResponse, error) {
+	out := new(EvaluateAllResponse)
+	err := c.cc.Invoke(ctx, Guard_EvaluateAll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guardClient) EvaluateStream(ctx context.Context, opts ...grpc.CallOption) (Guard_EvaluateStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Guard_ServiceDesc.Streams[0], Guard_EvaluateStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &guardEvaluateStreamClient{stream}
+	return x, nil
+}
+
+type Guard_EvaluateStreamClient interface {
+	Send(*EvaluateRequest) error
+	Recv() (*EvaluateResponse, error)
+	grpc.ClientStream
+}
+
+type guardEvaluateStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *guardEvaluateStreamClient) Send(m *EvaluateRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *guardEvaluateStreamClient) Recv() (*EvaluateResponse, error) {
+	m := new(EvaluateResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GuardServer is the server API for Guard service.
+// All implementations must embed UnimplementedGuardServer
+// for forward compatibility
+type GuardServer interface {
+	// Evaluate returns the verdict for a single context.
+	Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error)
+	// EvaluateAll returns the match result of every policy for a context.
+	EvaluateAll(context.Context, *EvaluateAllRequest) (*EvaluateAllResponse, error)
+	// EvaluateStream evaluates a stream of contexts, replying to each request
+	// in order. Intended for bulk replay of recorded traffic.
+	EvaluateStream(Guard_EvaluateStreamServer) error
+	mustEmbedUnimplementedGuardServer()
+}
+
+// UnimplementedGuardServer must be embedded to have forward compatible implementations.
+type UnimplementedGuardServer struct {
+}
+
+func (UnimplementedGuardServer) Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Evaluate not implemented")
+}
+func (UnimplementedGuardServer) EvaluateAll(context.Context, *EvaluateAllRequest) (*EvaluateAllResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvaluateAll not implemented")
+}
+func (UnimplementedGuardServer) EvaluateStream(Guard_EvaluateStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method EvaluateStream not implemented")
+}
+func (UnimplementedGuardServer) mustEmbedUnimplementedGuardServer() {}
+
+// UnsafeGuardServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GuardServer will
+// result in compilation errors.
+type UnsafeGuardServer interface {
+	mustEmbedUnimplementedGuardServer()
+}
+
+func RegisterGuardServer(s grpc.ServiceRegistrar, srv GuardServer) {
+	s.RegisterService(&Guard_ServiceDesc, srv)
+}
+
+func _Guard_Evaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuardServer).Evaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Guard_Evaluate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuardServer).Evaluate(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Guard_EvaluateAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuardServer).EvaluateAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Guard_EvaluateAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuardServer).EvaluateAll(ctx, req.(*EvaluateAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Guard_EvaluateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GuardServer).EvaluateStream(&guardEvaluateStreamServer{stream})
+}
+
+type Guard_EvaluateStreamServer interface {
+	Send(*EvaluateResponse) error
+	Recv() (*EvaluateRequest, error)
+	grpc.ServerStream
+}
+
+type guardEvaluateStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *guardEvaluateStreamServer) Send(m *EvaluateResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *guardEvaluateStreamServer) Recv() (*EvaluateRequest, error) {
+	m := new(EvaluateRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Guard_ServiceDesc is the grpc.ServiceDesc for Guard service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Guard_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "agentpolicy.guard.v1.Guard",
+	HandlerType: (*GuardServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Evaluate",
+			Handler:    _Guard_Evaluate_Handler,
+		},
+		{
+			MethodName: "EvaluateAll",
+			Handler:    _Guard_EvaluateAll_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "EvaluateStream",
+			Handler:       _Guard_EvaluateStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "guard.proto",
+}
@@ -0,0 +1,216 @@
+// Package guardgrpc serves a guard.PolicyEngine over gRPC. It lives in its
+// own package so that the core engine carries no protobuf dependencies.
+//
+// Register the server with a grpc.Server:
+//
+//	s := grpc.NewServer()
+//	guardpb.RegisterGuardServer(s, guardgrpc.NewGRPCServer(engine))
+package guardgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative guardpb/guard.proto
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc/status"
+
+	guard "github.com/agent-policy/guard"
+	"github.com/agent-policy/guard/guardgrpc/guardpb"
+)
+
+// Server implements guardpb.GuardServer on top of a PolicyEngine.
+type Server struct {
+	guardpb.UnimplementedGuardServer
+	engine *guard.PolicyEngine
+}
+
+// NewGRPCServer returns a gRPC service implementation backed by engine.
+// Policy reloads on engine are picked up by subsequent calls.
+func NewGRPCServer(engine *guard.PolicyEngine) *Server {
+	return &Server{engine: engine}
+}
+
+// Evaluate returns the verdict for a single context.
+func (s *Server) Evaluate(ctx context.Context, req *guardpb.EvaluateRequest) (*guardpb.EvaluateResponse, error) {
+	v, err := s.engine.EvaluateCtx(ctx, FromProtoContext(req.GetContext()))
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return &guardpb.EvaluateResponse{Verdict: ToProtoVerdict(v)}, nil
+}
+
+// EvaluateAll returns the match result of every policy for a context.
+func (s *Server) EvaluateAll(ctx context.Context, req *guardpb.EvaluateAllRequest) (*guardpb.EvaluateAllResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	results := s.engine.EvaluateAll(FromProtoContext(req.GetContext()))
+	out := make([]*guardpb.MatchResult, len(results))
+	for i, r := range results {
+		out[i] = ToProtoMatchResult(r)
+	}
+	return &guardpb.EvaluateAllResponse{Results: out}, nil
+}
+
+// EvaluateStream answers each request on the stream with its verdict, in
+// order, until the client closes its side of the stream.
+func (s *Server) EvaluateStream(stream guardpb.Guard_EvaluateStreamServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		v, err := s.engine.EvaluateCtx(ctx, FromProtoContext(req.GetContext()))
+		if err != nil {
+			return status.FromContextError(err).Err()
+		}
+		if err := stream.Send(&guardpb.EvaluateResponse{Verdict: ToProtoVerdict(v)}); err != nil {
+			return err
+		}
+	}
+}
+
+// ── Conversions ────────────────────────────────────────────────────────
+
+// FromProtoContext converts a proto EvalContext. A nil message yields the
+// zero EvalContext.
+func FromProtoContext(pc *guardpb.EvalContext) guard.EvalContext {
+	if pc == nil {
+		return guard.EvalContext{}
+	}
+	return guard.EvalContext{
+		Mode:              pc.GetMode(),
+		Model:             pc.GetModel(),
+		Channel:           pc.GetChannel(),
+		Tool:              pc.GetTool(),
+		McpServer:         pc.GetMcpServer(),
+		McpMethod:         pc.GetMcpMethod(),
+		Risk:              pc.GetRisk(),
+		User:              pc.GetUser(),
+		Session:           pc.GetSession(),
+		Args:              pc.GetArgs(),
+		SourceIP:          pc.GetSourceIp(),
+		Tags:              pc.GetTags(),
+		EstimatedCostUSD:  pc.GetEstimatedCostUsd(),
+		TokenCount:        int(pc.GetTokenCount()),
+		Agent:             pc.GetAgent(),
+		RiskScore:         int(pc.GetRiskScore()),
+		SessionAgeSeconds: int(pc.GetSessionAgeSeconds()),
+		Tenant:            pc.GetTenant(),
+		ReadOnly:          pc.ReadOnly,
+		Tools:             pc.GetTools(),
+		Risks:             pc.GetRisks(),
+		Authenticated:     pc.GetAuthenticated(),
+	}
+}
+
+// ToProtoContext converts an EvalContext to its proto form.
+func ToProtoContext(ec guard.EvalContext) *guardpb.EvalContext {
+	return &guardpb.EvalContext{
+		Mode:              ec.Mode,
+		Model:             ec.Model,
+		Channel:           ec.Channel,
+		Tool:              ec.Tool,
+		McpServer:         ec.McpServer,
+		McpMethod:         ec.McpMethod,
+		Risk:              ec.Risk,
+		User:              ec.User,
+		Session:           ec.Session,
+		Args:              ec.Args,
+		SourceIp:          ec.SourceIP,
+		Tags:              ec.Tags,
+		EstimatedCostUsd:  ec.EstimatedCostUSD,
+		TokenCount:        int64(ec.TokenCount),
+		Agent:             ec.Agent,
+		RiskScore:         int64(ec.RiskScore),
+		SessionAgeSeconds: int64(ec.SessionAgeSeconds),
+		Tenant:            ec.Tenant,
+		ReadOnly:          ec.ReadOnly,
+		Tools:             ec.Tools,
+		Risks:             ec.Risks,
+		Authenticated:     ec.Authenticated,
+	}
+}
+
+// ToProtoVerdict converts a Verdict to its proto form.
+func ToProtoVerdict(v guard.Verdict) *guardpb.Verdict {
+	return &guardpb.Verdict{
+		Effect:         string(v.Effect),
+		Channel:        string(v.Channel),
+		PolicyId:       v.PolicyID,
+		Reason:         v.Reason,
+		Code:           v.Code,
+		Obligations:    v.Obligations,
+		Source:         string(v.Source),
+		RequireReason:  v.RequireReason,
+		ChannelChain:   channelStrings(v.ChannelChain),
+		DryRunPolicyId: v.DryRunPolicyID,
+		DryRunEffect:   string(v.DryRunEffect),
+		Annotations:    v.Annotations,
+	}
+}
+
+// FromProtoVerdict converts a proto Verdict. A nil message yields the zero
+// Verdict.
+func FromProtoVerdict(pv *guardpb.Verdict) guard.Verdict {
+	if pv == nil {
+		return guard.Verdict{}
+	}
+	return guard.Verdict{
+		Effect:         guard.Effect(pv.GetEffect()),
+		Channel:        guard.Channel(pv.GetChannel()),
+		PolicyID:       pv.GetPolicyId(),
+		Reason:         pv.GetReason(),
+		Code:           pv.GetCode(),
+		Obligations:    pv.GetObligations(),
+		Source:         guard.VerdictSource(pv.GetSource()),
+		RequireReason:  pv.GetRequireReason(),
+		ChannelChain:   protoChannels(pv.GetChannelChain()),
+		DryRunPolicyID: pv.GetDryRunPolicyId(),
+		DryRunEffect:   guard.Effect(pv.GetDryRunEffect()),
+		Annotations:    pv.GetAnnotations(),
+	}
+}
+
+func channelStrings(chain []guard.Channel) []string {
+	if len(chain) == 0 {
+		return nil
+	}
+	out := make([]string, len(chain))
+	for i, c := range chain {
+		out[i] = string(c)
+	}
+	return out
+}
+
+func protoChannels(chain []string) []guard.Channel {
+	if len(chain) == 0 {
+		return nil
+	}
+	out := make([]guard.Channel, len(chain))
+	for i, c := range chain {
+		out[i] = guard.Channel(c)
+	}
+	return out
+}
+
+// ToProtoMatchResult converts a MatchResult to its proto form.
+func ToProtoMatchResult(r guard.MatchResult) *guardpb.MatchResult {
+	return &guardpb.MatchResult{
+		PolicyId: r.PolicyID,
+		Name:     r.Name,
+		Priority: int64(r.Priority),
+		Effect:   string(r.Effect),
+		Matched:  r.Matched,
+		Enabled:  r.Enabled,
+		Expired:  r.Expired,
+		Pending:  r.Pending,
+		Winner:   r.Winner,
+	}
+}
@@ -0,0 +1,199 @@
+package guardgrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	guard "github.com/agent-policy/guard"
+	"github.com/agent-policy/guard/guardgrpc/guardpb"
+)
+
+const testPolicies = `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: grpc-test
+defaults:
+  effect: ask
+policies:
+  - id: deny-shell
+    effect: deny
+    priority: 1
+    message: shell is disabled
+    code: GUARD_SHELL_DISABLED
+    condition:
+      tools: ["shell"]
+  - id: allow-read
+    effect: allow
+    priority: 2
+    obligations:
+      log: "true"
+    condition:
+      tools: ["read_*"]
+`
+
+func newTestClient(t *testing.T) guardpb.GuardClient {
+	t.Helper()
+	ps, err := guard.LoadPolicySetFromBytes([]byte(testPolicies))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	guardpb.RegisterGuardServer(s, NewGRPCServer(guard.NewPolicyEngine(ps)))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return guardpb.NewGuardClient(conn)
+}
+
+func TestEvaluate(t *testing.T) {
+	client := newTestClient(t)
+	resp, err := client.Evaluate(context.Background(), &guardpb.EvaluateRequest{
+		Context: &guardpb.EvalContext{Tool: "shell"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := FromProtoVerdict(resp.GetVerdict())
+	if v.Effect != guard.EffectDeny || v.PolicyID != "deny-shell" || v.Reason != "shell is disabled" || v.Code != "GUARD_SHELL_DISABLED" || v.Source != guard.SourceMatched {
+		t.Errorf("got %+v", v)
+	}
+
+	resp, err = client.Evaluate(context.Background(), &guardpb.EvaluateRequest{
+		Context: &guardpb.EvalContext{Tool: "read_file"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.GetVerdict().GetObligations()["log"]; got != "true" {
+		t.Errorf("obligation log = %q, want true", got)
+	}
+}
+
+func TestEvaluateNilContextUsesDefaults(t *testing.T) {
+	client := newTestClient(t)
+	resp, err := client.Evaluate(context.Background(), &guardpb.EvaluateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := resp.GetVerdict(); v.GetEffect() != "ask" || v.GetPolicyId() != "" || v.GetSource() != "default" {
+		t.Errorf("got %v, want default ask", v)
+	}
+}
+
+func TestEvaluateCanceled(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.Evaluate(ctx, &guardpb.EvaluateRequest{Context: &guardpb.EvalContext{Tool: "shell"}})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("code = %v, want Canceled", status.Code(err))
+	}
+}
+
+func TestEvaluateAll(t *testing.T) {
+	client := newTestClient(t)
+	resp, err := client.EvaluateAll(context.Background(), &guardpb.EvaluateAllRequest{
+		Context: &guardpb.EvalContext{Tool: "shell"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := resp.GetResults()
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if r := results[0]; r.GetPolicyId() != "deny-shell" || !r.GetMatched() || r.GetPriority() != 1 {
+		t.Errorf("results[0] = %v", r)
+	}
+	if r := results[1]; r.GetPolicyId() != "allow-read" || r.GetMatched() || !r.GetEnabled() {
+		t.Errorf("results[1] = %v", r)
+	}
+}
+
+func TestEvaluateStream(t *testing.T) {
+	client := newTestClient(t)
+	stream, err := client.EvaluateStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := []string{"shell", "read_file", "write_file"}
+	want := []string{"deny", "allow", "ask"}
+	for _, tool := range tools {
+		if err := stream.Send(&guardpb.EvaluateRequest{Context: &guardpb.EvalContext{Tool: tool}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range want {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv %d: %v", i, err)
+		}
+		if got := resp.GetVerdict().GetEffect(); got != w {
+			t.Errorf("verdict %d (%s) = %s, want %s", i, tools[i], got, w)
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("final recv = %v, want io.EOF", err)
+	}
+}
+
+func TestContextRoundTrip(t *testing.T) {
+	readOnly := true
+	ec := guard.EvalContext{
+		Mode: "auto", Model: "gpt-5", Channel: "chat", Tool: "shell",
+		McpServer: "fs", Risk: "high", User: "u1", Session: "s1",
+		Args: map[string]string{"path": "/tmp"}, SourceIP: "10.0.0.1", Agent: "planner",
+		McpMethod: "tools/call",
+		RiskScore: 64, SessionAgeSeconds: 3600, Tenant: "acme", ReadOnly: &readOnly,
+		Tools: []string{"shell", "view"}, Risks: []string{"high", "pii"}, Authenticated: true,
+	}
+	got := FromProtoContext(ToProtoContext(ec))
+	if got.Mode != ec.Mode || got.Model != ec.Model || got.Channel != ec.Channel ||
+		got.Tool != ec.Tool || got.McpServer != ec.McpServer || got.Risk != ec.Risk ||
+		got.User != ec.User || got.Session != ec.Session || got.SourceIP != ec.SourceIP ||
+		got.Args["path"] != "/tmp" || got.Agent != ec.Agent || got.McpMethod != ec.McpMethod ||
+		got.RiskScore != ec.RiskScore || got.SessionAgeSeconds != ec.SessionAgeSeconds ||
+		got.Tenant != ec.Tenant || got.ReadOnly == nil || *got.ReadOnly != readOnly ||
+		!reflect.DeepEqual(got.Tools, ec.Tools) || !reflect.DeepEqual(got.Risks, ec.Risks) ||
+		got.Authenticated != ec.Authenticated {
+		t.Errorf("round trip = %+v, want %+v", got, ec)
+	}
+}
+
+func TestVerdictRoundTrip(t *testing.T) {
+	v := guard.Verdict{
+		Effect: guard.EffectAsk, Channel: guard.ChannelPhone, PolicyID: "p1",
+		Reason: "call first", Code: "GUARD_CALL", Obligations: map[string]string{"log": "true"},
+		Source: guard.SourceMatched, RequireReason: true,
+		ChannelChain:   []guard.Channel{guard.ChannelPhone, guard.ChannelChat},
+		DryRunPolicyID: "p0", DryRunEffect: guard.EffectDeny,
+		Annotations: map[string]string{"owner": "payments"},
+	}
+	if got := FromProtoVerdict(ToProtoVerdict(v)); !reflect.DeepEqual(got, v) {
+		t.Errorf("round trip = %+v, want %+v", got, v)
+	}
+}
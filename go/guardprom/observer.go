@@ -0,0 +1,58 @@
+// Package guardprom exports guard evaluation metrics to Prometheus.
+//
+//	obs, err := guardprom.NewObserver(prometheus.DefaultRegisterer)
+//	if err != nil {
+//		return err
+//	}
+//	engine.SetObserver(obs)
+package guardprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	guard "github.com/agent-policy/guard"
+)
+
+// Observer is a guard.Observer that records verdict counts and evaluation
+// latency.
+type Observer struct {
+	verdicts *prometheus.CounterVec
+	latency  prometheus.Histogram
+}
+
+var _ guard.Observer = (*Observer)(nil)
+
+// NewObserver creates an Observer and registers its collectors with reg:
+//
+//	guard_verdicts_total{effect, policy_id}    verdicts by effect and winning
+//	                                           policy ("" for the default)
+//	guard_evaluation_duration_seconds          evaluation latency
+func NewObserver(reg prometheus.Registerer) (*Observer, error) {
+	o := &Observer{
+		verdicts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "guard_verdicts_total",
+			Help: "Policy verdicts by effect and winning policy ID.",
+		}, []string{"effect", "policy_id"}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "guard_evaluation_duration_seconds",
+			Help:    "Time taken to evaluate a single context.",
+			Buckets: prometheus.ExponentialBuckets(1e-6, 4, 10),
+		}),
+	}
+	if err := reg.Register(o.verdicts); err != nil {
+		return nil, err
+	}
+	if err := reg.Register(o.latency); err != nil {
+		reg.Unregister(o.verdicts)
+		return nil, err
+	}
+	return o, nil
+}
+
+// OnVerdict implements guard.Observer.
+func (o *Observer) OnVerdict(_ guard.EvalContext, v guard.Verdict, dur time.Duration) {
+	o.verdicts.WithLabelValues(string(v.Effect), v.PolicyID).Inc()
+	o.latency.Observe(dur.Seconds())
+}
@@ -0,0 +1,49 @@
+package guardprom
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	guard "github.com/agent-policy/guard"
+)
+
+func TestObserverCountsVerdicts(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	obs, err := NewObserver(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := guard.NewPolicyEngine(&guard.PolicySet{
+		Defaults: guard.Defaults{Effect: guard.EffectAsk, Channel: guard.ChannelChat},
+		Policies: []guard.Policy{
+			{ID: "deny-bash", Effect: guard.EffectDeny, Condition: guard.Condition{Tools: []string{"bash"}}},
+		},
+	})
+	engine.SetObserver(obs)
+
+	engine.Evaluate(guard.EvalContext{Tool: "bash"})
+	engine.Evaluate(guard.EvalContext{Tool: "bash"})
+	engine.Evaluate(guard.EvalContext{Tool: "view"})
+
+	if got := testutil.ToFloat64(obs.verdicts.WithLabelValues("deny", "deny-bash")); got != 2 {
+		t.Errorf("deny/deny-bash = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(obs.verdicts.WithLabelValues("ask", "")); got != 1 {
+		t.Errorf("ask/default = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(obs.latency); n != 1 {
+		t.Errorf("latency collectors = %d, want 1", n)
+	}
+}
+
+func TestNewObserverDuplicateRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := NewObserver(reg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewObserver(reg); err == nil {
+		t.Error("expected error registering twice")
+	}
+}
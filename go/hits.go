@@ -0,0 +1,59 @@
+package guard
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ── Hit counts ─────────────────────────────────────────────────────────
+
+// hitRegistry holds one win counter per policy ID. Counters outlive a
+// single Load, so reloading a policy set does not lose its counts.
+type hitRegistry struct {
+	mu   sync.Mutex
+	byID map[string]*atomic.Uint64
+}
+
+// counters returns the counters for policies, in order, creating any that
+// do not exist yet.
+func (r *hitRegistry) counters(policies []Policy) []*atomic.Uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.byID == nil {
+		r.byID = make(map[string]*atomic.Uint64)
+	}
+	out := make([]*atomic.Uint64, len(policies))
+	for i := range policies {
+		c, ok := r.byID[policies[i].ID]
+		if !ok {
+			c = new(atomic.Uint64)
+			r.byID[policies[i].ID] = c
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// HitCounts reports how many evaluations each loaded policy has won since
+// the engine was created or ResetHitCounts was last called, keyed by
+// policy ID. Policies that never won are included with a count of zero,
+// which makes the result suitable for finding dead rules. Counts survive
+// Load for policies whose ID is unchanged.
+func (e *PolicyEngine) HitCounts() map[string]uint64 {
+	st := e.state.Load()
+	out := make(map[string]uint64, len(st.policies))
+	for i := range st.policies {
+		out[st.policies[i].ID] = st.hits[i].Load()
+	}
+	return out
+}
+
+// ResetHitCounts sets every policy's hit count back to zero, e.g. at the
+// start of a sampling window.
+func (e *PolicyEngine) ResetHitCounts() {
+	e.hits.mu.Lock()
+	defer e.hits.mu.Unlock()
+	for _, c := range e.hits.byID {
+		c.Store(0)
+	}
+}
@@ -0,0 +1,86 @@
+package guard
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func hitPolicySet() *PolicySet {
+	ps := makePolicySet([]Policy{
+		{ID: "allow-bash", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "ask-shell", Effect: EffectAsk, Priority: 20, Condition: Condition{Tools: []string{"bash", "sh"}}},
+		{ID: "deny-rm-safe", Effect: EffectDeny, Priority: 30, Condition: Condition{Tools: []string{"rm"}, Modes: []string{"safe"}}},
+		{ID: "dead", Effect: EffectDeny, Priority: 40, Condition: Condition{Tools: []string{"never"}, Modes: []string{"none"}}},
+	}, EffectAsk)
+	ps.ContextFallbacks = map[string]string{"auto": "safe"}
+	return ps
+}
+
+func TestHitCountsOnlyCountWinners(t *testing.T) {
+	engine := NewPolicyEngine(hitPolicySet())
+	engine.Evaluate(EvalContext{Tool: "bash"}) // ask-shell matches too but loses
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	engine.Evaluate(EvalContext{Tool: "sh"})
+	engine.Evaluate(EvalContext{Tool: "rm", Mode: "auto"}) // via fallback
+	engine.EvaluateDetailed(EvalContext{Tool: "rm", Mode: "safe"})
+	engine.Evaluate(EvalContext{Tool: "view"}) // default
+	engine.EvaluateAll(EvalContext{Tool: "bash"})
+
+	want := map[string]uint64{"allow-bash": 2, "ask-shell": 1, "deny-rm-safe": 2, "dead": 0}
+	if got := engine.HitCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestResetHitCounts(t *testing.T) {
+	engine := NewPolicyEngine(hitPolicySet())
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	engine.ResetHitCounts()
+	engine.Evaluate(EvalContext{Tool: "sh"})
+
+	got := engine.HitCounts()
+	if got["allow-bash"] != 0 || got["ask-shell"] != 1 {
+		t.Errorf("after reset: %v", got)
+	}
+}
+
+func TestHitCountsSurviveLoad(t *testing.T) {
+	engine := NewPolicyEngine(hitPolicySet())
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	engine.Load(makePolicySet([]Policy{
+		{ID: "allow-bash", Effect: EffectAllow, Priority: 5, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "new", Effect: EffectDeny, Condition: Condition{Tools: []string{"rm"}}},
+	}, EffectAsk))
+	engine.Evaluate(EvalContext{Tool: "bash"})
+
+	want := map[string]uint64{"allow-bash": 2, "new": 0}
+	if got := engine.HitCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestHitCountsConcurrent(t *testing.T) {
+	engine := NewPolicyEngine(hitPolicySet())
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				engine.Evaluate(EvalContext{Tool: "bash"})
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			engine.HitCounts()
+		}
+	}()
+	wg.Wait()
+	if got := engine.HitCounts()["allow-bash"]; got != 800 {
+		t.Errorf("expected 800 hits, got %d", got)
+	}
+}
@@ -0,0 +1,115 @@
+package guard
+
+import "slices"
+
+// ── Hooks ──────────────────────────────────────────────────────────────
+
+// PreHook adjusts a context before it is matched, e.g. to lowercase tool
+// names or map aliases. It receives a copy: changes, including to Args
+// and Tags, are not visible to the caller.
+type PreHook func(ctx *EvalContext)
+
+// PostHook adjusts a verdict before it is returned, e.g. to upgrade the
+// channel. ctx is the context after pre-hooks and risk scoring; it must
+// not be modified.
+type PostHook func(ctx EvalContext, v *Verdict)
+
+// hookSet is an immutable snapshot of the registered hooks, replaced
+// wholesale on every Add so evaluations never lock.
+type hookSet struct {
+	pre  []PreHook
+	post []PostHook
+}
+
+// AddPreHook registers h to run before matching on every evaluation,
+// including EvaluateAll, EvaluateMatched, EvaluateDetailed and
+// EvaluateBatch. Pre-hooks run in registration order, before the risk
+// scorer and group resolver. Hooks must be safe for concurrent use.
+func (e *PolicyEngine) AddPreHook(h PreHook) {
+	e.hookMu.Lock()
+	defer e.hookMu.Unlock()
+	next := &hookSet{}
+	if cur := e.hooks.Load(); cur != nil {
+		*next = *cur
+	}
+	next.pre = append(next.pre[:len(next.pre):len(next.pre)], h)
+	e.hooks.Store(next)
+}
+
+// AddPostHook registers h to run on every verdict returned by Evaluate,
+// EvaluateCtx, EvaluateDetailed and EvaluateBatch. Post-hooks run in
+// registration order, after the decision cache and before observers and
+// audit sinks are notified, so those see the adjusted verdict. Hooks must
+// be safe for concurrent use.
+func (e *PolicyEngine) AddPostHook(h PostHook) {
+	e.hookMu.Lock()
+	defer e.hookMu.Unlock()
+	next := &hookSet{}
+	if cur := e.hooks.Load(); cur != nil {
+		*next = *cur
+	}
+	next.post = append(next.post[:len(next.post):len(next.post)], h)
+	e.hooks.Store(next)
+}
+
+// prepare returns ec as matched: after pre-hooks, tool alias resolution,
+// risk scoring and group resolution.
+func (e *PolicyEngine) prepare(st *engineState, ec EvalContext) EvalContext {
+	if hs := e.hooks.Load(); hs != nil && len(hs.pre) > 0 {
+		ec = runPreHooks(hs.pre, ec)
+	}
+	if tool, ok := st.aliases[ec.Tool]; ok {
+		ec.Tool = tool
+	}
+	ec.Tools = resolveAliases(st.aliases, ec.Tools)
+	if ec.Tenant == "" {
+		ec.Tenant = e.tenant
+	}
+	return e.resolveGroups(st, e.scoreRisk(ec))
+}
+
+// resolveAliases returns tools with every alias replaced by the tool it
+// names, copying the slice only if one is.
+func resolveAliases(aliases map[string]string, tools []string) []string {
+	for i, t := range tools {
+		if _, ok := aliases[t]; !ok {
+			continue
+		}
+		out := slices.Clone(tools)
+		for j := i; j < len(out); j++ {
+			if tool, ok := aliases[out[j]]; ok {
+				out[j] = tool
+			}
+		}
+		return out
+	}
+	return tools
+}
+
+// runPreHooks returns ec after every pre-hook has adjusted a copy of it.
+// It is kept apart from prepare so that only evaluations with hooks pay
+// for the heap copy the hooks' pointer requires.
+func runPreHooks(hooks []PreHook, ec EvalContext) EvalContext {
+	ec.Args = copyStringMap(ec.Args)
+	ec.Tags = copyStringMap(ec.Tags)
+	for _, h := range hooks {
+		h(&ec)
+	}
+	return ec
+}
+
+// runPostHooks returns v after every post-hook has adjusted it.
+func (e *PolicyEngine) runPostHooks(ec EvalContext, v Verdict) Verdict {
+	if hs := e.hooks.Load(); hs != nil && len(hs.post) > 0 {
+		return applyPostHooks(hs.post, ec, v)
+	}
+	return v
+}
+
+// applyPostHooks is the slow path of runPostHooks; see runPreHooks.
+func applyPostHooks(hooks []PostHook, ec EvalContext, v Verdict) Verdict {
+	for _, h := range hooks {
+		h(ec, &v)
+	}
+	return v
+}
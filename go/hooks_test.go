@@ -0,0 +1,90 @@
+package guard
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func hookPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "ask-rm", Effect: EffectAsk, Priority: 20, Condition: Condition{
+			Tools: []string{"rm"}, Args: map[string][]string{"path": {"/tmp/*"}},
+		}},
+	}, EffectAllow)
+}
+
+func TestPreHookNormalizes(t *testing.T) {
+	engine := NewPolicyEngine(hookPolicySet())
+	aliases := map[string]string{"shell": "bash", "delete": "rm"}
+	engine.AddPreHook(func(ctx *EvalContext) {
+		ctx.Tool = strings.ToLower(ctx.Tool)
+	})
+	engine.AddPreHook(func(ctx *EvalContext) {
+		if name, ok := aliases[ctx.Tool]; ok {
+			ctx.Tool = name
+		}
+		if p, ok := ctx.Args["path"]; ok {
+			ctx.Args["path"] = strings.TrimSuffix(p, "/")
+		}
+	})
+
+	for _, tool := range []string{"BASH", "Shell", "bash"} {
+		if v := engine.Evaluate(EvalContext{Tool: tool}); v.PolicyID != "deny-bash" {
+			t.Errorf("%s: expected deny-bash, got %q", tool, v.PolicyID)
+		}
+	}
+
+	args := map[string]string{"path": "/tmp/x/"}
+	ctx := EvalContext{Tool: "DELETE", Args: args}
+	if v := engine.Evaluate(ctx); v.PolicyID != "ask-rm" {
+		t.Errorf("expected ask-rm, got %q", v.PolicyID)
+	}
+	if ctx.Tool != "DELETE" || args["path"] != "/tmp/x/" {
+		t.Errorf("pre-hooks modified the caller's context: %+v", ctx)
+	}
+
+	if r := engine.EvaluateMatched(EvalContext{Tool: "Shell"}); len(r) != 1 || r[0].PolicyID != "deny-bash" {
+		t.Errorf("EvaluateMatched ignored pre-hooks: %+v", r)
+	}
+	if v := engine.EvaluateBatch([]EvalContext{{Tool: "SHELL"}})[0]; v.PolicyID != "deny-bash" {
+		t.Errorf("EvaluateBatch ignored pre-hooks: %+v", v)
+	}
+}
+
+func TestHooksRunInOrder(t *testing.T) {
+	engine := NewPolicyEngine(hookPolicySet())
+	var order []string
+	engine.AddPreHook(func(*EvalContext) { order = append(order, "pre1") })
+	engine.AddPostHook(func(EvalContext, *Verdict) { order = append(order, "post1") })
+	engine.AddPreHook(func(*EvalContext) { order = append(order, "pre2") })
+	engine.AddPostHook(func(EvalContext, *Verdict) { order = append(order, "post2") })
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	if want := []string{"pre1", "pre2", "post1", "post2"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
+
+func TestPostHookAdjustsVerdict(t *testing.T) {
+	engine := NewPolicyEngine(hookPolicySet())
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+	engine.AddPostHook(func(ctx EvalContext, v *Verdict) {
+		if v.Effect == EffectAsk && ctx.Args["path"] == "/tmp/prod" {
+			v.Channel = ChannelPhone
+		}
+	})
+
+	v, _ := engine.EvaluateDetailed(EvalContext{Tool: "rm", Args: map[string]string{"path": "/tmp/prod"}})
+	if v.Channel != ChannelPhone {
+		t.Errorf("expected the channel upgraded to phone, got %s", v.Channel)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "rm", Args: map[string]string{"path": "/tmp/dev"}}); v.Channel == ChannelPhone {
+		t.Error("expected the channel left alone outside /tmp/prod")
+	}
+	entries := sink.entries
+	if len(entries) != 2 || entries[0].Verdict.Channel != ChannelPhone {
+		t.Errorf("audit sink should see the adjusted verdict: %+v", entries)
+	}
+}
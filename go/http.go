@@ -0,0 +1,102 @@
+package guard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ── HTTP ───────────────────────────────────────────────────────────────
+
+// maxRequestBytes bounds the size of an evaluation request body.
+const maxRequestBytes = 1 << 20
+
+// ExplainResponse is returned by the evaluate endpoint when explain=true.
+type ExplainResponse struct {
+	Verdict Verdict       `json:"verdict"`
+	Trace   []MatchResult `json:"trace"`
+}
+
+// NewHTTPHandler returns an http.Handler that serves the engine remotely:
+//
+//	POST /evaluate   JSON EvalContext in, JSON Verdict out. With
+//	                 ?explain=true the response is an ExplainResponse that
+//	                 includes the match result of every policy, computed
+//	                 in the same pass as the verdict.
+//	GET  /healthz    200 once a policy set is loaded, 503 before.
+//
+// Malformed or unknown-field request bodies are rejected with 400.
+func NewHTTPHandler(engine *PolicyEngine) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/evaluate", func(w http.ResponseWriter, r *http.Request) {
+		handleEvaluate(engine, w, r)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		handleHealth(engine, w, r)
+	})
+	return mux
+}
+
+func handleEvaluate(engine *PolicyEngine, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	var ec EvalContext
+	if err := dec.Decode(&ec); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: trailing data after JSON object")
+		return
+	}
+
+	if r.URL.Query().Get("explain") == "true" {
+		if err := r.Context().Err(); err != nil {
+			writeJSONError(w, http.StatusServiceUnavailable, fmt.Sprintf("guard: evaluation aborted: %v", err))
+			return
+		}
+		v, trace := engine.EvaluateDetailed(ec)
+		writeJSON(w, http.StatusOK, ExplainResponse{Verdict: v, Trace: trace})
+		return
+	}
+	v, err := engine.EvaluateCtx(r.Context(), ec)
+	if err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func handleHealth(engine *PolicyEngine, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	st := engine.state.Load()
+	status := http.StatusOK
+	body := map[string]any{"status": "ok", "policies": len(st.policies)}
+	if !st.loaded {
+		status = http.StatusServiceUnavailable
+		body["status"] = "no policy set loaded"
+	}
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,124 @@
+package guard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func httpTestEngine() *PolicyEngine {
+	return NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "deny-bg-bash", Effect: EffectDeny, Priority: 10, Channel: ChannelChat,
+			Condition: Condition{Modes: []string{"background"}, Tools: []string{"bash"}}},
+		{ID: "allow-mcp", Effect: EffectAllow, Priority: 20, Channel: ChannelChat,
+			Condition: Condition{McpServers: []string{"github-*"}}},
+	}, EffectAsk))
+}
+
+func serve(t *testing.T, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTPEvaluate(t *testing.T) {
+	h := NewHTTPHandler(httpTestEngine())
+
+	rec := serve(t, h, http.MethodPost, "/evaluate", `{"tool":"bash","mode":"background"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	var v Verdict
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Effect != EffectDeny || v.PolicyID != "deny-bg-bash" {
+		t.Errorf("unexpected verdict %+v", v)
+	}
+
+	rec = serve(t, h, http.MethodPost, "/evaluate", `{"tool":"issues","mcp_server":"github-mcp"}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Effect != EffectAllow {
+		t.Errorf("mcp_server: expected allow, got %+v", v)
+	}
+}
+
+func TestHTTPEvaluateExplain(t *testing.T) {
+	h := NewHTTPHandler(httpTestEngine())
+	rec := serve(t, h, http.MethodPost, "/evaluate?explain=true", `{"tool":"bash","mode":"background"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp ExplainResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Verdict.PolicyID != "deny-bg-bash" {
+		t.Errorf("unexpected verdict %+v", resp.Verdict)
+	}
+	if len(resp.Trace) != 2 || !resp.Trace[0].Matched || resp.Trace[1].Matched {
+		t.Errorf("unexpected trace %+v", resp.Trace)
+	}
+	if len(resp.Trace) == 2 && (!resp.Trace[0].Winner || resp.Trace[1].Winner) {
+		t.Errorf("expected deny-bg-bash flagged as winner, got %+v", resp.Trace)
+	}
+}
+
+func TestHTTPEvaluateRejectsBadRequests(t *testing.T) {
+	h := NewHTTPHandler(httpTestEngine())
+	cases := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{"malformed", http.MethodPost, `{"tool":`, http.StatusBadRequest},
+		{"wrong type", http.MethodPost, `{"tool":42}`, http.StatusBadRequest},
+		{"unknown field", http.MethodPost, `{"tools":"bash"}`, http.StatusBadRequest},
+		{"trailing data", http.MethodPost, `{"tool":"bash"}{}`, http.StatusBadRequest},
+		{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(t, h, tc.method, "/evaluate", tc.body)
+			if rec.Code != tc.code {
+				t.Fatalf("expected %d, got %d: %s", tc.code, rec.Code, rec.Body)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == "" {
+				t.Errorf("expected JSON error body, got %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestHTTPHealth(t *testing.T) {
+	empty := NewHTTPHandler(NewPolicyEngine(nil))
+	if rec := serve(t, empty, http.MethodGet, "/healthz", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("unloaded: expected 503, got %d", rec.Code)
+	}
+
+	rec := serve(t, NewHTTPHandler(httpTestEngine()), http.MethodGet, "/healthz", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("loaded: expected 200, got %d", rec.Code)
+	}
+	var body struct {
+		Status   string `json:"status"`
+		Policies int    `json:"policies"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "ok" || body.Policies != 2 {
+		t.Errorf("unexpected health body %+v", body)
+	}
+}
@@ -0,0 +1,109 @@
+package guard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ── Includes ───────────────────────────────────────────────────────────
+
+// loadPolicyFile reads and decodes the policy file at path. stack holds
+// the absolute paths of the files that (transitively) include it.
+func loadPolicyFile(path string, stack []string, o loadOptions) (*PolicySet, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("guard: failed to resolve %s: %w", path, err)
+	}
+	for i, p := range stack {
+		if p == abs {
+			chain := append(append([]string(nil), stack[i:]...), abs)
+			return nil, fmt.Errorf("guard: %w: %s", ErrIncludeCycle, strings.Join(chain, " -> "))
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("guard: failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+	ps, err := decodePolicySet(f, filepath.Dir(abs), append(stack, abs), o)
+	if err != nil {
+		if len(stack) > 0 {
+			return nil, fmt.Errorf("guard: include %s: %w", path, err)
+		}
+		return nil, err
+	}
+	return ps, nil
+}
+
+// resolveIncludes loads every file listed in ps.Includes, relative to dir,
+// and merges them into ps. Included policies come first, in include order,
+// followed by ps's own policies. When two policies share an ID the later
+// one wins, so local policies override included ones. Context fallbacks,
+// groups and aliases merge the same way. Defaults always come from ps itself.
+func resolveIncludes(ps *PolicySet, dir string, stack []string, o loadOptions) error {
+	if len(ps.Includes) == 0 {
+		return nil
+	}
+	var policies []Policy
+	fallbacks := make(map[string]string)
+	groups := make(map[string]Condition)
+	aliases := make(map[string]string)
+	for _, inc := range ps.Includes {
+		path := inc
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		sub, err := loadPolicyFile(path, stack, o)
+		if err != nil {
+			return err
+		}
+		policies = mergePolicies(policies, sub.Policies)
+		for k, v := range sub.ContextFallbacks {
+			fallbacks[k] = v
+		}
+		for k, v := range sub.Groups {
+			groups[k] = v
+		}
+		for k, v := range sub.Aliases {
+			aliases[k] = v
+		}
+	}
+	ps.Policies = mergePolicies(policies, ps.Policies)
+	for k, v := range ps.ContextFallbacks {
+		fallbacks[k] = v
+	}
+	if len(fallbacks) > 0 {
+		ps.ContextFallbacks = fallbacks
+	}
+	for k, v := range ps.Groups {
+		groups[k] = v
+	}
+	if len(groups) > 0 {
+		ps.Groups = groups
+	}
+	for k, v := range ps.Aliases {
+		aliases[k] = v
+	}
+	if len(aliases) > 0 {
+		ps.Aliases = aliases
+	}
+	return nil
+}
+
+// mergePolicies appends override to base, dropping any base policy whose
+// ID is redefined in override.
+func mergePolicies(base, override []Policy) []Policy {
+	ids := make(map[string]bool, len(override))
+	for _, p := range override {
+		ids[p.ID] = true
+	}
+	out := make([]Policy, 0, len(base)+len(override))
+	for _, p := range base {
+		if !ids[p.ID] {
+			out = append(out, p)
+		}
+	}
+	return append(out, override...)
+}
@@ -0,0 +1,217 @@
+package guard
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePolicyFiles writes each name→content pair under a fresh temp dir
+// and returns the dir.
+func writePolicyFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func policyIDs(ps *PolicySet) []string {
+	ids := make([]string, len(ps.Policies))
+	for i, p := range ps.Policies {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func TestIncludesMergedInOrder(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml": `
+metadata: {name: top}
+include: [base.yaml, shared/mcp.yaml]
+defaults: {effect: deny}
+policies:
+  - id: local
+    effect: allow
+    condition: {tools: [view]}
+`,
+		"base.yaml": `
+metadata: {name: base}
+defaults: {effect: allow}
+context_fallbacks: {auto: safe}
+policies:
+  - id: base-bash
+    effect: ask
+    condition: {tools: [bash]}
+`,
+		"shared/mcp.yaml": `
+metadata: {name: mcp}
+policies:
+  - id: mcp-deny
+    effect: deny
+    condition: {mcp_servers: [untrusted]}
+`,
+	})
+
+	ps, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(policyIDs(ps), ","); got != "base-bash,mcp-deny,local" {
+		t.Errorf("policies = %s", got)
+	}
+	if ps.Defaults.Effect != EffectDeny {
+		t.Errorf("defaults must come from the including file, got %s", ps.Defaults.Effect)
+	}
+	if ps.ContextFallbacks["auto"] != "safe" {
+		t.Errorf("expected included context fallback, got %v", ps.ContextFallbacks)
+	}
+	if ps.Policies[0].Priority != 100 || ps.Policies[0].Channel != ChannelChat {
+		t.Errorf("loader defaults not applied to included policy: %+v", ps.Policies[0])
+	}
+}
+
+func TestIncludesLocalPolicyOverridesByID(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml": `
+metadata: {name: top}
+include: [base.yaml]
+policies:
+  - id: bash
+    effect: deny
+    condition: {tools: [bash]}
+`,
+		"base.yaml": `
+metadata: {name: base}
+policies:
+  - id: bash
+    effect: allow
+    condition: {tools: [bash]}
+  - id: view
+    effect: allow
+    condition: {tools: [view]}
+`,
+	})
+
+	ps, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(policyIDs(ps), ","); got != "view,bash" {
+		t.Fatalf("policies = %s", got)
+	}
+	if ps.Policies[1].Effect != EffectDeny {
+		t.Errorf("expected local bash policy to win, got %s", ps.Policies[1].Effect)
+	}
+}
+
+func TestIncludesNested(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml":   "metadata: {name: top}\ninclude: [lib/a.yaml]\n",
+		"lib/a.yaml": "metadata: {name: a}\ninclude: [b.yaml]\npolicies: [{id: a, effect: allow}]\n",
+		"lib/b.yaml": "metadata: {name: b}\npolicies: [{id: b, effect: deny}]\n",
+	})
+
+	ps, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(policyIDs(ps), ","); got != "b,a" {
+		t.Errorf("policies = %s", got)
+	}
+}
+
+func TestIncludesCircular(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"a.yaml": "metadata: {name: a}\ninclude: [b.yaml]\n",
+		"b.yaml": "metadata: {name: b}\ninclude: [a.yaml]\n",
+	})
+
+	_, err := LoadPolicySet(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "circular include") {
+		t.Fatalf("expected circular include error, got %v", err)
+	}
+}
+
+func TestIncludesSelf(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"a.yaml": "metadata: {name: a}\ninclude: [./a.yaml]\n",
+	})
+
+	_, err := LoadPolicySet(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "circular include") {
+		t.Fatalf("expected circular include error, got %v", err)
+	}
+}
+
+func TestIncludesDiamondIsNotCircular(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml":    "metadata: {name: top}\ninclude: [a.yaml, b.yaml]\n",
+		"a.yaml":      "metadata: {name: a}\ninclude: [common.yaml]\n",
+		"b.yaml":      "metadata: {name: b}\ninclude: [common.yaml]\n",
+		"common.yaml": "metadata: {name: common}\npolicies: [{id: common, effect: deny}]\n",
+	})
+
+	ps, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(policyIDs(ps), ","); got != "common" {
+		t.Errorf("policies = %s", got)
+	}
+}
+
+func TestIncludesMissingFile(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml": "metadata: {name: top}\ninclude: [missing.yaml]\n",
+	})
+
+	_, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Fatalf("expected error naming missing.yaml, got %v", err)
+	}
+}
+
+func TestIncludesValidatedAfterMerge(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml": "metadata: {name: top}\ninclude: [bad.yaml]\n",
+		"bad.yaml": "metadata: {name: bad}\npolicies: [{id: bad, effect: allow, condition: {source_cidrs: [nope]}}]\n",
+	})
+
+	_, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "invalid source CIDR") {
+		t.Fatalf("expected CIDR validation error, got %v", err)
+	}
+}
+
+func TestIncludedPoliciesInheritIncludingDefaultChannel(t *testing.T) {
+	dir := writePolicyFiles(t, map[string]string{
+		"top.yaml": `
+metadata: {name: top}
+include: [base.yaml]
+defaults: {effect: deny, channel: phone}
+`,
+		"base.yaml": `
+metadata: {name: base}
+policies:
+  - id: ask-deploy
+    effect: ask
+    condition: {tools: [deploy]}
+`,
+	})
+	ps, err := LoadPolicySet(filepath.Join(dir, "top.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ps.Policies[0].Channel; got != ChannelPhone {
+		t.Errorf("expected the including file's phone default, got %s", got)
+	}
+}
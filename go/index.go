@@ -0,0 +1,109 @@
+package guard
+
+import "slices"
+
+// ── Tool index ─────────────────────────────────────────────────────────
+
+// toolIndex narrows evaluation to the policies that could match a tool.
+// Policies whose tools condition lists only exact names are indexed under
+// each name; policies with a glob tool pattern, no tools condition at
+// all, or an inverted match are candidates for every tool. Both slices
+// hold policy indexes in ascending (priority) order.
+type toolIndex struct {
+	exact     map[string][]int
+	unindexed []int
+}
+
+func buildToolIndex(conds []compiledCondition) toolIndex {
+	ix := toolIndex{exact: make(map[string][]int)}
+	for i := range conds {
+		tools := &conds[i].tools
+		if !tools.set || !allExact(tools.matchers) || conds[i].invert {
+			ix.unindexed = append(ix.unindexed, i)
+			continue
+		}
+		// An empty tools list never matches, so it is left out entirely.
+		for _, m := range tools.matchers {
+			ids := ix.exact[m.lit]
+			if len(ids) > 0 && ids[len(ids)-1] == i {
+				continue // duplicate name within one policy
+			}
+			ix.exact[m.lit] = append(ids, i)
+		}
+	}
+	return ix
+}
+
+func allExact(matchers []matcher) bool {
+	for _, m := range matchers {
+		if m.kind != matchExact {
+			return false
+		}
+	}
+	return true
+}
+
+// maxMergedTools bounds the Tools a candidateIter merges in place. Larger
+// batches fall back to merging into a fresh slice.
+const maxMergedTools = 8
+
+// candidates returns an iterator over the policies that could match tool,
+// in priority order.
+func (ix *toolIndex) candidates(tool string) candidateIter {
+	it := candidateIter{n: 2}
+	it.lists[0], it.lists[1] = ix.exact[tool], ix.unindexed
+	return it
+}
+
+// candidatesFor returns an iterator over the policies that could match
+// ctx: with several Tools, those indexed under any of them.
+func (ix *toolIndex) candidatesFor(ctx *EvalContext) candidateIter {
+	if len(ctx.Tools) == 0 {
+		return ix.candidates(ctx.Tool)
+	}
+	if len(ctx.Tools) > maxMergedTools {
+		var a []int
+		for _, tool := range ctx.Tools {
+			a = append(a, ix.exact[tool]...)
+		}
+		slices.Sort(a)
+		it := candidateIter{n: 2}
+		it.lists[0], it.lists[1] = slices.Compact(a), ix.unindexed
+		return it
+	}
+	it := candidateIter{n: 1}
+	it.lists[0] = ix.unindexed
+	for _, tool := range ctx.Tools {
+		if ids := ix.exact[tool]; len(ids) > 0 {
+			it.lists[it.n] = ids
+			it.n++
+		}
+	}
+	return it
+}
+
+// candidateIter merges up to maxMergedTools+1 ascending index lists
+// without allocating, yielding an index found in several lists once.
+type candidateIter struct {
+	lists [maxMergedTools + 1][]int
+	n     int // lists in use
+}
+
+func (it *candidateIter) next() (int, bool) {
+	lists := it.lists[:it.n]
+	least := -1
+	for _, l := range lists {
+		if len(l) > 0 && (least < 0 || l[0] < least) {
+			least = l[0]
+		}
+	}
+	if least < 0 {
+		return 0, false
+	}
+	for k, l := range lists {
+		if len(l) > 0 && l[0] == least {
+			lists[k] = l[1:]
+		}
+	}
+	return least, true
+}
@@ -0,0 +1,157 @@
+package guard
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestToolIndexPreservesPriorityAcrossBuckets(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "exact-late", Effect: EffectAllow, Priority: 30, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "glob-mid", Effect: EffectAsk, Priority: 20, Condition: Condition{Tools: []string{"ba*"}}},
+		{ID: "any-early", Effect: EffectDeny, Priority: 10, Condition: Condition{Modes: []string{"background"}}},
+	}, EffectHITL)
+	engine := NewPolicyEngine(ps)
+
+	cases := []struct {
+		ctx  EvalContext
+		want string
+	}{
+		{EvalContext{Tool: "bash", Mode: "background"}, "any-early"},
+		{EvalContext{Tool: "bash", Mode: "interactive"}, "glob-mid"},
+		{EvalContext{Tool: "bat", Mode: "interactive"}, "glob-mid"},
+		{EvalContext{Tool: "grep", Mode: "interactive"}, ""},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(tc.ctx); v.PolicyID != tc.want {
+			t.Errorf("%+v: expected %q, got %q", tc.ctx, tc.want, v.PolicyID)
+		}
+	}
+}
+
+func TestToolIndexMatchesLinearScan(t *testing.T) {
+	ps := realisticPolicySet()
+	engine := NewPolicyEngine(ps)
+	st := engine.state.Load()
+
+	tools := []string{"tool-0", "tool-7", "tool-399", "mcp:github-issues", "mcp:azure-deploy", "unknown", ""}
+	modes := []string{"interactive", "background"}
+	for _, tool := range tools {
+		for _, mode := range modes {
+			ctx := EvalContext{Tool: tool, Mode: mode, Risk: "high"}
+			want := ""
+			for i := range st.policies {
+				if st.policies[i].IsEnabled() && st.conds[i].matches(ctx) {
+					want = st.policies[i].ID
+					break
+				}
+			}
+			if v := engine.Evaluate(ctx); v.PolicyID != want {
+				t.Errorf("%+v: expected %q, got %q", ctx, want, v.PolicyID)
+			}
+		}
+	}
+}
+
+func TestToolIndexMergesTools(t *testing.T) {
+	ix := &NewPolicyEngine(realisticPolicySet()).state.Load().index
+	many := make([]string, maxMergedTools+2)
+	for i := range many {
+		many[i] = fmt.Sprintf("tool-%d", 300-i*10)
+	}
+	for _, tools := range [][]string{
+		{"tool-399", "tool-7"},
+		{"tool-7", "tool-7", "unknown", "tool-0"},
+		{"mcp:server3-x", "tool-12"},
+		many,
+	} {
+		want := append([]int(nil), ix.unindexed...)
+		for _, tool := range tools {
+			want = append(want, ix.exact[tool]...)
+		}
+		slices.Sort(want)
+		want = slices.Compact(want)
+
+		var got []int
+		it := ix.candidatesFor(&EvalContext{Tools: tools})
+		for i, ok := it.next(); ok; i, ok = it.next() {
+			got = append(got, i)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%v: got candidates %v, want %v", tools, got, want)
+		}
+	}
+}
+
+func TestToolIndexDuplicateNames(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "p1", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash", "bash"}}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+	if got := engine.state.Load().index.exact["bash"]; len(got) != 1 {
+		t.Errorf("expected policy indexed once, got %v", got)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "p1" {
+		t.Errorf("expected p1, got %s", v.PolicyID)
+	}
+}
+
+// realisticPolicySet mixes tool-specific policies with MCP globs and a few
+// broad mode/risk rules, which is what most production files look like.
+func realisticPolicySet() *PolicySet {
+	var policies []Policy
+	for i := 0; i < 400; i++ {
+		policies = append(policies, Policy{
+			ID: fmt.Sprintf("tool-%d", i), Effect: EffectAllow, Priority: 100 + i,
+			Channel: ChannelChat, Condition: Condition{Tools: []string{fmt.Sprintf("tool-%d", i)}},
+		})
+	}
+	for i := 0; i < 50; i++ {
+		policies = append(policies, Policy{
+			ID: fmt.Sprintf("mcp-%d", i), Effect: EffectHITL, Priority: 50 + i,
+			Channel: ChannelChat, Condition: Condition{Tools: []string{fmt.Sprintf("mcp:server%d-*", i)}},
+		})
+	}
+	for i := 0; i < 50; i++ {
+		policies = append(policies, Policy{
+			ID: fmt.Sprintf("broad-%d", i), Effect: EffectDeny, Priority: 10 + i,
+			Channel: ChannelChat, Condition: Condition{Modes: []string{"background"}, Risk: []string{fmt.Sprintf("level-%d", i)}},
+		})
+	}
+	return makePolicySet(policies, EffectAsk)
+}
+
+func BenchmarkEvaluateRealisticIndexed(b *testing.B) {
+	engine := NewPolicyEngine(realisticPolicySet())
+	ctx := EvalContext{Tool: "tool-399", Mode: "interactive", Risk: "low"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.Evaluate(ctx)
+	}
+}
+
+func BenchmarkEvaluateRealisticTools(b *testing.B) {
+	engine := NewPolicyEngine(realisticPolicySet())
+	ctx := EvalContext{Tools: []string{"tool-399", "tool-7", "tool-42"}, Mode: "interactive", Risk: "low"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.Evaluate(ctx)
+	}
+}
+
+func BenchmarkEvaluateRealisticLinear(b *testing.B) {
+	st := NewPolicyEngine(realisticPolicySet()).state.Load()
+	ctx := EvalContext{Tool: "tool-399", Mode: "interactive", Risk: "low"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range st.policies {
+			if st.policies[j].IsEnabled() && st.conds[j].matches(ctx) {
+				break
+			}
+		}
+	}
+}
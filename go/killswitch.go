@@ -0,0 +1,61 @@
+package guard
+
+// ── Kill switch ────────────────────────────────────────────────────────
+
+// killSwitch is the override installed by SetKillSwitch.
+type killSwitch struct {
+	effect Effect
+	reason string
+}
+
+// SetKillSwitch overrides every policy during an incident: until
+// ClearKillSwitch is called, Evaluate, EvaluateCtx, EvaluateDetailed,
+// EvaluateBatch, EvaluateStream and EvaluateResult return effect for
+// every context, with reason in Verdict.Reason and Source
+// SourceKillSwitch. An empty effect means deny.
+//
+// The override takes effect for evaluations that start after it returns,
+// without a reload. Pre-hooks, post-hooks and the decision cache are
+// bypassed so nothing can soften it; observers and audit sinks are still
+// notified. EvaluateAll and EvaluateMatched keep reporting what the
+// policies alone would do.
+func (e *PolicyEngine) SetKillSwitch(effect Effect, reason string) {
+	if effect == "" {
+		effect = EffectDeny
+	}
+	e.kill.Store(&killSwitch{effect: effect, reason: reason})
+}
+
+// ClearKillSwitch removes the override set by SetKillSwitch, restoring
+// normal evaluation. It is a no-op when no kill switch is set.
+func (e *PolicyEngine) ClearKillSwitch() {
+	e.kill.Store(nil)
+}
+
+// KillSwitch returns the override set by SetKillSwitch, if one is active.
+func (e *PolicyEngine) KillSwitch() (effect Effect, reason string, ok bool) {
+	ks := e.kill.Load()
+	if ks == nil {
+		return "", "", false
+	}
+	return ks.effect, ks.reason, true
+}
+
+// killSwitched returns the kill-switch verdict for ec, after notifying
+// the observer and audit sink, or false when no kill switch is set.
+func (e *PolicyEngine) killSwitched(st *engineState, ec EvalContext) (Verdict, bool) {
+	ks := e.kill.Load()
+	if ks == nil {
+		return Verdict{}, false
+	}
+	v := Verdict{
+		Effect:  ks.effect,
+		Channel: st.defaults.channelFor(ks.effect),
+		Reason:  ks.reason,
+		Source:  SourceKillSwitch,
+	}
+	if obs, sink := e.observer.Load(), e.audit.Load(); obs != nil || sink != nil {
+		e.notify(obs, sink, e.clock.Now(), ec, v)
+	}
+	return v, true
+}
@@ -0,0 +1,100 @@
+package guard
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKillSwitchToggles(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "allow-view", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"view"}}},
+	}, EffectAsk))
+	view := EvalContext{Tool: "view"}
+
+	if _, _, ok := engine.KillSwitch(); ok {
+		t.Fatal("kill switch active on a new engine")
+	}
+	engine.SetKillSwitch(EffectDeny, "incident INC-42")
+	if effect, reason, ok := engine.KillSwitch(); !ok || effect != EffectDeny || reason != "incident INC-42" {
+		t.Errorf("KillSwitch() = %s, %q, %v", effect, reason, ok)
+	}
+	for _, ec := range []EvalContext{view, {Tool: "bash"}, {}} {
+		v := engine.Evaluate(ec)
+		if v.Effect != EffectDeny || v.Source != SourceKillSwitch || v.Reason != "incident INC-42" || v.PolicyID != "" {
+			t.Errorf("%q: got %+v, want the kill-switch deny", ec.Tool, v)
+		}
+	}
+	v, results := engine.EvaluateDetailed(view)
+	if v.Source != SourceKillSwitch || len(results) != 1 || !results[0].Matched || results[0].Winner {
+		t.Errorf("EvaluateDetailed = %+v, %+v", v, results)
+	}
+	for _, v := range engine.EvaluateBatch([]EvalContext{view, view}) {
+		if v.Source != SourceKillSwitch {
+			t.Errorf("EvaluateBatch: got source %s", v.Source)
+		}
+	}
+	if v := engine.EvaluateResult(view, ResultContext{}); v.Source != SourceKillSwitch {
+		t.Errorf("EvaluateResult: got source %s", v.Source)
+	}
+
+	engine.ClearKillSwitch()
+	if _, _, ok := engine.KillSwitch(); ok {
+		t.Error("kill switch still active after ClearKillSwitch")
+	}
+	if v := engine.Evaluate(view); v.Effect != EffectAllow || v.PolicyID != "allow-view" {
+		t.Errorf("after clear: got %+v, want allow-view", v)
+	}
+
+	// An empty effect means deny; another effect is returned as set.
+	engine.SetKillSwitch("", "")
+	if v := engine.Evaluate(view); v.Effect != EffectDeny {
+		t.Errorf("empty effect: got %s, want deny", v.Effect)
+	}
+	engine.SetKillSwitch(EffectAsk, "review everything")
+	if v := engine.Evaluate(view); v.Effect != EffectAsk || v.Channel != ChannelChat {
+		t.Errorf("ask override: got %s on %q", v.Effect, v.Channel)
+	}
+}
+
+func TestKillSwitchCannotBeSoftened(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet(nil, EffectAsk))
+	cache := NewMemoryDecisionCache(time.Hour)
+	engine.SetDecisionCache(cache)
+	engine.AddPostHook(func(_ EvalContext, v *Verdict) { v.Effect = EffectAllow })
+	obs := &recordingObserver{}
+	engine.SetObserver(obs)
+
+	ec := EvalContext{Tool: "bash", Session: "s1"}
+	engine.Approve(ec, Verdict{Effect: EffectAsk})
+	engine.SetKillSwitch(EffectAsk, "incident")
+	if v := engine.Evaluate(ec); v.Effect != EffectAsk {
+		t.Errorf("got %s, want ask despite the post-hook and cached approval", v.Effect)
+	}
+	if len(obs.verdicts) != 1 || obs.verdicts[0].Source != SourceKillSwitch {
+		t.Errorf("observer saw %+v, want one kill-switch verdict", obs.verdicts)
+	}
+}
+
+func TestKillSwitchConcurrent(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet(nil, EffectAllow))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				v := engine.Evaluate(EvalContext{Tool: "bash"})
+				if v.Effect != EffectAllow && v.Source != SourceKillSwitch {
+					t.Errorf("got %+v", v)
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		engine.SetKillSwitch(EffectDeny, "flapping")
+		engine.ClearKillSwitch()
+	}
+	wg.Wait()
+}
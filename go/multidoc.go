@@ -0,0 +1,122 @@
+package guard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ── Multi-document streams ─────────────────────────────────────────────
+
+// LoadPolicySetsFromBytes parses every PolicySet in a YAML stream whose
+// documents are separated by "---". Each document gets the same defaults,
+// include resolution and validation as LoadPolicySetFromBytes. Empty
+// documents are skipped. Errors name the 1-based document they occur in.
+func LoadPolicySetsFromBytes(data []byte, opts ...LoadOption) ([]*PolicySet, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.expandEnv {
+		var err error
+		if data, err = expandEnv(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var out []*PolicySet
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	// Node.Decode cannot reject unknown keys, so strict mode decodes each
+	// document a second time, in step, with a strict decoder.
+	var strict *yaml.Decoder
+	if o.strictFields {
+		strict = yaml.NewDecoder(bytes.NewReader(data))
+		strict.KnownFields(true)
+	}
+	for doc := 1; ; doc++ {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &ParseError{Document: doc, Err: err}
+		}
+		var ps PolicySet
+		if strict != nil {
+			err = strict.Decode(&ps)
+		}
+		if isEmptyDocument(&node) {
+			continue
+		}
+		if strict == nil {
+			err = node.Decode(&ps)
+		}
+		if err != nil {
+			return nil, &ParseError{Document: doc, Err: err}
+		}
+		if err := preparePolicySet(&ps, ".", nil, o); err != nil {
+			return nil, fmt.Errorf("guard: document %d: %w", doc, err)
+		}
+		if err := finishPolicySet(&ps, o); err != nil {
+			return nil, fmt.Errorf("guard: document %d: %w", doc, err)
+		}
+		out = append(out, &ps)
+	}
+	return out, nil
+}
+
+// isEmptyDocument reports whether a decoded document holds no content,
+// as produced by a stray "---" separator.
+func isEmptyDocument(n *yaml.Node) bool {
+	if n.Kind == 0 {
+		return true
+	}
+	if n.Kind == yaml.DocumentNode && len(n.Content) == 1 {
+		c := n.Content[0]
+		return c.Kind == yaml.ScalarNode && c.Tag == "!!null"
+	}
+	return false
+}
+
+// MergePolicySets combines sets into one, in order. Later sets override
+// earlier ones: a policy, context fallback, group or alias redefined by a
+// later set replaces the earlier definition. Metadata and defaults come
+// from the first set. It returns nil when sets is empty.
+func MergePolicySets(sets ...*PolicySet) *PolicySet {
+	if len(sets) == 0 {
+		return nil
+	}
+	first := sets[0]
+	merged := &PolicySet{
+		APIVersion: first.APIVersion,
+		Kind:       first.Kind,
+		Metadata:   first.Metadata,
+		Defaults:   first.Defaults,
+	}
+	for _, ps := range sets {
+		merged.Policies = mergePolicies(merged.Policies, ps.Policies)
+		for k, v := range ps.ContextFallbacks {
+			if merged.ContextFallbacks == nil {
+				merged.ContextFallbacks = make(map[string]string)
+			}
+			merged.ContextFallbacks[k] = v
+		}
+		for k, v := range ps.Groups {
+			if merged.Groups == nil {
+				merged.Groups = make(map[string]Condition)
+			}
+			merged.Groups[k] = v
+		}
+		for k, v := range ps.Aliases {
+			if merged.Aliases == nil {
+				merged.Aliases = make(map[string]string)
+			}
+			merged.Aliases[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,122 @@
+package guard
+
+import (
+	"strings"
+	"testing"
+)
+
+const multiDoc = `---
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata: {name: base}
+defaults: {effect: deny}
+context_fallbacks: {scheduler: background}
+policies:
+  - id: bash
+    effect: ask
+    condition: {tools: [bash]}
+  - id: view
+    effect: allow
+    condition: {tools: [view]}
+---
+---
+metadata: {name: overrides}
+policies:
+  - id: bash
+    effect: deny
+    condition: {tools: [bash]}
+  - id: mcp
+    effect: allow
+    condition: {mcp_servers: [trusted]}
+`
+
+func TestLoadPolicySetsFromBytes(t *testing.T) {
+	sets, err := LoadPolicySetsFromBytes([]byte(multiDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 2 {
+		t.Fatalf("expected 2 sets, got %d", len(sets))
+	}
+	if sets[0].Metadata.Name != "base" || sets[1].Metadata.Name != "overrides" {
+		t.Errorf("names = %s, %s", sets[0].Metadata.Name, sets[1].Metadata.Name)
+	}
+	// Loader defaults apply to each document.
+	if sets[1].Kind != "PolicySet" || sets[1].Defaults.Effect != EffectAsk || sets[1].Policies[0].Priority != 100 {
+		t.Errorf("defaults not applied to second document: %+v", sets[1])
+	}
+}
+
+func TestLoadPolicySetsFromBytesErrorNamesDocument(t *testing.T) {
+	data := multiDoc + `---
+metadata: {name: broken}
+policies:
+  - id: bad
+    effect: allow
+    condition: {source_cidrs: [nope]}
+`
+	_, err := LoadPolicySetsFromBytes([]byte(data))
+	if err == nil || !strings.Contains(err.Error(), "document 4") {
+		t.Fatalf("expected error naming document 4, got %v", err)
+	}
+
+	_, err = LoadPolicySetsFromBytes([]byte("metadata: {name: a}\n---\nkind: Secret\n"))
+	if err == nil || !strings.Contains(err.Error(), "document 2: guard: unsupported kind") {
+		t.Fatalf("expected kind error in document 2, got %v", err)
+	}
+}
+
+func TestLoadPolicySetsFromBytesEmpty(t *testing.T) {
+	sets, err := LoadPolicySetsFromBytes([]byte("---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 0 {
+		t.Errorf("expected no sets, got %d", len(sets))
+	}
+}
+
+func TestLoadPolicySetsFromBytesStrictFields(t *testing.T) {
+	if _, err := LoadPolicySetsFromBytes([]byte(multiDoc), WithStrictFields()); err != nil {
+		t.Fatalf("valid stream with empty documents: %v", err)
+	}
+	data := multiDoc + `---
+metadata: {name: typo}
+policies:
+  - id: p1
+    effect: deny
+    condition: {tool: [bash]}
+`
+	if _, err := LoadPolicySetsFromBytes([]byte(data)); err != nil {
+		t.Fatalf("lenient: %v", err)
+	}
+	_, err := LoadPolicySetsFromBytes([]byte(data), WithStrictFields())
+	if err == nil || !strings.Contains(err.Error(), "document 4") || !strings.Contains(err.Error(), "field tool not found") {
+		t.Fatalf("expected unknown field error in document 4, got %v", err)
+	}
+}
+
+func TestMergePolicySets(t *testing.T) {
+	sets, err := LoadPolicySetsFromBytes([]byte(multiDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := MergePolicySets(sets...)
+	if merged.Metadata.Name != "base" || merged.Defaults.Effect != EffectDeny {
+		t.Errorf("metadata/defaults should come from first set: %+v %+v", merged.Metadata, merged.Defaults)
+	}
+	if got := strings.Join(policyIDs(merged), ","); got != "view,bash,mcp" {
+		t.Errorf("policies = %s", got)
+	}
+	if merged.ContextFallbacks["scheduler"] != "background" {
+		t.Errorf("context fallbacks = %v", merged.ContextFallbacks)
+	}
+
+	engine := NewPolicyEngine(merged)
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Effect != EffectDeny || v.PolicyID != "bash" {
+		t.Errorf("bash: expected overriding deny, got %+v", v)
+	}
+	if MergePolicySets() != nil {
+		t.Error("expected nil for no sets")
+	}
+}
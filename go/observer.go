@@ -0,0 +1,28 @@
+package guard
+
+import "time"
+
+// ── Observer ───────────────────────────────────────────────────────────
+
+// Observer is notified of every verdict the engine produces, e.g. to feed
+// metrics. OnVerdict runs synchronously on the evaluating goroutine, so
+// implementations must be fast and safe for concurrent use.
+type Observer interface {
+	OnVerdict(ctx EvalContext, v Verdict, dur time.Duration)
+}
+
+// observerBox lets a nil-able interface live behind an atomic.Pointer.
+type observerBox struct {
+	obs Observer
+}
+
+// SetObserver installs o to be called after each evaluation, replacing any
+// previous observer. Passing nil removes it. With no observer installed the
+// engine does not read the clock at all.
+func (e *PolicyEngine) SetObserver(o Observer) {
+	if o == nil {
+		e.observer.Store(nil)
+		return
+	}
+	e.observer.Store(&observerBox{obs: o})
+}
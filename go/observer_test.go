@@ -0,0 +1,88 @@
+package guard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	verdicts []Verdict
+	tools    []string
+}
+
+func (r *recordingObserver) OnVerdict(ctx EvalContext, v Verdict, dur time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verdicts = append(r.verdicts, v)
+	r.tools = append(r.tools, ctx.Tool)
+	if dur < 0 {
+		panic("negative duration")
+	}
+}
+
+func observerPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk)
+}
+
+func TestObserverReceivesEveryVerdict(t *testing.T) {
+	engine := NewPolicyEngine(observerPolicySet())
+	obs := &recordingObserver{}
+	engine.SetObserver(obs)
+
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	engine.Evaluate(EvalContext{Tool: "view"})
+
+	if len(obs.verdicts) != 2 {
+		t.Fatalf("expected 2 verdicts, got %d", len(obs.verdicts))
+	}
+	if obs.verdicts[0].PolicyID != "deny-bash" || obs.verdicts[0].Effect != EffectDeny {
+		t.Errorf("first verdict = %+v", obs.verdicts[0])
+	}
+	if obs.verdicts[1].Effect != EffectAsk || obs.tools[1] != "view" {
+		t.Errorf("second verdict = %+v for %s", obs.verdicts[1], obs.tools[1])
+	}
+}
+
+func TestObserverCoversBatch(t *testing.T) {
+	engine := NewPolicyEngine(observerPolicySet())
+	obs := &recordingObserver{}
+	engine.SetObserver(obs)
+
+	engine.EvaluateBatch([]EvalContext{{Tool: "bash"}, {Tool: "view"}, {Tool: "bash"}})
+	if len(obs.verdicts) != 3 {
+		t.Errorf("expected 3 verdicts, got %d", len(obs.verdicts))
+	}
+}
+
+func TestObserverSkipsAbortedEvaluation(t *testing.T) {
+	engine := NewPolicyEngine(observerPolicySet())
+	obs := &recordingObserver{}
+	engine.SetObserver(obs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := engine.EvaluateCtx(ctx, EvalContext{Tool: "bash"}); err == nil {
+		t.Fatal("expected error from cancelled context")
+	}
+	if len(obs.verdicts) != 0 {
+		t.Errorf("expected no verdicts, got %d", len(obs.verdicts))
+	}
+}
+
+func TestSetObserverNilRemoves(t *testing.T) {
+	engine := NewPolicyEngine(observerPolicySet())
+	obs := &recordingObserver{}
+	engine.SetObserver(obs)
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	engine.SetObserver(nil)
+	engine.Evaluate(EvalContext{Tool: "bash"})
+
+	if len(obs.verdicts) != 1 {
+		t.Errorf("expected 1 verdict, got %d", len(obs.verdicts))
+	}
+}
@@ -0,0 +1,90 @@
+package guard
+
+import "sync"
+
+// ── Rate limiting ──────────────────────────────────────────────────────
+
+// Counter counts invocations for the rate-limit effect. Incr increments the
+// count for key and returns the new value. Implementations must be safe for
+// concurrent use; they may be backed by shared storage such as Redis.
+//
+// Simulate and ReplayTrace never call Incr. If the counter also has a
+// Count(key string) int method returning the current count, as
+// MemoryCounter does, they use it to judge the limit; otherwise they treat
+// every invocation as within it.
+type Counter interface {
+	Incr(key string) int
+}
+
+// countPeeker is the optional read-only side of a Counter.
+type countPeeker interface {
+	Count(key string) int
+}
+
+// MemoryCounter is an in-process Counter. The zero value is ready to use.
+type MemoryCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewMemoryCounter returns an empty in-process Counter.
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{}
+}
+
+// Incr increments and returns the count for key.
+func (c *MemoryCounter) Incr(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[key]++
+	return c.counts[key]
+}
+
+// Count returns the count for key without incrementing it.
+func (c *MemoryCounter) Count(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[key]
+}
+
+// Reset clears all counts.
+func (c *MemoryCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = nil
+}
+
+// rateLimitKey identifies the bucket counted for an invocation.
+func rateLimitKey(ctx EvalContext) string {
+	return ctx.Session + "|" + ctx.toolKey()
+}
+
+// rateLimitEffect counts an invocation against a rate-limit policy and
+// returns allow while the count is within the limit, or the policy's
+// exceeded effect afterwards.
+func (e *PolicyEngine) rateLimitEffect(p *Policy, ctx EvalContext) Effect {
+	exceeded := EffectAsk
+	limit := 0
+	if p.RateLimit != nil {
+		limit = p.RateLimit.Max
+		if p.RateLimit.Exceeded != "" {
+			exceeded = p.RateLimit.Exceeded
+		}
+	}
+	if e.counter == nil {
+		return exceeded
+	}
+	if ctx.simulated {
+		if peek, ok := e.counter.(countPeeker); ok && peek.Count(rateLimitKey(ctx)) >= limit {
+			return exceeded
+		}
+		return EffectAllow
+	}
+	if e.counter.Incr(rateLimitKey(ctx)) > limit {
+		return exceeded
+	}
+	return EffectAllow
+}
@@ -0,0 +1,153 @@
+package guard
+
+import "testing"
+
+func rateLimitPolicySet(max int, exceeded Effect) *PolicySet {
+	return makePolicySet([]Policy{
+		{
+			ID: "limited", Effect: EffectRateLimit, Priority: 10,
+			RateLimit: &RateLimit{Max: max, Exceeded: exceeded},
+			Condition: Condition{Tools: []string{"web_search"}},
+		},
+	}, EffectDeny)
+}
+
+func TestRateLimitDowngradesAfterThreshold(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(rateLimitPolicySet(2, ""), WithCounter(NewMemoryCounter()))
+	ctx := EvalContext{Tool: "web_search", Session: "s1"}
+
+	for i := 1; i <= 2; i++ {
+		v := engine.Evaluate(ctx)
+		if v.Effect != EffectAllow {
+			t.Fatalf("call %d: expected allow, got %s", i, v.Effect)
+		}
+		if v.PolicyID != "limited" {
+			t.Fatalf("call %d: expected limited, got %s", i, v.PolicyID)
+		}
+	}
+	if v := engine.Evaluate(ctx); v.Effect != EffectAsk {
+		t.Errorf("call 3: expected ask, got %s", v.Effect)
+	}
+}
+
+func TestRateLimitKeyedOnSessionAndTool(t *testing.T) {
+	ps := rateLimitPolicySet(1, EffectDeny)
+	ps.Policies[0].Condition = Condition{}
+	engine := NewPolicyEngineWithOptions(ps, WithCounter(NewMemoryCounter()))
+
+	if v := engine.Evaluate(EvalContext{Tool: "web_search", Session: "s1"}); v.Effect != EffectAllow {
+		t.Fatalf("s1 first: expected allow, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "web_search", Session: "s1"}); v.Effect != EffectDeny {
+		t.Errorf("s1 second: expected deny, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "web_search", Session: "s2"}); v.Effect != EffectAllow {
+		t.Errorf("s2 first: expected allow, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "view", Session: "s1"}); v.Effect != EffectAllow {
+		t.Errorf("s1 other tool: expected allow, got %s", v.Effect)
+	}
+}
+
+func TestRateLimitWithoutCounter(t *testing.T) {
+	engine := NewPolicyEngine(rateLimitPolicySet(5, ""))
+	if v := engine.Evaluate(EvalContext{Tool: "web_search", Session: "s1"}); v.Effect != EffectAsk {
+		t.Errorf("expected ask without a counter, got %s", v.Effect)
+	}
+}
+
+func TestMemoryCounterReset(t *testing.T) {
+	c := NewMemoryCounter()
+	c.Incr("k")
+	if got := c.Incr("k"); got != 2 {
+		t.Fatalf("expected 2, got %d", got)
+	}
+	c.Reset()
+	if got := c.Incr("k"); got != 1 {
+		t.Errorf("expected 1 after reset, got %d", got)
+	}
+}
+
+func TestRateLimitLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+metadata:
+  name: rate
+policies:
+  - id: search
+    effect: rate-limit
+    rate_limit:
+      max: 1
+      exceeded: hitl
+    condition:
+      tools: [web_search]
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc), WithStrictEffects())
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngineWithOptions(ps, WithCounter(NewMemoryCounter()))
+	ctx := EvalContext{Tool: "web_search", Session: "s1"}
+	if got := engine.Resolve(ctx); got != "allow" {
+		t.Errorf("first: expected allow, got %s", got)
+	}
+	if got := engine.Resolve(ctx); got != "hitl" {
+		t.Errorf("second: expected hitl, got %s", got)
+	}
+}
+
+func TestRateLimitRequiresConfig(t *testing.T) {
+	bad := "metadata:\n  name: bad\npolicies:\n  - id: p1\n    effect: rate-limit\n"
+	if _, err := LoadPolicySetFromBytes([]byte(bad)); err == nil {
+		t.Fatal("expected error for rate-limit without rate_limit")
+	}
+}
+
+func TestRateLimitChannelFollowsResolvedEffect(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+metadata:
+  name: channels
+defaults:
+  effect: deny
+  channel: slack
+  channel_by_effect:
+    allow: chat
+    pitl: phone
+    rate-limit: email
+policies:
+  - id: limited
+    effect: rate-limit
+    rate_limit:
+      max: 1
+      exceeded: pitl
+    condition:
+      tools: [web_search]
+  - id: limited-chat
+    effect: rate-limit
+    channel: chat
+    rate_limit:
+      max: 1
+      exceeded: pitl
+    condition:
+      tools: [fetch]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngineWithOptions(ps, WithCounter(NewMemoryCounter()))
+	for i, want := range []struct {
+		tool    string
+		effect  Effect
+		channel Channel
+	}{
+		{"web_search", EffectAllow, ChannelChat},
+		{"web_search", EffectPITL, ChannelPhone},
+		// The policy's own channel wins over the defaults either way.
+		{"fetch", EffectAllow, ChannelChat},
+		{"fetch", EffectPITL, ChannelChat},
+	} {
+		v := engine.Evaluate(EvalContext{Tool: want.tool, Session: "s1"})
+		if v.Effect != want.effect || v.Channel != want.channel {
+			t.Errorf("call %d (%s): got %s via %q, want %s via %q", i+1, want.tool, v.Effect, v.Channel, want.effect, want.channel)
+		}
+	}
+}
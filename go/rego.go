@@ -0,0 +1,472 @@
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ── Rego export ────────────────────────────────────────────────────────
+
+// ExportRego renders ps as a self-contained OPA Rego module (package
+// guard) so the same policies can run inside existing OPA pipelines.
+// Querying data.guard.verdict with an EvalContext, encoded as JSON, as
+// input yields an object shaped like Verdict's JSON form: effect, channel,
+// policy_id, reason, code, obligations, annotations, require_reason,
+// channel_chain and source.
+//
+// The module implements the default engine semantics: tool aliases are
+// resolved, the first enabled policy in priority order whose condition
+// matches wins, context
+// fallbacks are walked when nothing matches the original mode, and the
+// defaults apply otherwise. Globs are matched with glob.match using "/" as
+// the delimiter, and prefix:/suffix: patterns with startswith and
+// endswith, mirroring GlobMatch. Result policies, which only
+// EvaluateResult considers, are left out. Conditions that have no Rego
+// equivalent here, model_version, days, "group:" users patterns,
+// require_present and the rate-limit effect, are rejected with an error.
+func ExportRego(ps *PolicySet) (string, error) {
+	policies := analysisOrder(ps)
+	data := make([]map[string]any, 0, len(policies))
+	for i := range policies {
+		if isResultPolicy(&policies[i]) || policies[i].DryRun {
+			continue
+		}
+		p, err := regoPolicy(&policies[i])
+		if err != nil {
+			return "", err
+		}
+		data = append(data, p)
+	}
+
+	defaults := map[string]any{
+		"effect":  ps.Defaults.Effect,
+		"channel": ps.Defaults.Channel,
+	}
+	if len(ps.Defaults.PerMode) > 0 {
+		defaults["per_mode"] = ps.Defaults.PerMode
+	}
+	if len(ps.Defaults.ChannelByEffect) > 0 {
+		defaults["channel_by_effect"] = ps.Defaults.ChannelByEffect
+	}
+
+	var b strings.Builder
+	b.WriteString("# Code generated by guard.ExportRego. DO NOT EDIT.\n")
+	if ps.Metadata.Name != "" {
+		fmt.Fprintf(&b, "# Policy set: %s\n", ps.Metadata.Name)
+	}
+	b.WriteString("#\n# Query data.guard.verdict with an EvalContext as input.\n")
+	b.WriteString("package guard\n\nimport rego.v1\n\n")
+	if err := writeRegoValue(&b, "defaults", defaults); err != nil {
+		return "", err
+	}
+	aliases := ps.Aliases
+	if aliases == nil {
+		aliases = map[string]string{}
+	}
+	b.WriteString("# Canonical names for aliased tools.\n")
+	if err := writeRegoValue(&b, "aliases", aliases); err != nil {
+		return "", err
+	}
+	b.WriteString("# Modes to try for each mode with context fallbacks, in order.\n")
+	if err := writeRegoValue(&b, "mode_chains", modeChains(ps.ContextFallbacks)); err != nil {
+		return "", err
+	}
+	b.WriteString("# Enabled policies in evaluation order: ascending priority, then ID.\n")
+	if err := writeRegoValue(&b, "policies", data); err != nil {
+		return "", err
+	}
+	b.WriteString(regoRules)
+	return b.String(), nil
+}
+
+// regoPolicy converts a policy to the data object the generated rules read.
+func regoPolicy(p *Policy) (map[string]any, error) {
+	if p.Effect == EffectRateLimit {
+		return nil, fmt.Errorf("guard: policy %q: the rate-limit effect cannot be exported to Rego", p.ID)
+	}
+	if len(p.RequirePresent) > 0 {
+		return nil, fmt.Errorf("guard: policy %q: require_present cannot be exported to Rego", p.ID)
+	}
+	cond, err := regoCondition(&p.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("guard: policy %q: %w", p.ID, err)
+	}
+
+	obligations := p.Obligations
+	if obligations == nil {
+		obligations = map[string]string{}
+	}
+	out := map[string]any{
+		"id":             p.ID,
+		"priority":       p.Priority,
+		"effect":         p.Effect,
+		"channel":        p.Channel,
+		"message":        p.Message,
+		"code":           p.Code,
+		"obligations":    obligations,
+		"require_reason": p.RequireReason,
+		"condition":      cond,
+	}
+	if p.Invert {
+		out["invert"] = true
+	}
+	if len(p.Annotations) > 0 {
+		out["annotations"] = p.Annotations
+	}
+	if len(p.Channels) > 0 {
+		out["channel_chain"] = p.Channels
+	}
+	if len(p.AnyOf) > 0 {
+		blocks := make([]map[string]any, len(p.AnyOf))
+		for i := range p.AnyOf {
+			if blocks[i], err = regoCondition(&p.AnyOf[i]); err != nil {
+				return nil, fmt.Errorf("guard: policy %q: any_of[%d]: %w", p.ID, i, err)
+			}
+		}
+		out["any_of"] = blocks
+	}
+	if p.ActiveFrom != nil {
+		out["active_from_ns"] = p.ActiveFrom.UnixNano()
+	}
+	if p.ExpiresAt != nil {
+		out["expires_at_ns"] = p.ExpiresAt.UnixNano()
+	}
+	return out, nil
+}
+
+// regoCondition converts a condition or AnyOf block to its data object.
+// Only constrained fields are emitted, so that an empty list (which
+// matches nothing) stays distinct from an absent one.
+func regoCondition(c *Condition) (map[string]any, error) {
+	if c.ModelVersion != "" {
+		return nil, fmt.Errorf("model_version cannot be exported to Rego")
+	}
+	if c.Days != nil {
+		return nil, fmt.Errorf("days cannot be exported to Rego")
+	}
+	for _, u := range c.Users {
+		if strings.HasPrefix(u, groupPrefix) {
+			return nil, fmt.Errorf("group patterns in users cannot be exported to Rego")
+		}
+	}
+	cond := map[string]any{}
+	for _, f := range []struct {
+		key  string
+		list []string
+	}{
+		{"modes", c.Modes}, {"models", c.Models}, {"channels", c.Channels},
+		{"tools", c.Tools}, {"mcp_servers", c.McpServers}, {"mcp_methods", c.McpMethods},
+		{"risk", c.Risk}, {"users", c.Users}, {"sessions", c.Sessions},
+		{"agents", c.Agents}, {"tenants", c.Tenants}, {"source_cidrs", c.SourceCIDRs},
+	} {
+		if f.list != nil {
+			cond[f.key] = f.list
+		}
+	}
+	if c.Args != nil {
+		cond["args"] = c.Args
+	}
+	if c.Tags != nil {
+		cond["tags"] = c.Tags
+	}
+	if c.CostGTE != nil {
+		cond["cost_gte"] = *c.CostGTE
+	}
+	if c.TokensGTE != nil {
+		cond["tokens_gte"] = *c.TokensGTE
+	}
+	if c.ReadOnly != nil {
+		cond["read_only"] = *c.ReadOnly
+	}
+	if c.Authenticated != nil {
+		cond["authenticated"] = *c.Authenticated
+	}
+	if c.SessionAgeGTE != nil {
+		cond["session_age_gte"] = *c.SessionAgeGTE
+	}
+	if c.RiskScoreGTE != nil {
+		cond["risk_score_gte"] = *c.RiskScoreGTE
+	}
+	if c.RiskScoreLTE != nil {
+		cond["risk_score_lte"] = *c.RiskScoreLTE
+	}
+	return cond, nil
+}
+
+// writeRegoValue writes "name := <json>" followed by a blank line. JSON is
+// valid Rego term syntax, and encoding/json sorts map keys, so the output
+// is deterministic.
+func writeRegoValue(b *strings.Builder, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return fmt.Errorf("guard: encoding %s for Rego: %w", name, err)
+	}
+	fmt.Fprintf(b, "%s := %s\n\n", name, data)
+	return nil
+}
+
+// regoRules is the fixed part of the generated module.
+const regoRules = `input_mode := object.get(input, "mode", "")
+
+input_tool := object.get(aliases, raw_tool, raw_tool) if {
+	raw_tool := object.get(input, "tool", "")
+}
+
+input_tools := [object.get(aliases, t, t) | some t in input.tools] if {
+	count(object.get(input, "tools", [])) > 0
+} else := [input_tool]
+
+input_risks := input.risks if {
+	count(object.get(input, "risks", [])) > 0
+} else := [object.get(input, "risk", "")]
+
+modes := object.get(mode_chains, input_mode, [input_mode])
+
+matches(mode) := [p | some p in policies; applies(p, mode)]
+
+# Indexes into modes at which some policy matches.
+matched_modes := {i | some i, mode in modes; count(matches(mode)) > 0}
+
+verdict := v if {
+	count(matched_modes) > 0
+	i := min(matched_modes)
+	p := matches(modes[i])[0]
+	v := {
+		"effect": p.effect,
+		"channel": p.channel,
+		"policy_id": p.id,
+		"reason": p.message,
+		"code": p.code,
+		"obligations": p.obligations,
+		"annotations": object.get(p, "annotations", {}),
+		"require_reason": p.require_reason,
+		"channel_chain": object.get(p, "channel_chain", []),
+		"source": source(i),
+	}
+}
+
+verdict := {"effect": default_effect, "channel": default_channel, "source": "default"} if {
+	count(matched_modes) == 0
+}
+
+default_channel := object.get(object.get(defaults, "channel_by_effect", {}), default_effect, defaults.channel)
+
+per_mode := object.get(defaults, "per_mode", {})
+
+# Indexes into modes that have a per-mode default effect.
+default_modes := {i | some i, mode in modes; per_mode[mode]}
+
+default_effect := per_mode[modes[min(default_modes)]] if count(default_modes) > 0
+
+default_effect := defaults.effect if count(default_modes) == 0
+
+source(0) := "matched"
+
+source(i) := "fallback_matched" if {
+	i > 0
+}
+
+applies(p, mode) if {
+	not p.invert
+	policy_matches(p, mode)
+	active(p)
+}
+
+applies(p, mode) if {
+	p.invert
+	not policy_matches(p, mode)
+	active(p)
+}
+
+policy_matches(p, mode) if {
+	condition_matches(p.condition, mode)
+	any_of_matches(p, mode)
+}
+
+condition_matches(cond, mode) if {
+	patterns_match(cond, "modes", mode)
+	patterns_match(cond, "models", object.get(input, "model", ""))
+	patterns_match(cond, "channels", object.get(input, "channel", ""))
+	some_value_matches(cond, "tools", input_tools)
+	some_value_matches(cond, "risk", input_risks)
+	patterns_match(cond, "users", object.get(input, "user", ""))
+	mcp_server_matches(cond)
+	mcp_method_matches(cond)
+	session_matches(cond)
+	agent_matches(cond)
+	tenant_matches(cond)
+	read_only_matches(cond)
+	authenticated_matches(cond)
+	keyed_match(cond, "args", object.get(input, "args", {}))
+	keyed_match(cond, "tags", object.get(input, "tags", {}))
+	source_ip_matches(cond)
+	at_least(cond, "cost_gte", object.get(input, "estimated_cost_usd", 0))
+	at_least(cond, "tokens_gte", object.get(input, "token_count", 0))
+	at_least(cond, "session_age_gte", object.get(input, "session_age_seconds", 0))
+	at_least(cond, "risk_score_gte", object.get(input, "risk_score", 0))
+	at_most(cond, "risk_score_lte", object.get(input, "risk_score", 0))
+}
+
+any_of_matches(p, _) if {
+	not p.any_of
+}
+
+any_of_matches(p, mode) if {
+	some cond in p.any_of
+	condition_matches(cond, mode)
+}
+
+patterns_match(cond, field, _) if {
+	not cond[field]
+}
+
+patterns_match(cond, field, value) if {
+	some pattern in cond[field]
+	glob_matches(pattern, value)
+}
+
+some_value_matches(cond, field, values) if {
+	some value in values
+	patterns_match(cond, field, value)
+}
+
+glob_matches(pattern, _) if {
+	pattern == "*"
+}
+
+glob_matches(pattern, value) if {
+	pattern != ""
+	not anchored(pattern)
+	glob.match(pattern, ["/"], value)
+}
+
+glob_matches(pattern, value) if {
+	startswith(pattern, "prefix:")
+	startswith(value, substring(pattern, 7, -1))
+}
+
+glob_matches(pattern, value) if {
+	startswith(pattern, "suffix:")
+	endswith(value, substring(pattern, 7, -1))
+}
+
+anchored(pattern) if {
+	startswith(pattern, "prefix:")
+}
+
+anchored(pattern) if {
+	startswith(pattern, "suffix:")
+}
+
+mcp_server_matches(cond) if {
+	not cond.mcp_servers
+}
+
+mcp_server_matches(cond) if {
+	server := object.get(input, "mcp_server", "")
+	server != ""
+	patterns_match(cond, "mcp_servers", server)
+}
+
+mcp_method_matches(cond) if {
+	not cond.mcp_methods
+}
+
+mcp_method_matches(cond) if {
+	method := object.get(input, "mcp_method", "")
+	method != ""
+	patterns_match(cond, "mcp_methods", method)
+}
+
+session_matches(cond) if {
+	not cond.sessions
+}
+
+session_matches(cond) if {
+	session := object.get(input, "session", "")
+	session != ""
+	patterns_match(cond, "sessions", session)
+}
+
+agent_matches(cond) if {
+	not cond.agents
+}
+
+agent_matches(cond) if {
+	agent := object.get(input, "agent", "")
+	agent != ""
+	patterns_match(cond, "agents", agent)
+}
+
+tenant_matches(cond) if {
+	not cond.tenants
+}
+
+read_only_matches(cond) if {
+	object.get(cond, "read_only", null) == null
+}
+
+read_only_matches(cond) if {
+	input.read_only == cond.read_only
+}
+
+authenticated_matches(cond) if {
+	object.get(cond, "authenticated", null) == null
+}
+
+authenticated_matches(cond) if {
+	object.get(input, "authenticated", false) == cond.authenticated
+}
+
+tenant_matches(cond) if {
+	tenant := object.get(input, "tenant", "")
+	tenant != ""
+	patterns_match(cond, "tenants", tenant)
+}
+
+keyed_match(cond, field, _) if {
+	not cond[field]
+}
+
+keyed_match(cond, field, values) if {
+	every key, patterns in cond[field] {
+		some pattern in patterns
+		glob_matches(pattern, values[key])
+	}
+}
+
+source_ip_matches(cond) if {
+	not cond.source_cidrs
+}
+
+source_ip_matches(cond) if {
+	some cidr in cond.source_cidrs
+	net.cidr_contains(cidr, input.source_ip)
+}
+
+at_least(cond, field, _) if {
+	not cond[field]
+}
+
+at_least(cond, field, value) if {
+	value >= cond[field]
+}
+
+at_most(cond, field, _) if {
+	not cond[field]
+}
+
+at_most(cond, field, value) if {
+	value <= cond[field]
+}
+
+now_ns := time.parse_rfc3339_ns(input.now) if {
+	input.now
+} else := time.now_ns()
+
+active(p) if {
+	object.get(p, "active_from_ns", now_ns) <= now_ns
+	now_ns <= object.get(p, "expires_at_ns", now_ns)
+}
+`
@@ -0,0 +1,93 @@
+package guard
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestExportRegoGolden(t *testing.T) {
+	for _, name := range []string{"permissive", "balanced", "restrictive"} {
+		t.Run(name, func(t *testing.T) {
+			ps, err := LoadPolicySet(filepath.Join("..", "examples", name+".yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ExportRego(ps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "rego", name+".rego")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestExportRegoGolden -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("generated Rego differs from %s; rerun with -update and review the diff", golden)
+			}
+		})
+	}
+}
+
+func TestExportRegoEncodesConditions(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "off", Effect: EffectDeny, Priority: 1, Enabled: boolPtr(false)},
+		{ID: "none", Effect: EffectDeny, Priority: 2, Condition: Condition{Tools: []string{}}},
+		{ID: "args", Effect: EffectAsk, Priority: 3, Condition: Condition{
+			Args: map[string][]string{"path": {"/etc/**"}},
+		}},
+		{ID: "either", Effect: EffectDeny, Priority: 4, AnyOf: []Condition{
+			{Tools: []string{"rm"}}, {Risk: []string{"critical"}},
+		}},
+		{ID: "not-view", Effect: EffectAsk, Priority: 5, Invert: true, Condition: Condition{Tools: []string{"view"}}},
+	}, EffectAllow)
+	ps.ContextFallbacks = map[string]string{"scheduler": "background", "background": "scheduler"}
+	ps.Aliases = map[string]string{"shell": "bash"}
+	got, err := ExportRego(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"tools": []`, // an empty list must survive: it matches nothing
+		`"/etc/**"`,
+		`"invert": true`,
+		"aliases := {\n\t\"shell\": \"bash\"\n}",
+		"\"any_of\": [\n\t\t\t{\n\t\t\t\t\"tools\": [\n\t\t\t\t\t\"rm\"",
+		// chains stop at the first repeated mode
+		"\"scheduler\": [\n\t\t\"scheduler\",\n\t\t\"background\"\n\t]",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q", want)
+		}
+	}
+	if strings.Contains(got, `"off"`) {
+		t.Error("disabled policy should be omitted")
+	}
+}
+
+func TestExportRegoRejectsUnsupported(t *testing.T) {
+	cases := map[string]Policy{
+		"model_version":            {ID: "v", Effect: EffectDeny, Condition: Condition{ModelVersion: ">=5"}},
+		"rate-limit":               {ID: "r", Effect: EffectRateLimit, RateLimit: &RateLimit{Max: 1}},
+		"group":                    {ID: "g", Effect: EffectAllow, Condition: Condition{Users: []string{"group:oncall"}}},
+		"any_of[1]: model_version": {ID: "a", Effect: EffectDeny, AnyOf: []Condition{{Tools: []string{"rm"}}, {ModelVersion: ">=5"}}},
+	}
+	for want, p := range cases {
+		_, err := ExportRego(makePolicySet([]Policy{p}, EffectAllow))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+}
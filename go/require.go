@@ -0,0 +1,158 @@
+package guard
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ── Required context fields ────────────────────────────────────────────
+
+// requiredValue returns the context value that Policy.RequirePresent
+// field name requires, and whether name is a field it accepts. Tools is
+// left out: every invocation names its tool.
+func requiredValue(ctx *EvalContext, name string) (string, bool) {
+	switch name {
+	case "modes":
+		return ctx.Mode, true
+	case "models":
+		return ctx.Model, true
+	case "channels":
+		return ctx.Channel, true
+	case "mcp_servers":
+		return ctx.McpServer, true
+	case "mcp_methods":
+		return ctx.McpMethod, true
+	case "risk":
+		if len(ctx.Risks) > 0 {
+			// Present if any of them is.
+			return strings.Join(ctx.Risks, ""), true
+		}
+		return ctx.Risk, true
+	case "users":
+		return ctx.User, true
+	case "sessions":
+		return ctx.Session, true
+	case "agents":
+		return ctx.Agent, true
+	case "tenants":
+		return ctx.Tenant, true
+	case "source_cidrs":
+		return ctx.SourceIP, true
+	}
+	return "", false
+}
+
+// requiredList returns the condition list constraining a field that
+// requiredValue accepts.
+func requiredList(c *Condition, name string) *[]string {
+	switch name {
+	case "modes":
+		return &c.Modes
+	case "models":
+		return &c.Models
+	case "channels":
+		return &c.Channels
+	case "mcp_servers":
+		return &c.McpServers
+	case "mcp_methods":
+		return &c.McpMethods
+	case "risk":
+		return &c.Risk
+	case "users":
+		return &c.Users
+	case "sessions":
+		return &c.Sessions
+	case "agents":
+		return &c.Agents
+	case "tenants":
+		return &c.Tenants
+	case "source_cidrs":
+		return &c.SourceCIDRs
+	}
+	return nil
+}
+
+// validateRequirePresent checks the field names in p.RequirePresent.
+func validateRequirePresent(p *Policy) error {
+	for _, name := range p.RequirePresent {
+		if _, ok := requiredValue(&EvalContext{}, name); !ok {
+			return fmt.Errorf("require_present: unknown field %q: want one of agents, channels, mcp_methods, mcp_servers, models, modes, risk, sessions, source_cidrs, tenants or users", name)
+		}
+	}
+	if len(p.RequirePresent) > 0 && p.Invert {
+		return fmt.Errorf("require_present cannot be combined with invert")
+	}
+	return nil
+}
+
+// compileRequired sets up cc, compiled from p, to fail closed on the
+// fields p requires: relaxed is p's condition without those fields, which
+// tells whether the rest of the policy matches.
+func (cc *compiledCondition) compileRequired(p *Policy) {
+	if len(p.RequirePresent) == 0 {
+		return
+	}
+	cc.required = p.RequirePresent
+	relaxed := *p
+	relaxed.RequirePresent = nil
+	relaxed.AnyOf = append([]Condition(nil), p.AnyOf...)
+	for _, name := range p.RequirePresent {
+		if requiredList(&relaxed.Condition, name) == nil {
+			continue // rejected by the loader
+		}
+		*requiredList(&relaxed.Condition, name) = nil
+		for i := range relaxed.AnyOf {
+			*requiredList(&relaxed.AnyOf[i], name) = nil
+		}
+	}
+	r := compilePolicy(&relaxed)
+	cc.relaxed = &r
+}
+
+// missingField returns the first required field ctx has no value for, or
+// "" if none is missing.
+func (cc *compiledCondition) missingField(ctx *EvalContext) string {
+	for _, name := range cc.required {
+		if v, ok := requiredValue(ctx, name); ok && v == "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// failsClosed reports the required field ctx is missing if the rest of
+// the policy matches ctx, in which case the policy denies rather than
+// letting evaluation fall through. ctx must not match the policy.
+func (cc *compiledCondition) failsClosed(ctx EvalContext) string {
+	if cc.relaxed == nil {
+		return ""
+	}
+	field := cc.missingField(&ctx)
+	if field == "" || !cc.relaxed.matches(ctx) {
+		return ""
+	}
+	return field
+}
+
+// missingVerdict is the deny verdict of the policy at index i when the
+// context lacks the required field. It carries the policy's fields like a
+// matched verdict, but the default channel for deny rather than the
+// policy's own approval channels.
+func (st *engineState) missingVerdict(i int, field string) Verdict {
+	p := &st.policies[i]
+	reason := p.Message
+	if reason == "" {
+		reason = fmt.Sprintf("policy %s requires %s to be present", p.ID, field)
+	}
+	return Verdict{
+		Effect:        EffectDeny,
+		Channel:       st.defaults.channelFor(EffectDeny),
+		PolicyID:      p.ID,
+		Reason:        reason,
+		Code:          p.Code,
+		Obligations:   copyStringMap(p.Obligations),
+		Annotations:   copyStringMap(p.Annotations),
+		Source:        SourceMissingField,
+		RequireReason: p.RequireReason,
+	}
+}
@@ -0,0 +1,121 @@
+package guard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func requirePolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "admins-deploy", Priority: 1, Effect: EffectAllow, RequirePresent: []string{"users"},
+			Condition: Condition{Tools: []string{"deploy"}, Users: []string{"admin-*"}}},
+		{ID: "deny-deploy", Priority: 5, Effect: EffectDeny, Condition: Condition{Tools: []string{"deploy"}}},
+		{ID: "session-bash", Priority: 5, Effect: EffectAsk, RequirePresent: []string{"sessions"},
+			Message: "bash needs a session", Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+}
+
+func TestRequirePresentFailsClosed(t *testing.T) {
+	ps := requirePolicySet()
+	ps.Policies[1].Effect = EffectAsk // would otherwise catch the deploy without a user
+	engine := NewPolicyEngine(ps)
+
+	tests := []struct {
+		name     string
+		ctx      EvalContext
+		effect   Effect
+		policyID string
+		source   VerdictSource
+	}{
+		{"admin", EvalContext{Tool: "deploy", User: "admin-alice"}, EffectAllow, "admins-deploy", SourceMatched},
+		{"other user", EvalContext{Tool: "deploy", User: "bob"}, EffectAsk, "deny-deploy", SourceMatched},
+		{"no user", EvalContext{Tool: "deploy"}, EffectDeny, "admins-deploy", SourceMissingField},
+		{"session", EvalContext{Tool: "bash", Session: "s1"}, EffectAsk, "session-bash", SourceMatched},
+		{"no session", EvalContext{Tool: "bash"}, EffectDeny, "session-bash", SourceMissingField},
+		{"rest does not match", EvalContext{Tool: "view"}, EffectAllow, "", SourceDefault},
+	}
+	for _, tt := range tests {
+		v := engine.Evaluate(tt.ctx)
+		if v.Effect != tt.effect || v.PolicyID != tt.policyID || v.Source != tt.source {
+			t.Errorf("%s: got %s from %q (%s), want %s from %q (%s)", tt.name, v.Effect, v.PolicyID, v.Source, tt.effect, tt.policyID, tt.source)
+		}
+	}
+
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Reason != "bash needs a session" {
+		t.Errorf("reason = %q, want the policy's message", v.Reason)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); !strings.Contains(v.Reason, "users") {
+		t.Errorf("reason = %q, want it to name the field", v.Reason)
+	}
+}
+
+func TestRequirePresentVerdictFields(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "session-bash", Effect: EffectAsk, RequirePresent: []string{"sessions"}, Channel: ChannelPhone,
+			Code: "needs-session", RequireReason: true,
+			Obligations: map[string]string{"log": "full"}, Annotations: map[string]string{"owner": "platform"},
+			Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow)
+	ps.Defaults.Channel = ChannelChat
+	ps.Defaults.ChannelByEffect = map[Effect]Channel{EffectDeny: "slack"}
+	v := NewPolicyEngine(ps).Evaluate(EvalContext{Tool: "bash"})
+	if v.Effect != EffectDeny || v.Source != SourceMissingField {
+		t.Fatalf("expected a fail-closed deny, got %+v", v)
+	}
+	if v.Channel != "slack" {
+		t.Errorf("channel = %q, want the default channel for deny", v.Channel)
+	}
+	if v.Code != "needs-session" || !v.RequireReason || v.Obligations["log"] != "full" || v.Annotations["owner"] != "platform" {
+		t.Errorf("verdict does not carry the policy's fields: %+v", v)
+	}
+}
+
+func TestRequirePresentBlocksFallbacks(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "user-bash", Effect: EffectAsk, RequirePresent: []string{"users"}, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"auto"}}},
+		{ID: "allow-bash", Effect: EffectAllow, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"safe"}}},
+	}, EffectAllow)
+	ps.ContextFallbacks = map[string]string{"auto": "safe"}
+	engine := NewPolicyEngine(ps)
+
+	if v := engine.Evaluate(EvalContext{Mode: "auto", Tool: "bash"}); v.Effect != EffectDeny || v.Source != SourceMissingField {
+		t.Errorf("got %+v", v)
+	}
+	v, results := engine.EvaluateDetailed(EvalContext{Mode: "auto", Tool: "bash"})
+	if v.Effect != EffectDeny || v.Source != SourceMissingField {
+		t.Errorf("detailed: got %+v", v)
+	}
+	for _, r := range results {
+		if r.Winner != (r.PolicyID == "user-bash") || r.Matched {
+			t.Errorf("detailed result = %+v", r)
+		}
+	}
+}
+
+func TestRequirePresentAfterSettledWinner(t *testing.T) {
+	ps := requirePolicySet()
+	ps.Policies = append(ps.Policies, Policy{ID: "allow-bash", Effect: EffectAllow, Condition: Condition{Tools: []string{"bash"}}})
+	engine := NewPolicyEngine(ps)
+	// allow-bash has priority 0, so it decides before the requirement of
+	// session-bash is reached.
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "allow-bash" {
+		t.Errorf("got %+v", v)
+	}
+}
+
+func TestRequirePresentValidated(t *testing.T) {
+	for _, tt := range []struct {
+		policy Policy
+		want   string
+	}{
+		{Policy{ID: "p", Effect: EffectDeny, RequirePresent: []string{"user"}}, `unknown field "user"`},
+		{Policy{ID: "p", Effect: EffectDeny, RequirePresent: []string{"tools"}}, `unknown field "tools"`},
+		{Policy{ID: "p", Effect: EffectDeny, RequirePresent: []string{"users"}, Invert: true}, "invert"},
+	} {
+		err := validatePolicy(0, &tt.policy, loadOptions{})
+		if !errors.Is(err, ErrInvalidPolicy) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: got %v, want an error containing %q", tt.policy.RequirePresent, err, tt.want)
+		}
+	}
+}
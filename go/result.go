@@ -0,0 +1,62 @@
+package guard
+
+import "context"
+
+// ── Result evaluation ──────────────────────────────────────────────────
+
+// ResultContext describes what a tool produced, for guardrails that apply
+// after it has run, such as filtering output that contains secrets.
+type ResultContext struct {
+	// OutputBytes is the size of the tool's output, matched by
+	// Condition.OutputBytesGTE.
+	OutputBytes int `json:"output_bytes,omitempty"`
+	// Categories are the kinds of content detected in the output by the
+	// caller's scanners, e.g. secret or pii, matched by
+	// Condition.OutputCategories.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// EvaluateResult returns a Verdict for the result of an invocation that
+// Evaluate has already allowed, typically filter or deny for output that
+// must not reach the model. Only result policies, those whose condition
+// or an AnyOf block sets OutputBytesGTE or OutputCategories, are
+// considered; Evaluate in turn ignores them. The rest of the condition is
+// matched against ctx as usual, with context fallbacks.
+//
+// If no result policy matches, the verdict is allow with source default:
+// output passes through unless a policy says otherwise. Pre-hooks,
+// post-hooks, observers and the audit sink apply; the decision cache does
+// not.
+func (e *PolicyEngine) EvaluateResult(ctx EvalContext, result ResultContext) Verdict {
+	st := e.state.Load()
+	if v, ok := e.killSwitched(st, ctx); ok {
+		return v
+	}
+	ctx = e.prepare(st, ctx)
+	ctx.result = &result
+	obs, sink := e.observer.Load(), e.audit.Load()
+	start := e.clock.Now()
+	v, _ := e.evaluate(context.Background(), st, ctx, nil)
+	v = e.runPostHooks(ctx, v)
+	if obs != nil || sink != nil {
+		e.notify(obs, sink, start, ctx, v)
+	}
+	return v
+}
+
+// isResultPolicy reports whether p gates on a tool's result.
+func isResultPolicy(p *Policy) bool {
+	if p.Condition.gatesOnResult() {
+		return true
+	}
+	for i := range p.AnyOf {
+		if p.AnyOf[i].gatesOnResult() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Condition) gatesOnResult() bool {
+	return c.OutputBytesGTE != nil || c.OutputCategories != nil
+}
@@ -0,0 +1,91 @@
+package guard
+
+import "testing"
+
+func resultPolicySet() *PolicySet {
+	large := 1 << 20
+	return makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "filter-large", Effect: EffectFilter, Priority: 20, Message: "output truncated",
+			Condition: Condition{Tools: []string{"bash", "read_file"}, OutputBytesGTE: &large}},
+		{ID: "deny-secrets", Effect: EffectDeny, Priority: 5,
+			Condition: Condition{OutputCategories: []string{"secret*"}}},
+	}, EffectAsk)
+}
+
+func TestEvaluateResultOutputSize(t *testing.T) {
+	engine := NewPolicyEngine(resultPolicySet())
+	tests := []struct {
+		name   string
+		ctx    EvalContext
+		result ResultContext
+		want   Effect
+		id     string
+	}{
+		{"large output", EvalContext{Tool: "read_file"}, ResultContext{OutputBytes: 2 << 20}, EffectFilter, "filter-large"},
+		{"at threshold", EvalContext{Tool: "read_file"}, ResultContext{OutputBytes: 1 << 20}, EffectFilter, "filter-large"},
+		{"small output", EvalContext{Tool: "read_file"}, ResultContext{OutputBytes: 100}, EffectAllow, ""},
+		{"other tool", EvalContext{Tool: "view"}, ResultContext{OutputBytes: 2 << 20}, EffectAllow, ""},
+		// deny-bash is a pre-execution policy and plays no part here.
+		{"pre policies ignored", EvalContext{Tool: "bash"}, ResultContext{OutputBytes: 2 << 20}, EffectFilter, "filter-large"},
+		{"category", EvalContext{Tool: "view"}, ResultContext{Categories: []string{"pii", "secret.aws"}}, EffectDeny, "deny-secrets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := engine.EvaluateResult(tt.ctx, tt.result)
+			if v.Effect != tt.want || v.PolicyID != tt.id {
+				t.Errorf("got %s/%q, want %s/%q", v.Effect, v.PolicyID, tt.want, tt.id)
+			}
+		})
+	}
+}
+
+func TestEvaluateIgnoresResultPolicies(t *testing.T) {
+	engine := NewPolicyEngine(resultPolicySet())
+	if v := engine.Evaluate(EvalContext{Tool: "read_file"}); v.Source != SourceDefault || v.Effect != EffectAsk {
+		t.Errorf("expected the default verdict, got %+v", v)
+	}
+	for _, r := range engine.EvaluateMatched(EvalContext{Tool: "read_file"}) {
+		t.Errorf("result policy matched before execution: %+v", r)
+	}
+
+	// An inverted result policy still waits for a result.
+	ps := makePolicySet([]Policy{
+		{ID: "not-secret", Effect: EffectDeny, Invert: true, Condition: Condition{OutputCategories: []string{"public"}}},
+	}, EffectAllow)
+	engine = NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Tool: "view"}); v.Effect != EffectAllow {
+		t.Errorf("expected allow before execution, got %+v", v)
+	}
+	if v := engine.EvaluateResult(EvalContext{Tool: "view"}, ResultContext{}); v.PolicyID != "not-secret" {
+		t.Errorf("expected not-secret for an uncategorized result, got %+v", v)
+	}
+}
+
+func TestResultPolicyLoadedFromYAML(t *testing.T) {
+	ps, err := LoadPolicySetFromBytes([]byte(`
+policies:
+  - id: filter-large
+    effect: filter
+    condition:
+      tools: [read_file]
+      output_bytes_gte: 65536
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if v := engine.EvaluateResult(EvalContext{Tool: "read_file"}, ResultContext{OutputBytes: 70000}); v.Effect != EffectFilter {
+		t.Errorf("expected filter, got %+v", v)
+	}
+
+	if _, err := LoadPolicySetFromBytes([]byte(`
+policies:
+  - id: bad
+    effect: filter
+    condition:
+      output_bytes_gte: -1
+`)); err == nil {
+		t.Error("expected an error for a negative output_bytes_gte")
+	}
+}
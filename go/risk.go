@@ -0,0 +1,27 @@
+package guard
+
+// ── Risk scoring ───────────────────────────────────────────────────────
+
+// RiskScorer classifies an invocation into a risk level such as "low" or
+// "high", for callers that do not compute EvalContext.Risk themselves.
+// Implementations must be safe for concurrent use.
+type RiskScorer interface {
+	Score(ctx EvalContext) string
+}
+
+// WithRiskScorer makes the engine fill in EvalContext.Risk with s when the
+// caller leaves it and Risks empty, before any policy is matched. A risk
+// supplied by the caller is never overridden.
+func WithRiskScorer(s RiskScorer) Option {
+	return func(e *PolicyEngine) {
+		e.scorer = s
+	}
+}
+
+// scoreRisk returns ec with Risk filled in by the engine's scorer, if any.
+func (e *PolicyEngine) scoreRisk(ec EvalContext) EvalContext {
+	if e.scorer != nil && ec.Risk == "" && len(ec.Risks) == 0 {
+		ec.Risk = e.scorer.Score(ec)
+	}
+	return ec
+}
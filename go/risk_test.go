@@ -0,0 +1,69 @@
+package guard
+
+import "testing"
+
+// toolRiskScorer maps tool names to risk levels, defaulting to "low".
+type toolRiskScorer map[string]string
+
+func (s toolRiskScorer) Score(ctx EvalContext) string {
+	if r, ok := s[ctx.Tool]; ok {
+		return r
+	}
+	return "low"
+}
+
+func riskPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "deny-critical", Effect: EffectDeny, Priority: 10, Condition: Condition{Risk: []string{"critical"}}},
+		{ID: "ask-high", Effect: EffectAsk, Priority: 20, Condition: Condition{Risk: []string{"high"}}},
+	}, EffectAllow)
+}
+
+func TestRiskScorerFillsEmptyRisk(t *testing.T) {
+	scorer := toolRiskScorer{"rm": "critical", "bash": "high"}
+	engine := NewPolicyEngineWithOptions(riskPolicySet(), WithRiskScorer(scorer))
+
+	cases := []struct {
+		tool string
+		want Effect
+	}{
+		{"rm", EffectDeny},
+		{"bash", EffectAsk},
+		{"view", EffectAllow},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Tool: tc.tool}); v.Effect != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.tool, tc.want, v.Effect)
+		}
+	}
+	if !resultMatched(engine.EvaluateAll(EvalContext{Tool: "rm"}), "deny-critical") {
+		t.Error("EvaluateAll: expected deny-critical to match a scored context")
+	}
+	if v, _ := engine.EvaluateDetailed(EvalContext{Tool: "bash"}); v.PolicyID != "ask-high" {
+		t.Errorf("EvaluateDetailed: expected ask-high, got %q", v.PolicyID)
+	}
+}
+
+func TestRiskScorerKeepsCallerRisk(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(riskPolicySet(), WithRiskScorer(toolRiskScorer{"rm": "critical"}))
+	if v := engine.Evaluate(EvalContext{Tool: "rm", Risk: "high"}); v.PolicyID != "ask-high" {
+		t.Errorf("expected caller risk to win, got %q", v.PolicyID)
+	}
+}
+
+func TestRiskScorerVisibleToAudit(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(riskPolicySet(), WithRiskScorer(toolRiskScorer{"rm": "critical"}))
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+	engine.Evaluate(EvalContext{Tool: "rm"})
+	if len(sink.entries) != 1 || sink.entries[0].Context.Risk != "critical" {
+		t.Errorf("expected audited risk critical, got %+v", sink.entries)
+	}
+}
+
+func TestRiskScorerSkipsRisks(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(riskPolicySet(), WithRiskScorer(toolRiskScorer{"rm": "critical"}))
+	if v := engine.Evaluate(EvalContext{Tool: "rm", Risks: []string{"low", "high"}}); v.PolicyID != "ask-high" {
+		t.Errorf("got %q, want ask-high from the caller's risks", v.PolicyID)
+	}
+}
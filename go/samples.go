@@ -0,0 +1,104 @@
+package guard
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// ── Sample contexts ────────────────────────────────────────────────────
+
+// GenerateSampleContexts returns representative contexts for ps, one for
+// each distinct value listed in each enabled policy's condition (every
+// mode, tool, risk level and so on), so a test can assert the verdict for
+// each and document what every rule does.
+//
+// Each context is built to match the policy the value came from: the
+// value's field is set to it and the policy's other fields to a matching
+// sample. Glob patterns yield a synthetic value they match, e.g. "mcp:x"
+// for "mcp:*". A policy with no listed values contributes a single
+// context. Duplicates are dropped, and the order follows policy
+// precedence. Values that cannot be sampled, such as an unsatisfiable
+// model_version, are skipped, and so are inverted policies.
+func GenerateSampleContexts(ps *PolicySet) []EvalContext {
+	var out []EvalContext
+	seen := make(map[string]bool)
+	add := func(ec EvalContext) {
+		key, err := json.Marshal(ec)
+		if err != nil || seen[string(key)] {
+			return
+		}
+		seen[string(key)] = true
+		out = append(out, ec)
+	}
+
+	policies := analysisOrder(ps)
+	for i := range policies {
+		cc := compilePolicy(&policies[i])
+		for _, v := range anyOfVariants(&policies[i], true) {
+			sampled := false
+			for _, narrowed := range narrowedPolicies(&v) {
+				if ec, ok := overlapSample(&narrowed, &narrowed, &cc, &cc); ok {
+					add(ec)
+					sampled = true
+				}
+			}
+			if !sampled {
+				if ec, ok := overlapSample(&v, &v, &cc, &cc); ok {
+					add(ec)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// narrowedPolicies returns one copy of p per pattern in its condition's
+// lists and keyed fields, with that field narrowed to the single pattern.
+func narrowedPolicies(p *Policy) []Policy {
+	var out []Policy
+	lists := []func(c *Condition) *[]string{
+		func(c *Condition) *[]string { return &c.Modes },
+		func(c *Condition) *[]string { return &c.Models },
+		func(c *Condition) *[]string { return &c.Channels },
+		func(c *Condition) *[]string { return &c.Tools },
+		func(c *Condition) *[]string { return &c.McpServers },
+		func(c *Condition) *[]string { return &c.McpMethods },
+		func(c *Condition) *[]string { return &c.Agents },
+		func(c *Condition) *[]string { return &c.Tenants },
+		func(c *Condition) *[]string { return &c.Risk },
+		func(c *Condition) *[]string { return &c.Users },
+		func(c *Condition) *[]string { return &c.Sessions },
+	}
+	for _, field := range lists {
+		for _, pattern := range *field(&p.Condition) {
+			n := *p
+			*field(&n.Condition) = []string{pattern}
+			out = append(out, n)
+		}
+	}
+	maps := []func(c *Condition) *map[string][]string{
+		func(c *Condition) *map[string][]string { return &c.Args },
+		func(c *Condition) *map[string][]string { return &c.Tags },
+	}
+	for _, field := range maps {
+		m := *field(&p.Condition)
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, pattern := range m[key] {
+				narrowed := make(map[string][]string, len(m))
+				for k, v := range m {
+					narrowed[k] = v
+				}
+				narrowed[key] = []string{pattern}
+				n := *p
+				*field(&n.Condition) = narrowed
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package guard
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateSampleContexts(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "deny-shell-bg", Effect: EffectDeny, Priority: 10, Condition: Condition{
+			Tools: []string{"bash", "sh"},
+			Modes: []string{"background"},
+		}},
+		{ID: "ask-mcp", Effect: EffectAsk, Priority: 20, Condition: Condition{Tools: []string{"mcp:*"}}},
+		{ID: "deny-etc", Effect: EffectDeny, Priority: 30, Condition: Condition{
+			Tools: []string{"write"},
+			Args:  map[string][]string{"path": {"/etc/*", "/usr/*"}},
+		}},
+		{ID: "catch-all", Effect: EffectAllow, Priority: 40},
+		{ID: "off", Effect: EffectDeny, Enabled: boolPtr(false), Condition: Condition{Tools: []string{"rm"}}},
+	}, EffectAsk)
+
+	want := []EvalContext{
+		{Mode: "background", Tool: "bash"},
+		{Mode: "background", Tool: "sh"},
+		{Tool: "mcp:"},
+		{Tool: "write", Args: map[string]string{"path": "/etc/"}},
+		{Tool: "write", Args: map[string]string{"path": "/usr/"}},
+		{},
+	}
+	got := GenerateSampleContexts(ps)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+
+	engine := NewPolicyEngine(ps)
+	wantIDs := []string{"deny-shell-bg", "deny-shell-bg", "ask-mcp", "deny-etc", "deny-etc", "catch-all"}
+	for i, ec := range got {
+		if v := engine.Evaluate(ec); v.PolicyID != wantIDs[i] {
+			t.Errorf("%+v: expected %s, got %q", ec, wantIDs[i], v.PolicyID)
+		}
+	}
+}
+
+func TestGenerateSampleContextsAnyOf(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "either", Effect: EffectDeny, Condition: Condition{Modes: []string{"prod"}}, AnyOf: []Condition{
+			{Tools: []string{"rm"}}, {Risk: []string{"critical"}},
+		}},
+	}, EffectAllow)
+	engine := NewPolicyEngine(ps)
+	got := GenerateSampleContexts(ps)
+	if len(got) != 2 {
+		t.Fatalf("expected one context per block, got %+v", got)
+	}
+	for _, ec := range got {
+		if v := engine.Evaluate(ec); v.PolicyID != "either" {
+			t.Errorf("%+v: expected either to match, got %q", ec, v.PolicyID)
+		}
+	}
+}
+
+func TestGenerateSampleContextsExamples(t *testing.T) {
+	ps, err := LoadPolicySet(filepath.Join("..", "examples", "balanced.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := GenerateSampleContexts(ps); len(got) == 0 {
+		t.Error("expected sample contexts for the balanced example")
+	}
+}
@@ -0,0 +1,120 @@
+package guard
+
+import (
+	"context"
+	"sort"
+)
+
+// ── Simulation ─────────────────────────────────────────────────────────
+
+// SimReport summarises how verdicts change when a candidate engine replaces
+// the current one over a sample of traffic. A verdict counts as changed when
+// its effect differs.
+type SimReport struct {
+	Total       int              `json:"total"`
+	Unchanged   int              `json:"unchanged"`
+	Changed     int              `json:"changed"`
+	Transitions []SimTransition  `json:"transitions,omitempty"`
+	Policies    []SimAttribution `json:"policies,omitempty"`
+}
+
+// SimTransition counts the verdicts that flipped from one effect to another.
+type SimTransition struct {
+	From  Effect `json:"from"`
+	To    Effect `json:"to"`
+	Count int    `json:"count"`
+}
+
+// SimAttribution records how often a policy decided a verdict under each
+// engine, and how many changed verdicts it was involved in. PolicyID is
+// empty for verdicts that fell through to the defaults.
+type SimAttribution struct {
+	PolicyID string `json:"policy_id"`
+	OldWins  int    `json:"old_wins"`
+	NewWins  int    `json:"new_wins"`
+	Changed  int    `json:"changed"`
+}
+
+// Simulate evaluates ctxs against both engines and reports which verdicts
+// would change. Transitions are sorted by descending count, then by effect;
+// policies are sorted by ID.
+//
+// Simulation has no side effects on either engine: hit counts and
+// rate-limit counters are left untouched, the decision cache, kill switch
+// and deprecation warner are not consulted, and observers and audit sinks
+// are not notified. Pre- and post-hooks still shape each verdict.
+func Simulate(old, new *PolicyEngine, ctxs []EvalContext) SimReport {
+	before := old.evaluateSimulatedBatch(ctxs)
+	after := new.evaluateSimulatedBatch(ctxs)
+
+	report := SimReport{Total: len(ctxs)}
+	transitions := make(map[[2]Effect]int)
+	policies := make(map[string]*SimAttribution)
+	attribution := func(id string) *SimAttribution {
+		a, ok := policies[id]
+		if !ok {
+			a = &SimAttribution{PolicyID: id}
+			policies[id] = a
+		}
+		return a
+	}
+
+	for i := range ctxs {
+		o, n := before[i], after[i]
+		attribution(o.PolicyID).OldWins++
+		attribution(n.PolicyID).NewWins++
+		if o.Effect == n.Effect {
+			report.Unchanged++
+			continue
+		}
+		report.Changed++
+		transitions[[2]Effect{o.Effect, n.Effect}]++
+		attribution(o.PolicyID).Changed++
+		if n.PolicyID != o.PolicyID {
+			attribution(n.PolicyID).Changed++
+		}
+	}
+
+	for pair, count := range transitions {
+		report.Transitions = append(report.Transitions, SimTransition{From: pair[0], To: pair[1], Count: count})
+	}
+	sort.Slice(report.Transitions, func(i, j int) bool {
+		a, b := report.Transitions[i], report.Transitions[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	for _, a := range policies {
+		report.Policies = append(report.Policies, *a)
+	}
+	sort.Slice(report.Policies, func(i, j int) bool {
+		return report.Policies[i].PolicyID < report.Policies[j].PolicyID
+	})
+	return report
+}
+
+// evaluateSimulatedBatch evaluates ctxs against one policy snapshot with
+// evaluateSimulated.
+func (e *PolicyEngine) evaluateSimulatedBatch(ctxs []EvalContext) []Verdict {
+	st := e.state.Load()
+	out := make([]Verdict, len(ctxs))
+	for i := range ctxs {
+		out[i] = e.evaluateSimulated(st, ctxs[i])
+	}
+	return out
+}
+
+// evaluateSimulated returns the verdict Evaluate would for ec, before the
+// decision cache, without counting hits, consuming rate limits or
+// notifying anyone.
+func (e *PolicyEngine) evaluateSimulated(st *engineState, ec EvalContext) Verdict {
+	ec = e.prepare(st, ec)
+	ec.simulated = true
+	// Background is never cancelled, so evaluate cannot fail.
+	v, _ := e.evaluate(context.Background(), st, ec, nil)
+	return e.runPostHooks(ec, v)
+}
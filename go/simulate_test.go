@@ -0,0 +1,134 @@
+package guard
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	old := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "allow-bash", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAsk))
+	new := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "deny-bg-bash", Effect: EffectDeny, Priority: 5, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"background"}}},
+		{ID: "allow-bash", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"bash"}}},
+		{ID: "allow-view", Effect: EffectAllow, Priority: 20, Condition: Condition{Tools: []string{"view"}}},
+	}, EffectAsk))
+
+	ctxs := []EvalContext{
+		{Tool: "bash", Mode: "interactive"}, // allow -> allow
+		{Tool: "bash", Mode: "background"},  // allow -> deny
+		{Tool: "bash", Mode: "background"},  // allow -> deny
+		{Tool: "view"},                      // ask -> allow
+		{Tool: "grep"},                      // ask -> ask
+	}
+	report := Simulate(old, new, ctxs)
+
+	if report.Total != 5 || report.Unchanged != 2 || report.Changed != 3 {
+		t.Errorf("unexpected totals: %+v", report)
+	}
+	wantTransitions := []SimTransition{
+		{From: EffectAllow, To: EffectDeny, Count: 2},
+		{From: EffectAsk, To: EffectAllow, Count: 1},
+	}
+	if !reflect.DeepEqual(report.Transitions, wantTransitions) {
+		t.Errorf("unexpected transitions: %+v", report.Transitions)
+	}
+	wantPolicies := []SimAttribution{
+		{PolicyID: "", OldWins: 2, NewWins: 1, Changed: 1},
+		{PolicyID: "allow-bash", OldWins: 3, NewWins: 1, Changed: 2},
+		{PolicyID: "allow-view", OldWins: 0, NewWins: 1, Changed: 1},
+		{PolicyID: "deny-bg-bash", OldWins: 0, NewWins: 2, Changed: 2},
+	}
+	if !reflect.DeepEqual(report.Policies, wantPolicies) {
+		t.Errorf("unexpected attribution:\n got %+v\nwant %+v", report.Policies, wantPolicies)
+	}
+}
+
+func TestSimReportJSON(t *testing.T) {
+	engine := NewPolicyEngine(makePolicySet(nil, EffectDeny))
+	report := Simulate(engine, engine, []EvalContext{{Tool: "bash"}})
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back SimReport
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, report) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", back, report)
+	}
+	want := `{"total":1,"unchanged":1,"changed":0,"policies":[{"policy_id":"","old_wins":1,"new_wins":1,"changed":0}]}`
+	if string(data) != want {
+		t.Errorf("unexpected JSON:\n got %s\nwant %s", data, want)
+	}
+}
+
+func TestSimulateHasNoSideEffects(t *testing.T) {
+	counter := NewMemoryCounter()
+	engine := NewPolicyEngineWithOptions(rateLimitPolicySet(1, EffectDeny), WithCounter(counter))
+	sink := &memorySink{}
+	engine.SetAuditSink(sink)
+	obs := &recordingObserver{}
+	engine.SetObserver(obs)
+
+	ctx := EvalContext{Tool: "web_search", Session: "s1"}
+	if v := engine.Evaluate(ctx); v.Effect != EffectAllow {
+		t.Fatalf("expected allow, got %s", v.Effect)
+	}
+	hits := engine.HitCounts()
+
+	report := Simulate(engine, engine, []EvalContext{ctx, {Tool: "web_search", Session: "s2"}})
+	want := []SimTransition(nil)
+	if report.Unchanged != 2 || !reflect.DeepEqual(report.Transitions, want) {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	if n := counter.Count(rateLimitKey(ctx)); n != 1 {
+		t.Errorf("rate-limit counter changed: got %d, want 1", n)
+	}
+	if n := counter.Count(rateLimitKey(EvalContext{Tool: "web_search", Session: "s2"})); n != 0 {
+		t.Errorf("rate-limit counter for s2 changed: got %d, want 0", n)
+	}
+	if got := engine.HitCounts(); !reflect.DeepEqual(got, hits) {
+		t.Errorf("hit counts changed: got %v, want %v", got, hits)
+	}
+	if len(sink.entries) != 1 {
+		t.Errorf("audit sink got %d entries, want 1", len(sink.entries))
+	}
+	if len(obs.verdicts) != 1 {
+		t.Errorf("observer got %d verdicts, want 1", len(obs.verdicts))
+	}
+}
+
+func TestSimulateJudgesRateLimitsWithoutConsuming(t *testing.T) {
+	ctx := EvalContext{Tool: "web_search", Session: "s1"}
+	old := NewPolicyEngineWithOptions(rateLimitPolicySet(1, EffectDeny), WithCounter(NewMemoryCounter()))
+	engine := NewPolicyEngineWithOptions(rateLimitPolicySet(1, EffectDeny), WithCounter(NewMemoryCounter()))
+	engine.Evaluate(ctx)
+
+	report := Simulate(old, engine, []EvalContext{ctx, ctx})
+	want := []SimTransition{{From: EffectAllow, To: EffectDeny, Count: 2}}
+	if !reflect.DeepEqual(report.Transitions, want) {
+		t.Errorf("unexpected transitions: %+v", report.Transitions)
+	}
+}
+
+func TestSimulateIgnoresDecisionCache(t *testing.T) {
+	ps := makePolicySet(nil, EffectAsk)
+	engine := NewPolicyEngine(ps)
+	engine.SetDecisionCache(NewMemoryDecisionCache(time.Hour))
+	ctx := EvalContext{Tool: "bash", Session: "s1"}
+	engine.Approve(ctx, engine.Evaluate(ctx))
+	if v := engine.Evaluate(ctx); v.Effect != EffectAllow {
+		t.Fatalf("expected cached allow, got %s", v.Effect)
+	}
+
+	report := Simulate(NewPolicyEngine(ps), engine, []EvalContext{ctx})
+	if report.Changed != 0 {
+		t.Errorf("decision cache leaked into simulation: %+v", report.Transitions)
+	}
+}
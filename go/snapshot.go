@@ -0,0 +1,42 @@
+package guard
+
+// ── Snapshots ──────────────────────────────────────────────────────────
+
+// EngineState is an opaque, immutable snapshot of an engine's loaded
+// policies, defaults, context fallbacks and aliases, taken by Snapshot.
+// The zero EngineState holds nothing.
+type EngineState struct {
+	engine *PolicyEngine
+	st     *engineState
+}
+
+// Snapshot captures the engine's current policy set. It is cheap: the
+// compiled snapshot the engine evaluates against is shared, not copied.
+func (e *PolicyEngine) Snapshot() EngineState {
+	return EngineState{engine: e, st: e.state.Load()}
+}
+
+// Restore makes s the active policy set, e.g. to switch back after trying
+// a candidate set. Like Load it is safe to call while other goroutines
+// are evaluating. A snapshot of this engine is reinstated as is, without
+// recompiling, so field matchers set since it was taken do not apply to
+// it. One taken from another engine is recompiled with this engine's
+// options, such as its label selector and field matchers.
+// Restoring the zero EngineState does nothing.
+func (e *PolicyEngine) Restore(s EngineState) {
+	if s.st == nil {
+		return
+	}
+	e.loadMu.Lock()
+	defer e.loadMu.Unlock()
+	if s.engine == e || !s.st.loaded {
+		e.state.Store(s.st)
+		return
+	}
+	e.state.Store(e.buildState(&PolicySet{
+		Defaults:         s.st.defaults,
+		Policies:         s.st.policies,
+		ContextFallbacks: s.st.contextFallbacks,
+		Aliases:          s.st.aliases,
+	}))
+}
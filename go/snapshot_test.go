@@ -0,0 +1,62 @@
+package guard
+
+import "testing"
+
+func TestSnapshotRestore(t *testing.T) {
+	original := makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}, Modes: []string{"safe"}}},
+	}, EffectAsk)
+	original.ContextFallbacks = map[string]string{"auto": "safe"}
+	engine := NewPolicyEngine(original)
+	contexts := []EvalContext{
+		{Mode: "safe", Tool: "bash"},
+		{Mode: "auto", Tool: "bash"},
+		{Mode: "safe", Tool: "view"},
+	}
+	want := make([]Verdict, len(contexts))
+	for i, ec := range contexts {
+		want[i] = engine.Evaluate(ec)
+	}
+	hash := engine.PolicyHash()
+	snap := engine.Snapshot()
+
+	engine.Load(makePolicySet([]Policy{
+		{ID: "allow-all", Effect: EffectAllow, Condition: Condition{Tools: []string{"*"}}},
+	}, EffectDeny))
+	if v := engine.Evaluate(contexts[0]); v.Effect != EffectAllow {
+		t.Fatalf("candidate not active: %+v", v)
+	}
+
+	engine.Restore(snap)
+	for i, ec := range contexts {
+		if got := engine.Evaluate(ec); got.Effect != want[i].Effect || got.PolicyID != want[i].PolicyID || got.Source != want[i].Source {
+			t.Errorf("%+v: got %+v, want %+v", ec, got, want[i])
+		}
+	}
+	if engine.PolicyHash() != hash {
+		t.Error("policy hash changed across restore")
+	}
+	if n := engine.HitCounts()["deny-bash"]; n != 4 {
+		t.Errorf("deny-bash hits = %d, want 4", n)
+	}
+}
+
+func TestRestoreFromAnotherEngine(t *testing.T) {
+	source := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}}},
+	}, EffectAllow))
+	engine := NewPolicyEngine(nil)
+	engine.Restore(source.Snapshot())
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "deny-bash" {
+		t.Errorf("got %+v", v)
+	}
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	if n := source.HitCounts()["deny-bash"]; n != 0 {
+		t.Errorf("hits counted on the source engine: %d", n)
+	}
+
+	engine.Restore(EngineState{})
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "deny-bash" {
+		t.Errorf("zero EngineState changed the engine: %+v", v)
+	}
+}
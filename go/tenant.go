@@ -0,0 +1,52 @@
+package guard
+
+// ── Tenants ────────────────────────────────────────────────────────────
+
+// WithTenant scopes the engine to one tenant of a multi-tenant policy
+// set. Policies that cannot match tenant, because their condition lists
+// tenants none of which matches it, or every any_of block does, are
+// dropped on Load, like those filtered out by WithLabelSelector, so other
+// tenants' policies are never evaluated. Policies without tenants are
+// shared and kept, as are inverted policies, which match precisely the
+// tenants they do not list. Contexts without a Tenant are evaluated as
+// belonging to tenant.
+func WithTenant(tenant string) Option {
+	return func(e *PolicyEngine) {
+		e.tenant = tenant
+	}
+}
+
+// tenantSelected reports whether an engine scoped to tenant keeps p. An
+// unscoped engine keeps every policy; otherwise p is kept unless it
+// provably cannot match tenant.
+func tenantSelected(tenant string, p *Policy) bool {
+	if tenant == "" || p.Invert {
+		return true
+	}
+	if !tenantsAllow(tenant, p.Condition.Tenants) {
+		return false
+	}
+	if len(p.AnyOf) == 0 {
+		return true
+	}
+	for i := range p.AnyOf {
+		if tenantsAllow(tenant, p.AnyOf[i].Tenants) {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantsAllow reports whether a condition with the given tenant
+// patterns can match tenant. A nil list matches every tenant.
+func tenantsAllow(tenant string, patterns []string) bool {
+	if patterns == nil {
+		return true
+	}
+	for _, pattern := range patterns {
+		if GlobMatch(pattern, tenant) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package guard
+
+import (
+	"slices"
+	"testing"
+)
+
+func tenantPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "acme-deny-deploy", Priority: 1, Effect: EffectDeny, Condition: Condition{Tools: []string{"deploy"}, Tenants: []string{"acme"}}},
+		{ID: "globex-allow-deploy", Priority: 1, Effect: EffectAllow, Condition: Condition{Tools: []string{"deploy"}, Tenants: []string{"globex"}}},
+		{ID: "ask-deploy", Priority: 5, Effect: EffectAsk, Condition: Condition{Tools: []string{"deploy"}}},
+		{ID: "deny-rm", Priority: 5, Effect: EffectDeny, Condition: Condition{Tools: []string{"rm"}}},
+	}, EffectAllow)
+}
+
+func TestTenantsMatch(t *testing.T) {
+	engine := NewPolicyEngine(tenantPolicySet())
+	for _, tt := range []struct {
+		tenant string
+		want   string
+	}{
+		{"acme", "acme-deny-deploy"},
+		{"globex", "globex-allow-deploy"},
+		{"initech", "ask-deploy"},
+		{"", "ask-deploy"},
+	} {
+		if v := engine.Evaluate(EvalContext{Tool: "deploy", Tenant: tt.tenant}); v.PolicyID != tt.want {
+			t.Errorf("tenant %q: got %q, want %q", tt.tenant, v.PolicyID, tt.want)
+		}
+	}
+}
+
+func TestWithTenantIsolation(t *testing.T) {
+	engine := NewPolicyEngineWithOptions(tenantPolicySet(), WithTenant("acme"))
+	var ids []string
+	for _, p := range engine.Policies() {
+		ids = append(ids, p.ID)
+	}
+	if want := []string{"acme-deny-deploy", "ask-deploy", "deny-rm"}; !slices.Equal(ids, want) {
+		t.Errorf("policies = %v, want %v", ids, want)
+	}
+
+	for _, tt := range []struct {
+		ctx  EvalContext
+		want string
+	}{
+		{EvalContext{Tool: "deploy"}, "acme-deny-deploy"},
+		{EvalContext{Tool: "deploy", Tenant: "acme"}, "acme-deny-deploy"},
+		// Another tenant's policy is never evaluated, even for its tenant.
+		{EvalContext{Tool: "deploy", Tenant: "globex"}, "ask-deploy"},
+		{EvalContext{Tool: "rm"}, "deny-rm"},
+	} {
+		if v := engine.Evaluate(tt.ctx); v.PolicyID != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.ctx, v.PolicyID, tt.want)
+		}
+	}
+
+	globex := NewPolicyEngineWithOptions(tenantPolicySet(), WithTenant("globex"))
+	if v := globex.Evaluate(EvalContext{Tool: "deploy"}); v.PolicyID != "globex-allow-deploy" {
+		t.Errorf("globex: got %+v", v)
+	}
+}
+
+func TestWithTenantKeepsInvertedAndAnyOf(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		// Applies to every tenant but globex, so acme must keep it.
+		{ID: "not-globex-deny-deploy", Priority: 1, Effect: EffectDeny, Invert: true, Condition: Condition{Tenants: []string{"globex"}}},
+		{ID: "acme-or-initech-ask-rm", Priority: 2, Effect: EffectAsk, Condition: Condition{Tools: []string{"rm"}}, AnyOf: []Condition{
+			{Tenants: []string{"initech"}},
+			{Tenants: []string{"acme"}},
+		}},
+		{ID: "anyof-shared-ask-rm", Priority: 3, Effect: EffectAsk, Condition: Condition{Tools: []string{"rm"}}, AnyOf: []Condition{
+			{Tenants: []string{"globex"}},
+			{Modes: []string{"background"}},
+		}},
+		{ID: "globex-only-anyof", Priority: 4, Effect: EffectAllow, AnyOf: []Condition{
+			{Tenants: []string{"globex"}, Tools: []string{"rm"}},
+			{Tenants: []string{"glob*"}, Tools: []string{"view"}},
+		}},
+	}, EffectAllow)
+
+	engine := NewPolicyEngineWithOptions(ps, WithTenant("acme"))
+	var ids []string
+	for _, p := range engine.Policies() {
+		ids = append(ids, p.ID)
+	}
+	if want := []string{"not-globex-deny-deploy", "acme-or-initech-ask-rm", "anyof-shared-ask-rm"}; !slices.Equal(ids, want) {
+		t.Errorf("policies = %v, want %v", ids, want)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "deploy"}); v.PolicyID != "not-globex-deny-deploy" {
+		t.Errorf("inverted policy: got %+v", v)
+	}
+
+	globex := NewPolicyEngineWithOptions(ps, WithTenant("globex"))
+	if v := globex.Evaluate(EvalContext{Tool: "rm"}); v.PolicyID != "anyof-shared-ask-rm" {
+		t.Errorf("globex any_of: got %+v", v)
+	}
+	if v := globex.Evaluate(EvalContext{Tool: "view"}); v.PolicyID != "globex-only-anyof" {
+		t.Errorf("globex any_of pattern: got %+v", v)
+	}
+}
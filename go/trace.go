@@ -0,0 +1,116 @@
+package guard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ── Traces ─────────────────────────────────────────────────────────────
+
+// TraceRecord is one evaluation captured by a TraceRecorder: the context
+// as the engine saw it (after risk scoring), the verdict it produced and
+// when it was evaluated.
+type TraceRecord struct {
+	Time    time.Time
+	Context EvalContext
+	Verdict Verdict
+}
+
+// traceLine is the JSON form of a TraceRecord, one per line:
+//
+//	{"time":"…","context":{"tool":"bash"},"verdict":{"effect":"deny",…}}
+type traceLine struct {
+	Time    time.Time   `json:"time"`
+	Context EvalContext `json:"context"`
+	Verdict Verdict     `json:"verdict"`
+}
+
+// TraceRecorder is an AuditSink that writes every decision to an
+// io.Writer as JSON lines, for replaying production traffic against
+// candidate policies with ReplayTrace.
+type TraceRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// RecordTrace returns a TraceRecorder writing to w. Install it with
+// SetAuditSink:
+//
+//	engine.SetAuditSink(guard.RecordTrace(f))
+//
+// Each entry is written synchronously, so wrap slow writers in a
+// bufio.Writer (and flush it when done).
+func RecordTrace(w io.Writer) *TraceRecorder {
+	return &TraceRecorder{enc: json.NewEncoder(w)}
+}
+
+// Record writes the entry as one trace line. After a write fails, later
+// entries are dropped and Err reports the failure.
+func (r *TraceRecorder) Record(a AuditEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err := r.enc.Encode(traceLine{Time: a.Time, Context: a.Context, Verdict: a.Verdict}); err != nil {
+		r.err = fmt.Errorf("guard: trace: %w", err)
+	}
+}
+
+// Err returns the first write error, if any.
+func (r *TraceRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// ReadTrace parses a trace written by a TraceRecorder. Blank lines are
+// skipped.
+func ReadTrace(r io.Reader) ([]TraceRecord, error) {
+	var out []TraceRecord
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var line traceLine
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("guard: trace line %d: %w", n, err)
+		}
+		out = append(out, TraceRecord{Time: line.Time, Context: line.Context, Verdict: line.Verdict})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("guard: trace: %w", err)
+	}
+	return out, nil
+}
+
+// ReplayTrace re-evaluates every context in the trace read from r against
+// engine and returns the new verdicts in trace order, ready to compare
+// with the recorded ones from ReadTrace. A context without Now is
+// evaluated at its recorded time, so activation windows behave as they
+// did in production. Like Simulate, replay leaves engine's hit counts,
+// rate-limit counters, observers and audit sink untouched and bypasses
+// its decision cache, so a live engine can replay its own trace.
+func ReplayTrace(r io.Reader, engine *PolicyEngine) ([]Verdict, error) {
+	records, err := ReadTrace(r)
+	if err != nil {
+		return nil, err
+	}
+	st := engine.state.Load()
+	out := make([]Verdict, len(records))
+	for i, rec := range records {
+		ec := rec.Context
+		if ec.Now.IsZero() {
+			ec.Now = rec.Time
+		}
+		out[i] = engine.evaluateSimulated(st, ec)
+	}
+	return out, nil
+}
@@ -0,0 +1,172 @@
+package guard
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fullContext sets every exported EvalContext field.
+func fullContext() EvalContext {
+	return EvalContext{
+		Mode:              "background",
+		Model:             "claude-3-opus",
+		Channel:           "phone",
+		Tool:              "bash",
+		McpServer:         "github",
+		Risk:              "high",
+		User:              "alice",
+		Session:           "s1",
+		Args:              map[string]string{"cmd": "rm -rf /"},
+		SourceIP:          "10.0.0.1",
+		Tags:              map[string]string{"team": "payments"},
+		EstimatedCostUSD:  1.5,
+		TokenCount:        4000,
+		Agent:             "planner/researcher-1",
+		McpMethod:         "tools/call",
+		RiskScore:         72,
+		SessionAgeSeconds: 5400,
+		Tenant:            "acme",
+		ReadOnly:          new(bool),
+		Tools:             []string{"bash", "view"},
+		Risks:             []string{"high", "pii"},
+		Authenticated:     true,
+		Now:               time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestTraceRoundTripsContext(t *testing.T) {
+	ec := fullContext()
+	rv := reflect.ValueOf(ec)
+	for i := 0; i < rv.NumField(); i++ {
+		if f := rv.Type().Field(i); f.IsExported() && rv.Field(i).IsZero() {
+			t.Fatalf("fullContext does not set %s", f.Name)
+		}
+	}
+
+	var buf bytes.Buffer
+	engine := NewPolicyEngine(hitPolicySet())
+	rec := RecordTrace(&buf)
+	engine.SetAuditSink(rec)
+	want := engine.Evaluate(ec)
+	engine.Evaluate(EvalContext{Tool: "sh"})
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if !reflect.DeepEqual(records[0].Context, ec) {
+		t.Errorf("context did not round-trip:\n got %+v\nwant %+v", records[0].Context, ec)
+	}
+	if !reflect.DeepEqual(records[0].Verdict, want) {
+		t.Errorf("verdict did not round-trip: got %+v, want %+v", records[0].Verdict, want)
+	}
+	if records[1].Verdict.PolicyID != "ask-shell" {
+		t.Errorf("second record: %+v", records[1].Verdict)
+	}
+}
+
+func TestReplayTraceAgainstCandidate(t *testing.T) {
+	var buf bytes.Buffer
+	engine := NewPolicyEngine(hitPolicySet())
+	engine.SetAuditSink(RecordTrace(&buf))
+	for _, tool := range []string{"bash", "sh", "rm", "view"} {
+		engine.Evaluate(EvalContext{Tool: tool, Mode: "safe"})
+	}
+	trace := buf.String()
+
+	candidate := NewPolicyEngine(makePolicySet([]Policy{
+		{ID: "deny-shells", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash", "sh"}}},
+	}, EffectAsk))
+	got, err := ReplayTrace(strings.NewReader(trace), candidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, _ := ReadTrace(strings.NewReader(trace))
+	var changed []string
+	for i := range got {
+		if got[i].Effect != records[i].Verdict.Effect {
+			changed = append(changed, records[i].Context.Tool)
+		}
+	}
+	if want := []string{"bash", "sh", "rm"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("expected changes for %v, got %v", want, changed)
+	}
+}
+
+func TestReplayTraceUsesRecordedTime(t *testing.T) {
+	expires := time.Date(2025, 3, 7, 17, 0, 0, 0, time.UTC)
+	ps := makePolicySet([]Policy{
+		{ID: "freeze", Effect: EffectDeny, ExpiresAt: &expires, Condition: Condition{Tools: []string{"deploy"}}},
+	}, EffectAllow)
+	var buf bytes.Buffer
+	engine := NewPolicyEngineWithOptions(ps, WithClock(&fakeClock{now: expires.Add(-time.Hour)}))
+	engine.SetAuditSink(RecordTrace(&buf))
+	engine.Evaluate(EvalContext{Tool: "deploy"})
+
+	// The replaying engine's clock is past expiry; the trace is not.
+	replayer := NewPolicyEngineWithOptions(ps, WithClock(&fakeClock{now: expires.Add(time.Hour)}))
+	got, err := ReplayTrace(&buf, replayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Effect != EffectDeny {
+		t.Errorf("expected deny at the recorded time, got %+v", got)
+	}
+}
+
+func TestReplayTraceHasNoSideEffects(t *testing.T) {
+	var buf bytes.Buffer
+	engine := NewPolicyEngine(hitPolicySet())
+	engine.SetAuditSink(RecordTrace(&buf))
+	for _, tool := range []string{"bash", "view"} {
+		engine.Evaluate(EvalContext{Tool: tool, Mode: "safe"})
+	}
+	trace := buf.String()
+	hits := engine.HitCounts()
+	obs := &recordingObserver{}
+	engine.SetObserver(obs)
+
+	if _, err := ReplayTrace(strings.NewReader(trace), engine); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != trace {
+		t.Errorf("replay wrote to the audit sink:\n%s", buf.String()[len(trace):])
+	}
+	if got := engine.HitCounts(); !reflect.DeepEqual(got, hits) {
+		t.Errorf("hit counts changed: got %v, want %v", got, hits)
+	}
+	if len(obs.verdicts) != 0 {
+		t.Errorf("observer got %d verdicts, want 0", len(obs.verdicts))
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	_, err := ReadTrace(strings.NewReader("{\"context\":{\"tool\":\"bash\"},\"verdict\":{\"effect\":\"allow\"}}\n\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected a line 3 error, got %v", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestTraceRecorderErr(t *testing.T) {
+	engine := NewPolicyEngine(hitPolicySet())
+	rec := RecordTrace(failingWriter{})
+	engine.SetAuditSink(rec)
+	engine.Evaluate(EvalContext{Tool: "bash"})
+	if err := rec.Err(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("expected write error, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package guard
+
+import "strings"
+
+// ── User groups ────────────────────────────────────────────────────────
+
+// groupPrefix marks a users pattern that matches the user's groups rather
+// than the user ID, e.g. "group:oncall" or "group:sre-*".
+const groupPrefix = "group:"
+
+// GroupResolver looks up the groups a user belongs to, typically from an
+// LDAP directory or identity provider. Implementations must be safe for
+// concurrent use.
+type GroupResolver interface {
+	Groups(user string) []string
+}
+
+// WithGroupResolver lets users patterns prefixed with "group:" match any
+// of the user's groups as reported by r. Without a resolver such patterns
+// never match. The resolver is only consulted when the loaded policy set
+// has group patterns and the context names a user.
+func WithGroupResolver(r GroupResolver) Option {
+	return func(e *PolicyEngine) {
+		e.resolver = r
+	}
+}
+
+// resolveGroups returns ec with the user's groups filled in, if st needs
+// them and the engine has a resolver.
+func (e *PolicyEngine) resolveGroups(st *engineState, ec EvalContext) EvalContext {
+	if st.grouped && e.resolver != nil && ec.User != "" {
+		ec.groups = e.resolver.Groups(ec.User)
+	}
+	return ec
+}
+
+// compileUserPatterns compiles the users field without its group patterns,
+// which compileCondition keeps separately. The list stays set when it only
+// holds group patterns, so that it matches no user ID.
+func compileUserPatterns(patterns []string) patternList {
+	pl := patternList{set: patterns != nil}
+	for _, p := range patterns {
+		if !strings.HasPrefix(p, groupPrefix) {
+			pl.matchers = append(pl.matchers, compilePattern(p))
+		}
+	}
+	return pl
+}
+
+// groupMatches reports whether any of groups matches a group pattern.
+func (cc *compiledCondition) groupMatches(groups []string) bool {
+	for i := range cc.groups {
+		for _, g := range groups {
+			if cc.groups[i].match(g) {
+				return true
+			}
+		}
+	}
+	return false
+}
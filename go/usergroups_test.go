@@ -0,0 +1,91 @@
+package guard
+
+import "testing"
+
+// staticGroups maps users to their groups.
+type staticGroups map[string][]string
+
+func (g staticGroups) Groups(user string) []string {
+	return g[user]
+}
+
+func groupPolicySet() *PolicySet {
+	return makePolicySet([]Policy{
+		{ID: "allow-oncall", Effect: EffectAllow, Priority: 10, Condition: Condition{Tools: []string{"deploy"}, Users: []string{"group:oncall", "root"}}},
+		{ID: "ask-sre", Effect: EffectAsk, Priority: 20, Condition: Condition{Tools: []string{"deploy"}, Users: []string{"group:sre-*"}}},
+	}, EffectDeny)
+}
+
+func TestGroupResolverMatchesUsersByGroup(t *testing.T) {
+	resolver := staticGroups{
+		"alice": {"dev", "oncall"},
+		"bob":   {"sre-eu"},
+		"carol": {"dev"},
+	}
+	engine := NewPolicyEngineWithOptions(groupPolicySet(), WithGroupResolver(resolver))
+
+	cases := []struct {
+		user string
+		want Effect
+	}{
+		{"alice", EffectAllow},
+		{"bob", EffectAsk},
+		{"carol", EffectDeny},
+		{"root", EffectAllow},
+		{"group:oncall", EffectDeny}, // a user ID is never matched against group patterns
+		{"", EffectDeny},
+	}
+	for _, tc := range cases {
+		if v := engine.Evaluate(EvalContext{Tool: "deploy", User: tc.user}); v.Effect != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.user, tc.want, v.Effect)
+		}
+	}
+	if !resultMatched(engine.EvaluateAll(EvalContext{Tool: "deploy", User: "bob"}), "ask-sre") {
+		t.Error("EvaluateAll: expected ask-sre to match a user in sre-eu")
+	}
+	if v, _ := engine.EvaluateDetailed(EvalContext{Tool: "deploy", User: "alice"}); v.PolicyID != "allow-oncall" {
+		t.Errorf("EvaluateDetailed: expected allow-oncall, got %q", v.PolicyID)
+	}
+}
+
+func TestGroupPatternsNeverMatchWithoutResolver(t *testing.T) {
+	engine := NewPolicyEngine(groupPolicySet())
+	if v := engine.Evaluate(EvalContext{Tool: "deploy", User: "alice"}); v.Effect != EffectDeny {
+		t.Errorf("expected deny, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "deploy", User: "root"}); v.Effect != EffectAllow {
+		t.Errorf("user ID pattern: expected allow, got %s", v.Effect)
+	}
+}
+
+func TestGroupResolverSkippedWithoutGroupPatterns(t *testing.T) {
+	calls := 0
+	resolver := groupFunc(func(string) []string {
+		calls++
+		return nil
+	})
+	engine := NewPolicyEngineWithOptions(makePolicySet([]Policy{
+		{ID: "deny-bash", Effect: EffectDeny, Condition: Condition{Tools: []string{"bash"}, Users: []string{"alice"}}},
+	}, EffectAsk), WithGroupResolver(resolver))
+	engine.Evaluate(EvalContext{Tool: "bash", User: "alice"})
+	if calls != 0 {
+		t.Errorf("resolver called %d times for a policy set without group patterns", calls)
+	}
+}
+
+type groupFunc func(user string) []string
+
+func (f groupFunc) Groups(user string) []string { return f(user) }
+
+func TestShadowedUserPatternDoesNotCoverGroup(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "broad", Effect: EffectDeny, Priority: 10, Condition: Condition{Users: []string{"g*"}}},
+		{ID: "by-group", Effect: EffectAllow, Priority: 20, Condition: Condition{Users: []string{"group:oncall"}}},
+		{ID: "all", Effect: EffectAsk, Priority: 30, Condition: Condition{Users: []string{"group:*"}}},
+		{ID: "same-group", Effect: EffectAllow, Priority: 40, Condition: Condition{Users: []string{"group:oncall"}}},
+	}, EffectAsk)
+	got := DetectShadowed(ps)
+	if len(got) != 1 || got[0] != "same-group" {
+		t.Errorf("expected [same-group], got %v", got)
+	}
+}
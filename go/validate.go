@@ -0,0 +1,111 @@
+package guard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ── Validation ─────────────────────────────────────────────────────────
+
+// Validate checks ps more thoroughly than the loader and returns every
+// problem found, or nil. Besides the loader's own checks it reports
+// policies without an ID, duplicate policy IDs, and cycles in the context
+// fallback chain. Pass WithStrictEffects to also reject unknown effects,
+// WithStrictCodes to check policy codes, and WithRequireEnabled to reject
+// a set with every policy disabled.
+//
+// The engine tolerates all of these (a duplicate ID simply loses the
+// priority tie-break, and a fallback cycle is cut at the first repeat),
+// but each is almost certainly a mistake worth failing CI over.
+func Validate(ps *PolicySet, opts ...LoadOption) []error {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var errs []error
+	if err := validateDefaults(ps.Defaults, o); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateAliases(ps.Aliases); err != nil {
+		errs = append(errs, err)
+	}
+	seen := make(map[string]bool, len(ps.Policies))
+	codes := make(map[string]bool)
+	for i := range ps.Policies {
+		p := &ps.Policies[i]
+		switch {
+		case p.ID == "":
+			errs = append(errs, &PolicyError{Index: i, Err: ErrMissingID})
+		case seen[p.ID]:
+			errs = append(errs, &PolicyError{Index: i, ID: p.ID, Err: ErrDuplicateID})
+		}
+		seen[p.ID] = true
+		if o.strictCodes && p.Code != "" {
+			if codes[p.Code] {
+				errs = append(errs, &PolicyError{Index: i, ID: p.ID, Err: fmt.Errorf("%w %q", ErrDuplicateCode, p.Code)})
+			}
+			codes[p.Code] = true
+		}
+		if err := validatePolicy(i, p, o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, cycle := range fallbackCycles(ps.ContextFallbacks) {
+		errs = append(errs, fmt.Errorf("guard: %w: %s", ErrFallbackCycle, strings.Join(cycle, " -> ")))
+	}
+	if o.requireEnabled {
+		if err := validateEnabled(ps.Policies); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// fallbackCycles returns each cycle in the fallback map once, starting
+// from its lexicographically smallest mode and ending where it began,
+// e.g. [a b a].
+func fallbackCycles(fallbacks map[string]string) [][]string {
+	var cycles [][]string
+	reported := make(map[string]bool)
+	for _, start := range sortedModes(fallbacks) {
+		index := map[string]int{}
+		var path []string
+		for mode, ok := start, true; ok; mode, ok = fallbacks[mode] {
+			if i, seen := index[mode]; seen {
+				cycle := rotateToSmallest(path[i:])
+				if key := strings.Join(cycle, "\x00"); !reported[key] {
+					reported[key] = true
+					cycles = append(cycles, append(cycle, cycle[0]))
+				}
+				break
+			}
+			index[mode] = len(path)
+			path = append(path, mode)
+		}
+	}
+	return cycles
+}
+
+func sortedModes(m map[string]string) []string {
+	modes := make([]string, 0, len(m))
+	for k := range m {
+		modes = append(modes, k)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// rotateToSmallest returns a copy of cycle starting at its smallest
+// element, so the same cycle found from different modes compares equal.
+func rotateToSmallest(cycle []string) []string {
+	first := 0
+	for i, m := range cycle {
+		if m < cycle[first] {
+			first = i
+		}
+	}
+	out := make([]string, 0, len(cycle)+1)
+	out = append(out, cycle[first:]...)
+	return append(out, cycle[:first]...)
+}
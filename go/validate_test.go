@@ -0,0 +1,123 @@
+package guard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateValidSet(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "a", Effect: EffectAllow, Priority: 10},
+		{ID: "b", Effect: EffectDeny, Priority: 20},
+	}, EffectAsk)
+	ps.ContextFallbacks = map[string]string{"scheduler": "background"}
+	if errs := Validate(ps, WithStrictEffects()); errs != nil {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "a", Effect: EffectAllow},
+		{ID: "a", Effect: EffectDeny},
+		{Effect: EffectDeny},
+		{ID: "cidr", Effect: EffectDeny, Condition: Condition{SourceCIDRs: []string{"nope"}}},
+		{ID: "custom", Effect: "quarantine"},
+	}, EffectAsk)
+	ps.ContextFallbacks = map[string]string{"b": "c", "c": "a", "a": "b", "x": "a"}
+
+	var msgs []string
+	for _, err := range Validate(ps) {
+		msgs = append(msgs, err.Error())
+	}
+	got := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		`policy "a": duplicate id`,
+		"policy #3: missing id",
+		`policy "cidr": invalid source CIDR`,
+		"context fallback cycle: a -> b -> c -> a",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "cycle") != 1 {
+		t.Errorf("expected the cycle reported once:\n%s", got)
+	}
+	if strings.Contains(got, "quarantine") {
+		t.Errorf("custom effects are allowed without strict mode:\n%s", got)
+	}
+	if errs := Validate(ps, WithStrictEffects()); len(errs) != len(msgs)+1 {
+		t.Errorf("strict mode: expected one more error, got %v", errs)
+	}
+}
+
+func TestValidateStrictCodes(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "a", Effect: EffectDeny, Code: "GUARD_BLOCKED"},
+		{ID: "b", Effect: EffectDeny, Code: "GUARD_BLOCKED"},
+		{ID: "c", Effect: EffectDeny, Code: "not a code"},
+		{ID: "d", Effect: EffectAllow},
+	}, EffectAsk)
+	if errs := Validate(ps); errs != nil {
+		t.Errorf("codes are free-form without strict mode, got %v", errs)
+	}
+	errs := Validate(ps, WithStrictCodes())
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), `policy "b": duplicate code "GUARD_BLOCKED"`) ||
+		!strings.Contains(errs[1].Error(), `policy "c": invalid code "not a code"`) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestFallbackSelfCycle(t *testing.T) {
+	cycles := fallbackCycles(map[string]string{"a": "a"})
+	if len(cycles) != 1 || strings.Join(cycles[0], " ") != "a a" {
+		t.Errorf("expected [a a], got %v", cycles)
+	}
+}
+
+func TestRequireEnabled(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "a", Effect: EffectDeny, Enabled: boolPtr(false)},
+		{ID: "b", Effect: EffectAllow, Enabled: boolPtr(false)},
+	}, EffectAllow)
+	if errs := Validate(ps); errs != nil {
+		t.Errorf("without the option: got %v", errs)
+	}
+	errs := Validate(ps, WithRequireEnabled())
+	if len(errs) != 1 || !errors.Is(errs[0], ErrNoEnabledPolicies) {
+		t.Errorf("got %v, want ErrNoEnabledPolicies", errs)
+	}
+
+	engine := NewPolicyEngine(ps)
+	if got, want := engine.Stats(), (PolicyStats{Total: 2, Enabled: 0, Disabled: 2}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	ps.Policies[1].Enabled = nil
+	if errs := Validate(ps, WithRequireEnabled()); errs != nil {
+		t.Errorf("one enabled: got %v", errs)
+	}
+	if errs := Validate(makePolicySet(nil, EffectDeny), WithRequireEnabled()); errs != nil {
+		t.Errorf("empty set: got %v", errs)
+	}
+
+	doc := []byte(`
+metadata:
+  name: generated
+policies:
+  - id: a
+    effect: deny
+    enabled: false
+`)
+	if _, err := LoadPolicySetFromBytes(doc, WithRequireEnabled()); !errors.Is(err, ErrNoEnabledPolicies) {
+		t.Errorf("loader: got %v, want ErrNoEnabledPolicies", err)
+	}
+	if _, err := LoadPolicySetFromBytes(doc); err != nil {
+		t.Errorf("loader without the option: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package guard
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ── Model version constraints ──────────────────────────────────────────
+
+// modelVersionRe finds the first dotted numeric run in a model name,
+// e.g. "5.2" in "gpt-5.2" or "4" in "gpt-4o".
+var modelVersionRe = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// version is a dotted numeric version. Missing components compare as zero,
+// so 5 == 5.0 == 5.0.0.
+type version []int
+
+func parseVersion(s string) (version, error) {
+	parts := strings.Split(s, ".")
+	v := make(version, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// modelVersion extracts the numeric version carried by a model name.
+func modelVersion(model string) (version, bool) {
+	m := modelVersionRe.FindString(model)
+	if m == "" {
+		return nil, false
+	}
+	v, err := parseVersion(m)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func compareVersions(a, b version) int {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionComparator is a single bound such as ">=5.0.0".
+type versionComparator struct {
+	op string
+	v  version
+}
+
+func (c versionComparator) allows(v version) bool {
+	cmp := compareVersions(v, c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	default: // "=", "=="
+		return cmp == 0
+	}
+}
+
+// parseVersionConstraint parses a whitespace- or comma-separated list of
+// comparators, all of which must hold, e.g. ">=5.0.0 <6.0.0". Supported
+// operators are >, >=, <, <=, =, == and !=. A bare version means equality.
+func parseVersionConstraint(s string) ([]versionComparator, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+	out := make([]versionComparator, 0, len(fields))
+	for _, f := range fields {
+		op := strings.TrimRight(f, "0123456789.")
+		switch op {
+		case "", "=", "==", "!=", ">", ">=", "<", "<=":
+		default:
+			return nil, fmt.Errorf("invalid comparator %q in version constraint %q", f, s)
+		}
+		v, err := parseVersion(f[len(op):])
+		if err != nil {
+			return nil, fmt.Errorf("invalid comparator %q in version constraint %q", f, s)
+		}
+		out = append(out, versionComparator{op: op, v: v})
+	}
+	return out, nil
+}
+
+// modelVersionMatches reports whether the version embedded in model
+// satisfies constraint. Models without a parseable version never match.
+func modelVersionMatches(constraint, model string) bool {
+	comparators, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	return modelVersionAllowed(comparators, model)
+}
+
+// modelVersionAllowed reports whether the version embedded in model
+// satisfies every comparator.
+func modelVersionAllowed(comparators []versionComparator, model string) bool {
+	v, ok := modelVersion(model)
+	if !ok {
+		return false
+	}
+	for _, c := range comparators {
+		if !c.allows(v) {
+			return false
+		}
+	}
+	return true
+}
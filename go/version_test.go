@@ -0,0 +1,124 @@
+package guard
+
+import "testing"
+
+func TestModelVersionExtraction(t *testing.T) {
+	cases := []struct {
+		model string
+		want  string
+		ok    bool
+	}{
+		{"gpt-5.2", "5.2", true},
+		{"claude-sonnet-4.6", "4.6", true},
+		{"gpt-4o", "4", true},
+		{"llama-3.1.405", "3.1.405", true},
+		{"my-custom-model", "", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		v, ok := modelVersion(tc.model)
+		if ok != tc.ok {
+			t.Errorf("%q: expected ok=%v, got %v", tc.model, tc.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		want, _ := parseVersion(tc.want)
+		if compareVersions(v, want) != 0 {
+			t.Errorf("%q: expected %s, got %v", tc.model, tc.want, v)
+		}
+	}
+}
+
+func TestModelVersionBounds(t *testing.T) {
+	const constraint = ">=5.0.0 <6.0.0"
+	cases := []struct {
+		model string
+		want  bool
+	}{
+		{"gpt-4.9", false},
+		{"gpt-5", true}, // 5 == 5.0.0, inclusive lower bound
+		{"gpt-5.0.0", true},
+		{"gpt-5.2", true},
+		{"gpt-5.99.1", true},
+		{"gpt-6", false}, // exclusive upper bound
+		{"gpt-6.0.1", false},
+	}
+	for _, tc := range cases {
+		if got := modelVersionMatches(constraint, tc.model); got != tc.want {
+			t.Errorf("%q: expected %v, got %v", tc.model, tc.want, got)
+		}
+	}
+}
+
+func TestModelVersionOperators(t *testing.T) {
+	cases := []struct {
+		constraint string
+		model      string
+		want       bool
+	}{
+		{">5", "gpt-5", false},
+		{">5", "gpt-5.0.1", true},
+		{"<=4.6", "claude-4.6", true},
+		{"<=4.6", "claude-4.7", false},
+		{"=4", "gpt-4o", true},
+		{"==4.0", "gpt-4o", true},
+		{"4.1", "gpt-4.1", true},
+		{"!=4.1", "gpt-4.1", false},
+		{">=3,<4", "o3-mini", true},
+	}
+	for _, tc := range cases {
+		if got := modelVersionMatches(tc.constraint, tc.model); got != tc.want {
+			t.Errorf("%q vs %q: expected %v, got %v", tc.constraint, tc.model, tc.want, got)
+		}
+	}
+}
+
+func TestModelVersionWithoutVersionNeverMatches(t *testing.T) {
+	ps := makePolicySet([]Policy{
+		{ID: "v5", Effect: EffectAllow, Priority: 10, Condition: Condition{ModelVersion: ">=0"}},
+	}, EffectDeny)
+	engine := NewPolicyEngine(ps)
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Model: "custom-model"}); v.Effect != EffectDeny {
+		t.Errorf("expected deny, got %s", v.Effect)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.Effect != EffectDeny {
+		t.Errorf("no model: expected deny, got %s", v.Effect)
+	}
+}
+
+func TestModelVersionLoadedFromYAML(t *testing.T) {
+	yamlDoc := `
+metadata:
+  name: versions
+defaults:
+  effect: deny
+policies:
+  - id: allow-gpt5
+    effect: allow
+    condition:
+      models: ["gpt-*"]
+      model_version: ">=5.0.0 <6.0.0"
+`
+	ps, err := LoadPolicySetFromBytes([]byte(yamlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	if got := engine.Resolve(EvalContext{Tool: "bash", Model: "gpt-5.2"}); got != "allow" {
+		t.Errorf("gpt-5.2: expected allow, got %s", got)
+	}
+	if got := engine.Resolve(EvalContext{Tool: "bash", Model: "gpt-4o"}); got != "deny" {
+		t.Errorf("gpt-4o: expected deny, got %s", got)
+	}
+}
+
+func TestInvalidModelVersionConstraint(t *testing.T) {
+	for _, c := range []string{"~>5", ">=five", ">=", "5..1"} {
+		bad := "metadata:\n  name: bad\npolicies:\n  - id: p1\n    effect: deny\n    condition:\n      model_version: \"" + c + "\"\n"
+		if _, err := LoadPolicySetFromBytes([]byte(bad)); err == nil {
+			t.Errorf("%q: expected load error", c)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package guard
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits after the last change event
+// before reloading. Editors frequently write a file more than once per save.
+var watchDebounce = 100 * time.Millisecond
+
+// WatchPolicyFile watches the YAML file at path and reloads the engine
+// whenever it changes on disk. Rapid successive writes are debounced into a
+// single reload.
+//
+// After each reload attempt onReload (if non-nil) is called with either the
+// newly applied PolicySet or the error that prevented it. A PolicySet that
+// fails to load is never applied; the engine keeps its previous policies.
+// Every reload applies opts, so a file loaded with WithEnv or the strict
+// options is reloaded the same way.
+//
+// The parent directory is watched rather than the file itself so that
+// editors which save by renaming a temporary file over path are handled.
+// The returned stop function tears down the watcher and waits for any
+// in-flight reload to finish. It is safe to call more than once, but must
+// not be called from within onReload.
+func (e *PolicyEngine) WatchPolicyFile(path string, onReload func(*PolicySet, error), opts ...LoadOption) (stop func(), err error) {
+	path = filepath.Clean(path)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("guard: failed to create watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("guard: failed to watch %s: %w", path, err)
+	}
+
+	reload := func() {
+		ps, err := LoadPolicySet(path, opts...)
+		if err == nil {
+			e.Load(ps)
+		}
+		if onReload != nil {
+			onReload(ps, err)
+		}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		var timer *time.Timer
+		var fire <-chan time.Time
+		for {
+			select {
+			case <-done:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != path || ev.Op == fsnotify.Chmod {
+					continue
+				}
+				if timer == nil {
+					timer = time.NewTimer(watchDebounce)
+				} else {
+					if !timer.Stop() {
+						select {
+						case <-timer.C:
+						default:
+						}
+					}
+					timer.Reset(watchDebounce)
+				}
+				fire = timer.C
+			case <-fire:
+				fire = nil
+				reload()
+			case werr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				if onReload != nil {
+					onReload(nil, fmt.Errorf("guard: watching %s: %w", path, werr))
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+			watcher.Close()
+		})
+	}, nil
+}
@@ -0,0 +1,207 @@
+package guard
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const watchAllowDoc = `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: watched
+defaults:
+  effect: deny
+policies:
+  - id: allow-bash
+    effect: allow
+    condition:
+      tools: [bash]
+`
+
+const watchDenyDoc = `
+apiVersion: agent-policy/v1
+kind: PolicySet
+metadata:
+  name: watched
+defaults:
+  effect: deny
+policies:
+  - id: deny-bash
+    effect: deny
+    condition:
+      tools: [bash]
+`
+
+type reloadResult struct {
+	ps  *PolicySet
+	err error
+}
+
+func startWatch(t *testing.T, engine *PolicyEngine, path string, opts ...LoadOption) <-chan reloadResult {
+	t.Helper()
+	results := make(chan reloadResult, 16)
+	stop, err := engine.WatchPolicyFile(path, func(ps *PolicySet, err error) {
+		results <- reloadResult{ps, err}
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stop)
+	return results
+}
+
+func waitReload(t *testing.T, results <-chan reloadResult) reloadResult {
+	t.Helper()
+	select {
+	case r := <-results:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+		return reloadResult{}
+	}
+}
+
+func TestWatchPolicyFileReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(watchAllowDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := LoadPolicySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	results := startWatch(t, engine, path)
+
+	if err := os.WriteFile(path, []byte(watchDenyDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := waitReload(t, results)
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "deny-bash" {
+		t.Errorf("expected deny-bash after reload, got %s", v.PolicyID)
+	}
+}
+
+func TestWatchPolicyFileDebounces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(watchAllowDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(nil)
+	results := startWatch(t, engine, path)
+
+	// Two writes in quick succession, as an editor would do.
+	for _, doc := range []string{watchAllowDoc, watchDenyDoc} {
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := waitReload(t, results)
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.ps.Policies[0].ID != "deny-bash" {
+		t.Errorf("expected final contents, got %s", r.ps.Policies[0].ID)
+	}
+	select {
+	case extra := <-results:
+		t.Errorf("expected a single reload, got another: %+v", extra)
+	case <-time.After(3 * watchDebounce):
+	}
+}
+
+func TestWatchPolicyFileKeepsPoliciesOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(watchAllowDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := LoadPolicySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(ps)
+	results := startWatch(t, engine, path)
+
+	if err := os.WriteFile(path, []byte("policies: [unclosed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := waitReload(t, results)
+	if r.err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if r.ps != nil {
+		t.Errorf("expected nil policy set on error, got %+v", r.ps)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "bash"}); v.PolicyID != "allow-bash" {
+		t.Errorf("expected previous policies to stay active, got %s", v.PolicyID)
+	}
+}
+
+func TestWatchPolicyFileStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(watchAllowDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(nil)
+	results := make(chan reloadResult, 16)
+	stop, err := engine.WatchPolicyFile(path, func(ps *PolicySet, err error) {
+		results <- reloadResult{ps, err}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	stop() // idempotent
+
+	if err := os.WriteFile(path, []byte(watchDenyDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-results:
+		t.Errorf("expected no reload after stop, got %+v", r)
+	case <-time.After(3 * watchDebounce):
+	}
+}
+
+func TestWatchPolicyFileLoadOptions(t *testing.T) {
+	t.Setenv("GUARD_WATCH_EFFECT", "deny")
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(watchAllowDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewPolicyEngine(nil)
+	results := startWatch(t, engine, path, WithEnv(), WithStrictFields())
+
+	templated := strings.Replace(watchAllowDoc, "effect: deny", "effect: ${GUARD_WATCH_EFFECT}", 1)
+	if err := os.WriteFile(path, []byte(templated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r := waitReload(t, results); r.err != nil {
+		t.Fatalf("templated reload failed: %v", r.err)
+	}
+	if v := engine.Evaluate(EvalContext{Tool: "view"}); v.Effect != EffectDeny {
+		t.Errorf("default effect = %q, want the expanded deny", v.Effect)
+	}
+
+	misspelled := strings.Replace(watchAllowDoc, "tools: [bash]", "tool: [bash]", 1)
+	if err := os.WriteFile(path, []byte(misspelled), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r := waitReload(t, results); r.err == nil {
+		t.Error("strict reload accepted a misspelled field")
+	}
+}
+
+func TestWatchPolicyFileMissingDirectory(t *testing.T) {
+	engine := NewPolicyEngine(nil)
+	if _, err := engine.WatchPolicyFile(filepath.Join(t.TempDir(), "nope", "policy.yaml"), nil); err == nil {
+		t.Fatal("expected error watching a missing directory")
+	}
+}
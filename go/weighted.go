@@ -0,0 +1,81 @@
+package guard
+
+import "sort"
+
+// ── Weighted scoring ───────────────────────────────────────────────────
+
+// ScoreBand maps a range of total scores to an effect: it applies when
+// the summed weight of the matching policies is at least Min and below
+// the next band's Min.
+type ScoreBand struct {
+	Min    int
+	Effect Effect
+}
+
+// WithWeightedScoring switches the engine from picking one winning policy
+// to a risk-accumulation model: the Weight of every matching policy is
+// summed, and the band whose range contains the total decides the effect,
+// e.g.
+//
+//	guard.WithWeightedScoring(
+//		guard.ScoreBand{Min: 0, Effect: guard.EffectAllow},
+//		guard.ScoreBand{Min: 10, Effect: guard.EffectAsk},
+//		guard.ScoreBand{Min: 50, Effect: guard.EffectDeny},
+//	)
+//
+// The policies' own effects are ignored, so rate limits do not apply. The
+// verdict's other fields (policy ID, channel, message and so on) come
+// from the heaviest matching policy, ties going to the one with higher
+// precedence. When nothing matches, or the total is below every band, the
+// context fallback chain and defaults apply as usual. Weighted scoring
+// replaces the Strategy and CombiningAlgorithm.
+func WithWeightedScoring(bands ...ScoreBand) Option {
+	sorted := append([]ScoreBand(nil), bands...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Min < sorted[j].Min })
+	return func(e *PolicyEngine) {
+		e.bands = sorted
+	}
+}
+
+// offerWeighted adds p's weight to the total. Every match counts, so the
+// winner is never settled early.
+func (pk *picker) offerWeighted(i int, p *Policy) bool {
+	pk.score += p.Weight
+	if pk.winner < 0 || p.Weight > pk.best {
+		pk.winner, pk.best = i, p.Weight
+	}
+	return false
+}
+
+// result returns the index of the winning policy, or -1, and under
+// weighted scoring the effect of the band the total falls in. A total
+// below every band has no winner.
+func (pk *picker) result() (int, Effect) {
+	if pk.bands == nil || pk.winner < 0 {
+		return pk.winner, ""
+	}
+	for i := len(pk.bands) - 1; i >= 0; i-- {
+		if pk.score >= pk.bands[i].Min {
+			return pk.winner, pk.bands[i].Effect
+		}
+	}
+	return -1, ""
+}
+
+// verdictForResult builds the verdict for a picker result, overriding the
+// winner's effect with the band's under weighted scoring. A rate-limit
+// winner without a channel gets the default channel for the effect it
+// resolved to.
+func (e *PolicyEngine) verdictForResult(st *engineState, winner int, effect Effect, ctx EvalContext) Verdict {
+	p := &st.policies[winner]
+	if effect != "" {
+		cp := *p
+		cp.Effect = effect
+		p = &cp
+	}
+	v := e.verdictFor(p, ctx)
+	if v.Channel == "" && p.Effect == EffectRateLimit {
+		v.Channel = st.defaults.channelFor(v.Effect)
+	}
+	return v
+}
@@ -0,0 +1,61 @@
+package guard
+
+import "testing"
+
+func weightedPolicySet() *PolicySet {
+	ps := makePolicySet([]Policy{
+		{ID: "shell", Effect: EffectAllow, Priority: 10, Weight: 20, Message: "shell access", Condition: Condition{Tools: []string{"bash", "sh"}}},
+		{ID: "prod", Effect: EffectAllow, Priority: 20, Weight: 30, Condition: Condition{Tags: map[string][]string{"env": {"prod"}}}},
+		{ID: "background", Effect: EffectAllow, Priority: 30, Weight: 15, Condition: Condition{Modes: []string{"background"}}},
+		{ID: "trusted", Effect: EffectDeny, Priority: 40, Weight: -25, Condition: Condition{Users: []string{"admin-*"}}},
+	}, EffectAllow)
+	ps.Defaults.Channel = ChannelChat
+	ps.ContextFallbacks = map[string]string{"cron": "background"}
+	return ps
+}
+
+func weightedEngine() *PolicyEngine {
+	return NewPolicyEngineWithOptions(weightedPolicySet(), WithWeightedScoring(
+		ScoreBand{Min: 50, Effect: EffectDeny}, // out of order on purpose
+		ScoreBand{Min: 0, Effect: EffectAllow},
+		ScoreBand{Min: 20, Effect: EffectAsk},
+	))
+}
+
+func TestWeightedScoringSumsMatches(t *testing.T) {
+	engine := weightedEngine()
+	prod := map[string]string{"env": "prod"}
+	cases := []struct {
+		name   string
+		ctx    EvalContext
+		effect Effect
+		policy string
+	}{
+		{"shell alone", EvalContext{Tool: "bash"}, EffectAsk, "shell"},                                    // 20
+		{"shell in prod", EvalContext{Tool: "bash", Tags: prod}, EffectDeny, "prod"},                      // 50
+		{"everything", EvalContext{Tool: "sh", Tags: prod, Mode: "background"}, EffectDeny, "prod"},       // 65
+		{"mitigated", EvalContext{Tool: "sh", Tags: prod, User: "admin-jo"}, EffectAsk, "prod"},           // 25
+		{"background only", EvalContext{Tool: "view", Mode: "background"}, EffectAllow, "background"},     // 15
+		{"no match", EvalContext{Tool: "view"}, EffectAllow, ""},                                          // default
+		{"negative total", EvalContext{Tool: "view", User: "admin-jo"}, EffectAllow, ""},                  // -25: below every band
+		{"via fallback", EvalContext{Tool: "bash", Mode: "cron", User: "admin-jo"}, EffectAllow, "shell"}, // -5 in cron, 10 in background
+	}
+	for _, tc := range cases {
+		v := engine.Evaluate(tc.ctx)
+		if v.Effect != tc.effect || v.PolicyID != tc.policy {
+			t.Errorf("%s: expected %s from %q, got %s from %q", tc.name, tc.effect, tc.policy, v.Effect, v.PolicyID)
+		}
+	}
+
+	v, results := engine.EvaluateDetailed(EvalContext{Tool: "bash"})
+	if v.Effect != EffectAsk || v.Reason != "shell access" || !results[0].Winner {
+		t.Errorf("EvaluateDetailed: %+v", v)
+	}
+}
+
+func TestWeightedScoringIsOptIn(t *testing.T) {
+	engine := NewPolicyEngine(weightedPolicySet())
+	if v := engine.Evaluate(EvalContext{Tool: "bash", Tags: map[string]string{"env": "prod"}}); v.Effect != EffectAllow || v.PolicyID != "shell" {
+		t.Errorf("expected first-match allow from shell, got %s from %q", v.Effect, v.PolicyID)
+	}
+}